@@ -8,6 +8,7 @@ require (
 	github.com/google/uuid v1.6.0
 	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	golang.org/x/image v0.15.0
+	golang.org/x/text v0.14.0
 )
 
 require github.com/golang/freetype v0.0.0-20170609003504-e2365dfdc4a0 // indirect
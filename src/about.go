@@ -67,7 +67,7 @@ func (app *MiyooPod) showAboutScreen() {
 
 // waitForAboutExit waits for the user to press B to exit the about screen
 func (app *MiyooPod) waitForAboutExit() {
-	for app.Running {
+	for app.Running.Load() {
 		key := Key(C_GetKeyPress())
 		if key == NONE {
 			time.Sleep(33 * time.Millisecond)
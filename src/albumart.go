@@ -2,7 +2,10 @@ package main
 
 import (
 	"fmt"
+	"image"
+	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/image/font"
@@ -54,61 +57,105 @@ func (app *MiyooPod) scanAlbumArt() {
 	app.setScreen(ScreenAlbumArt)
 	app.drawCurrentScreen()
 
-	go app.runAlbumArtFetch()
+	var albums []*Album
+	for _, album := range app.Library.Albums {
+		if album.ArtData == nil && album.ArtPath == "" && album.Name != "" && album.Artist != "" {
+			albums = append(albums, album)
+		}
+	}
+	cancel := new(atomic.Bool)
+	app.albumArtCancel = cancel
+
+	go app.runAlbumArtFetch(albums, cancel)
 }
 
 // runAlbumArtFetch is the background goroutine that fetches album art.
-// IMPORTANT: Never call draw functions from here — only update state and requestRedraw.
-func (app *MiyooPod) runAlbumArtFetch() {
+// Album names are only read; results and progress are posted to the main loop.
+// IMPORTANT: Never call draw functions or write app state from here.
+func (app *MiyooPod) runAlbumArtFetch(albums []*Album, cancel *atomic.Bool) {
 	start := time.Now()
 
-	for _, album := range app.Library.Albums {
-		if !app.Running || !app.AlbumArtFetching {
+	// progress applies a state update unless the fetch was cancelled
+	progress := func(update func()) {
+		app.post(func() {
+			if cancel.Load() {
+				return
+			}
+			update()
+			app.requestRedraw()
+		})
+	}
+	status := func(text string) {
+		progress(func() { app.AlbumArtStatus = text })
+	}
+
+	fetched := 0
+	for i, album := range albums {
+		if !app.Running.Load() || cancel.Load() {
 			break
 		}
 
-		if album.ArtData == nil && album.ArtPath == "" && album.Name != "" && album.Artist != "" {
-			app.AlbumArtCurrent++
-			app.AlbumArtAlbumName = album.Name
-			app.AlbumArtArtist = album.Artist
+		current, name, artist := i+1, album.Name, album.Artist
+		progress(func() {
+			app.AlbumArtCurrent = current
+			app.AlbumArtAlbumName = name
+			app.AlbumArtArtist = artist
 			app.AlbumArtStatus = "Searching MusicBrainz..."
-			app.requestRedraw()
-
-			// Status callback updates state and signals redraw (no draw calls)
-			app.albumArtStatusFunc = func(status string) {
-				app.AlbumArtStatus = status
-				app.requestRedraw()
+		})
+
+		path, ok := fetchAlbumArtFromMusicBrainz(artist, name, status)
+		var cover image.Image
+		if ok {
+			fetched++
+			// Decode the new artwork (and write its RGBA cache) here rather
+			// than on the main loop
+			if data, err := os.ReadFile(path); err == nil {
+				if img, err := renderCover(data, COVER_CENTER_SIZE); err == nil {
+					cover = img
+					if rgba, ok := img.(*image.RGBA); ok {
+						app.saveRGBACache(rgbaCachePathFor(artist, name), rgba)
+					}
+				}
 			}
+		}
 
-			if app.fetchAlbumArtFromMusicBrainz(album) {
+		album := album
+		app.post(func() {
+			// Fetched art is kept even if the user cancelled meanwhile
+			if ok {
+				album.ArtPath = path
+				if cover != nil {
+					app.Coverflow.CoverCache[coverCacheKey(album, COVER_CENTER_SIZE)] = cover
+				}
+			}
+			if cancel.Load() {
+				return
+			}
+			if ok {
 				app.AlbumArtFetched++
 			} else {
 				app.AlbumArtFailed++
 			}
-
-			app.albumArtStatusFunc = nil
-		}
-	}
-
-	// Decode newly fetched artwork
-	if app.AlbumArtFetched > 0 {
-		app.AlbumArtStatus = "Decoding artwork..."
-		app.AlbumArtAlbumName = ""
-		app.AlbumArtArtist = ""
-		app.requestRedraw()
-
-		app.decodeAlbumArt()
-
-		if err := app.saveLibraryJSON(); err != nil {
-			logMsg(fmt.Sprintf("WARNING: Failed to save library: %v", err))
-		}
+		})
 	}
 
 	elapsed := time.Since(start)
-	app.AlbumArtElapsed = elapsed.Truncate(time.Second).String()
-	app.AlbumArtFetching = false
-	app.AlbumArtDone = true
-	app.requestRedraw()
+	app.post(func() {
+		// Save paths of newly fetched artwork
+		if fetched > 0 {
+			app.NPCacheDirty = true
+			if err := app.saveLibraryJSON(); err != nil {
+				logMsg(fmt.Sprintf("WARNING: Failed to save library: %v", err))
+			}
+		}
+		if cancel.Load() {
+			return
+		}
+		app.AlbumArtElapsed = elapsed.Truncate(time.Second).String()
+		app.AlbumArtFetching = false
+		app.AlbumArtDone = true
+		app.requestRedraw()
+	})
 }
 
 // drawAlbumArtScreen renders the album art fetch screen using list-style layout.
@@ -299,7 +346,10 @@ func (app *MiyooPod) drawAlbumArtStatusBar() {
 func (app *MiyooPod) handleAlbumArtKey(key Key) {
 	switch key {
 	case B, MENU:
-		app.AlbumArtFetching = false // Signal goroutine to stop
+		if app.albumArtCancel != nil {
+			app.albumArtCancel.Store(true) // Signal goroutine to stop
+		}
+		app.AlbumArtFetching = false
 		app.AlbumArtDone = false
 		app.setScreen(ScreenMenu)
 		app.drawCurrentScreen()
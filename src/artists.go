@@ -0,0 +1,165 @@
+package main
+
+import (
+	"io"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// FEAT_PATTERNS introduce featured artists in an artist tag ("A feat. B").
+// The settings file can replace them (feat_patterns); the library has to be
+// rescanned for a change to apply to tracks already scanned.
+var FEAT_PATTERNS = []string{"feat.", "feat", "ft.", "featuring"}
+
+// setFeatPatterns replaces FEAT_PATTERNS; an empty list keeps the defaults.
+// Only called while loading settings, before any scan reads them.
+func setFeatPatterns(patterns []string) {
+	var cleaned []string
+	for _, p := range patterns {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			cleaned = append(cleaned, p)
+		}
+	}
+	if len(cleaned) > 0 {
+		FEAT_PATTERNS = cleaned
+	}
+}
+
+// splitMultiValue splits a tag holding several values: separated by NULs
+// (ID3v2.4), ';' or '/'. A '/' only splits when every part is longer than two
+// characters, so "AC/DC" stays one artist.
+func splitMultiValue(s string) []string {
+	var values []string
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == 0 || r == ';' }) {
+		slashed := strings.Split(part, "/")
+		for _, p := range slashed {
+			if len(strings.TrimSpace(p)) <= 2 {
+				slashed = []string{part}
+				break
+			}
+		}
+		values = append(values, slashed...)
+	}
+	return cleanValues(values)
+}
+
+// splitArtists splits an artist tag into the artists it credits, main artist
+// first. Featured artists are split further on ',' and '&', which are left
+// alone in the main artist ("Simon & Garfunkel").
+func splitArtists(s string) []string {
+	var artists []string
+	for _, value := range splitMultiValue(s) {
+		main, featured := cutFeaturing(value)
+		artists = append(artists, main)
+		for _, f := range strings.Split(featured, ",") {
+			artists = append(artists, strings.Split(f, " & ")...)
+		}
+	}
+	return cleanValues(artists)
+}
+
+// cutFeaturing splits "A feat. B" or "A (ft. B)" at the earliest featuring
+// pattern, returning "A" and "B". Without one, featured is empty.
+func cutFeaturing(s string) (main, featured string) {
+	// ASCII-only lowering keeps byte offsets valid in s
+	lower := strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+
+	at, end := -1, 0
+	for _, pattern := range FEAT_PATTERNS {
+		for _, before := range []string{" ", "(", "["} {
+			match := before + pattern + " "
+			if i := strings.Index(lower, match); i >= 0 && (at < 0 || i < at) {
+				at, end = i, i+len(match)
+			}
+		}
+	}
+	if at < 0 {
+		return s, ""
+	}
+	return strings.TrimRight(s[:at], " ([-"), strings.TrimRight(s[end:], " )]")
+}
+
+// cleanValues trims values and drops empty and repeated ones, keeping order
+func cleanValues(values []string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, v := range values {
+		v = strings.TrimSpace(v)
+		key := strings.ToLower(v)
+		if v == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, v)
+	}
+	return out
+}
+
+// splitGenres splits a genre tag into its genres
+func splitGenres(s string) []string {
+	return splitMultiValue(s)
+}
+
+// readMultiValueTags fills in a track's artists and genres. ID3v2 frames are
+// read again for their NUL-separated values, which the tag reader loses.
+func readMultiValueTags(track *Track, m tag.Metadata, r io.ReadSeeker) {
+	artist, genre := track.Artist, track.Genre
+	if m.Format() == tag.ID3v2_3 || m.Format() == tag.ID3v2_4 {
+		frames := readID3TextFrames(r, "TPE1", "TCON")
+		if v := frames["TPE1"]; len(v) > 1 {
+			artist = strings.Join(v, "\x00")
+			track.Artist = strings.Join(v, "; ")
+		}
+		if v := frames["TCON"]; len(v) > 1 {
+			genre = strings.Join(v, "\x00")
+			track.Genre = strings.Join(v, "; ")
+		}
+	}
+
+	track.Artists, track.Genres = nil, nil
+	if artists := splitArtists(artist); len(artists) > 1 {
+		track.Artists = artists
+	}
+	if genres := splitGenres(genre); len(genres) > 1 {
+		track.Genres = genres
+	}
+}
+
+// trackArtists returns the artists credited on a track, main artist first.
+// It is never empty, though the one name may be.
+func trackArtists(t *Track) []string {
+	if len(t.Artists) > 0 {
+		return t.Artists
+	}
+	return []string{t.Artist}
+}
+
+// trackGenres returns a track's genres
+func trackGenres(t *Track) []string {
+	if len(t.Genres) > 0 {
+		return t.Genres
+	}
+	return []string{t.Genre}
+}
+
+// artistAppearances groups tracks by the credited artists they are not filed
+// under: compilation artists, featured artists, and track artists on albums
+// with another album artist
+func (app *MiyooPod) artistAppearances() []*trackGroup {
+	return groupTracksBy(app.Library.Tracks, func(t *Track) []string {
+		filed := trackAlbumArtist(t)
+		var names []string
+		for _, name := range trackArtists(t) {
+			if !strings.EqualFold(name, filed) {
+				names = append(names, name)
+			}
+		}
+		return names
+	})
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestSplitArtists(t *testing.T) {
+	for tag, want := range map[string]string{
+		"Artist A; Artist B":          "[Artist A Artist B]",
+		"Artist A\x00Artist B":        "[Artist A Artist B]",
+		"Artist A/Artist B":           "[Artist A Artist B]",
+		"AC/DC":                       "[AC/DC]",
+		"Simon & Garfunkel":           "[Simon & Garfunkel]",
+		"Main feat. Guest":            "[Main Guest]",
+		"Main (Ft. One, Two & Three)": "[Main One Two Three]",
+		"Main featuring Guest; main":  "[Main Guest]",
+		"Featherweight":               "[Featherweight]",
+		"  ":                          "[]",
+	} {
+		if got := fmt.Sprint(splitArtists(tag)); got != want {
+			t.Errorf("splitArtists(%q) = %s, want %s", tag, got, want)
+		}
+	}
+}
+
+func TestReadID3TextFrames(t *testing.T) {
+	frame := func(id string, body []byte) []byte {
+		n := len(body)
+		header := []byte(id)
+		header = append(header, byte(n>>21&0x7F), byte(n>>14&0x7F), byte(n>>7&0x7F), byte(n&0x7F), 0, 0)
+		return append(header, body...)
+	}
+	var frames []byte
+	frames = append(frames, frame("TIT2", []byte("\x03Song"))...)
+	frames = append(frames, frame("TPE1", []byte("\x03Artist A\x00Artist B"))...)
+	// UTF-16 with a little-endian BOM: "Pop\0Rock"
+	frames = append(frames, frame("TCON", []byte{1, 0xFF, 0xFE, 'P', 0, 'o', 0, 'p', 0, 0, 0, 'R', 0, 'o', 0, 'c', 0, 'k', 0})...)
+	frames = append(frames, make([]byte, 16)...) // Padding
+
+	n := len(frames)
+	tag := append([]byte{'I', 'D', '3', 4, 0, 0, byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}, frames...)
+
+	got := readID3TextFrames(bytes.NewReader(tag), "TPE1", "TCON")
+	if fmt.Sprint(got["TPE1"]) != "[Artist A Artist B]" || fmt.Sprint(got["TCON"]) != "[Pop Rock]" {
+		t.Errorf("frames = %v", got)
+	}
+	if _, ok := got["TIT2"]; ok {
+		t.Errorf("read a frame that wasn't asked for")
+	}
+}
+
+func TestArtistsMenuListsEveryCreditedArtist(t *testing.T) {
+	app, _ := newTestApp(t, 0)
+	for _, track := range []*Track{
+		{Path: "/m/A/1.mp3", Title: "One", Artist: "Main", Album: "First"},
+		{Path: "/m/A/2.mp3", Title: "Two", Artist: "Main feat. Guest", Artists: []string{"Main", "Guest"}, Album: "First"},
+		{Path: "/m/G/1.mp3", Title: "Solo", Artist: "Guest Star", Album: "Own"},
+	} {
+		app.Library.addTrack(track, nil)
+	}
+	app.Library.sort()
+
+	if got := fmt.Sprintf("%d %s", len(app.Library.Albums), app.Library.Albums[0].Artist); got != "2 Main" {
+		t.Fatalf("albums = %s, want the featured track on Main's album", got)
+	}
+
+	var labels []string
+	for _, item := range app.buildArtistMenuItems(app.RootMenu) {
+		labels = append(labels, item.Label)
+	}
+	if got := fmt.Sprint(labels); got != "[Guest Guest Star Main]" {
+		t.Errorf("artists = %s", got)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+// AudioBackend is the audio layer the player drives. The device build uses
+// SDL_mixer (sdl.go); headless builds and tests use the simulated backend in
+// audio_sim.go.
+type AudioBackend interface {
+	// Load opens a file as the current track, replacing any preloaded one
+	Load(path string) error
+	// Preload opens the track to switch to when the current one ends (gapless).
+	// gain is its linear ReplayGain factor.
+	Preload(path string, gain float64) error
+	ClearPreload()
+
+	Play() error
+	Stop()
+	Pause()
+	Resume()
+	TogglePause()
+	Seek(position float64)
+	// SetEnd makes the loaded track end position seconds into its file (a
+	// cue track): playback stops there and Finished is reported, without
+	// switching to a preloaded track. 0 plays to the end of the file; Load
+	// clears it.
+	SetEnd(position float64)
+
+	// SetGain sets the linear ReplayGain factor applied to the current track
+	SetGain(gain float64)
+	// SetVolume sets the mixer volume in percent
+	SetVolume(volume int)
+	// SetSpeed sets the playback speed (1.0 = normal) for the loaded track,
+	// switching a playing track over in place. Files the backend can't speed
+	// up play at normal speed and return an error.
+	SetSpeed(speed float64) error
+	// SetEqualizer sets the equalizer curve in dB per band of eqBandFreqs.
+	// All-zero gains turn it off.
+	SetEqualizer(gains []float64)
+
+	// State reports playback progress. Finished and Advanced are events: each
+	// is reported by one call only.
+	State() AudioStateSnapshot
+	// Notify registers fn to be called, from any goroutine, when a track ends,
+	// so the player can call State without waiting for its next poll
+	Notify(fn func())
+	FlushBuffers()
+
+	// DurationForFile opens a file just to read its length in seconds
+	DurationForFile(path string) float64
+	Quit()
+}
+
+type AudioStateSnapshot struct {
+	Position  float64
+	Duration  float64
+	IsPlaying bool
+	IsPaused  bool
+	Finished  bool
+	Advanced  bool // Audio layer switched to the preloaded track
+}
@@ -0,0 +1,337 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SIM_DEFAULT_DURATION is the length of simulated tracks without one set
+const SIM_DEFAULT_DURATION = 180.0
+
+// simAudio is a pure-Go AudioBackend that decodes nothing. Its clock only
+// moves when Advance is called, so tests can step through tracks and hit
+// end-of-track and gapless switches deterministically.
+type simAudio struct {
+	mu sync.Mutex
+
+	durations map[string]float64 // Per-file length in seconds
+	broken    map[string]bool    // Files that fail to load
+
+	path     string
+	position float64
+	duration float64
+	end      float64 // Where the track ends in the file, 0 at its end
+	gain     float64
+	volume   int
+	speed    float64
+	eq       []float64
+	playing  bool // A track is started (possibly paused)
+	paused   bool
+
+	preloadPath string
+	preloadGain float64
+
+	finished bool // Pending end-of-track event
+	advanced bool // Pending gapless switch event
+
+	loads []string // Every file passed to Load, in order
+}
+
+func newSimAudio() *simAudio {
+	return &simAudio{
+		durations: make(map[string]float64),
+		broken:    make(map[string]bool),
+		gain:      1.0,
+		volume:    100,
+		speed:     1.0,
+	}
+}
+
+// SetDuration sets the length reported for a file
+func (s *simAudio) SetDuration(path string, seconds float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.durations[path] = seconds
+}
+
+// SetBroken makes loading a file fail, as an unsupported or corrupt file would
+func (s *simAudio) SetBroken(path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.broken[path] = true
+}
+
+func (s *simAudio) durationOf(path string) float64 {
+	if d, ok := s.durations[path]; ok {
+		return d
+	}
+	return SIM_DEFAULT_DURATION
+}
+
+// Advance moves the playback clock forward by d of real time, scaled by the
+// playback speed. Reaching the end of a track switches to the preloaded one
+// (an Advanced event) or stops (a Finished event).
+func (s *simAudio) Advance(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(d.Seconds() * s.speed)
+}
+
+// Finish jumps to the end of the current track
+func (s *simAudio) Finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.advance(s.endOf() - s.position)
+}
+
+// endOf returns where the current track ends in its file
+func (s *simAudio) endOf() float64 {
+	if s.end > 0 && s.end < s.duration {
+		return s.end
+	}
+	return s.duration
+}
+
+// advance moves the clock forward by seconds of the track
+func (s *simAudio) advance(seconds float64) {
+	if !s.playing || s.paused {
+		return
+	}
+	s.position += seconds
+
+	for s.playing && s.position >= s.endOf() {
+		// A track that ends mid-file stops there, like one without a preload
+		if s.preloadPath == "" || s.endOf() < s.duration {
+			s.end = 0
+			s.position = 0
+			s.playing = false
+			s.finished = true
+			return
+		}
+		over := s.position - s.duration
+		s.path = s.preloadPath
+		s.gain = s.preloadGain
+		s.duration = s.durationOf(s.path)
+		s.end = 0
+		s.position = over
+		s.preloadPath = ""
+		s.advanced = true
+	}
+}
+
+// Current returns the file the simulated layer is playing, or ""
+func (s *simAudio) Current() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.playing {
+		return ""
+	}
+	return s.path
+}
+
+// Preloaded returns the file queued for a gapless switch, or ""
+func (s *simAudio) Preloaded() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.preloadPath
+}
+
+// Volume returns the mixer volume last set, in percent
+func (s *simAudio) Volume() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.volume
+}
+
+// Speed returns the playback speed last set
+func (s *simAudio) Speed() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.speed
+}
+
+// Equalizer returns the equalizer curve last set
+func (s *simAudio) Equalizer() []float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.eq
+}
+
+// Loads returns every file passed to Load, in order
+func (s *simAudio) Loads() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.loads...)
+}
+
+func (s *simAudio) Load(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loads = append(s.loads, path)
+	s.end = 0
+	s.playing = false
+	s.paused = false
+	s.preloadPath = ""
+	if s.broken[path] {
+		s.path = ""
+		return fmt.Errorf("failed to load audio: %s (simulated)", filepath.Base(path))
+	}
+	s.path = path
+	s.position = 0
+	s.duration = s.durationOf(path)
+	return nil
+}
+
+func (s *simAudio) Preload(path string, gain float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.broken[path] {
+		return fmt.Errorf("failed to preload audio: %s (simulated)", filepath.Base(path))
+	}
+	s.preloadPath = path
+	s.preloadGain = gain
+	return nil
+}
+
+func (s *simAudio) ClearPreload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.preloadPath = ""
+}
+
+func (s *simAudio) Play() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.path == "" {
+		return fmt.Errorf("failed to play audio")
+	}
+	s.playing = true
+	s.paused = false
+	s.finished = false
+	return nil
+}
+
+func (s *simAudio) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.playing = false
+	s.paused = false
+	s.position = 0
+	s.end = 0
+	s.preloadPath = ""
+}
+
+func (s *simAudio) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.playing {
+		s.paused = true
+	}
+}
+
+func (s *simAudio) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.paused = false
+}
+
+func (s *simAudio) TogglePause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.playing {
+		s.paused = !s.paused
+	}
+}
+
+func (s *simAudio) Seek(position float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.playing {
+		return
+	}
+	if position < 0 {
+		position = 0
+	}
+	if position > s.duration {
+		position = s.duration
+	}
+	s.position = position
+}
+
+func (s *simAudio) SetEnd(position float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.end = position
+}
+
+func (s *simAudio) SetGain(gain float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.gain = gain
+}
+
+func (s *simAudio) SetVolume(volume int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.volume = volume
+}
+
+func (s *simAudio) SetSpeed(speed float64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Like the device, only MP3s can be sped up
+	if speed != 1 && !strings.EqualFold(filepath.Ext(s.path), ".mp3") {
+		s.speed = 1
+		return fmt.Errorf("playback speed can't be changed for this file (simulated)")
+	}
+	s.speed = speed
+	return nil
+}
+
+func (s *simAudio) SetEqualizer(gains []float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.eq = append([]float64(nil), gains...)
+}
+
+func (s *simAudio) State() AudioStateSnapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := AudioStateSnapshot{
+		Duration: s.duration,
+		Finished: s.finished,
+		Advanced: s.advanced,
+	}
+	s.finished = false
+	s.advanced = false
+
+	if s.playing {
+		state.Position = s.position
+		state.IsPlaying = !s.paused
+		state.IsPaused = s.paused
+	}
+	return state
+}
+
+// Notify does nothing: tests call syncAudioState themselves
+func (s *simAudio) Notify(fn func()) {}
+
+func (s *simAudio) FlushBuffers() {}
+
+func (s *simAudio) DurationForFile(path string) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.durationOf(path)
+}
+
+func (s *simAudio) Quit() {
+	s.Stop()
+}
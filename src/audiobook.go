@@ -0,0 +1,410 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var AUDIOBOOK_PROGRESS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_audiobooks.json"
+
+// AudiobookProgress is where listening stopped in one file of an audiobook or
+// podcast folder, keyed by path. Like play stats it lives outside the library
+// JSON, so rescans don't lose it.
+type AudiobookProgress struct {
+	Position  float64 `json:"position"`
+	Duration  float64 `json:"duration,omitempty"`
+	UpdatedAt int64   `json:"updated_at"` // Unix time last listened to
+	Finished  bool    `json:"finished,omitempty"`
+}
+
+// audiobookSpeeds are the playback speeds offered for audiobook files
+var audiobookSpeeds = []float64{0.8, 1.0, 1.2, 1.5, 1.75, 2.0}
+
+const (
+	// AUDIOBOOK_SKIP_SECONDS is how far L2 and R2 jump on Now Playing
+	AUDIOBOOK_SKIP_SECONDS = 30.0
+	// AUDIOBOOK_FINISHED_LEFT is how close to the end a file counts as
+	// finished, so credits and outros don't leave it half-done
+	AUDIOBOOK_FINISHED_LEFT = 30.0
+	// CONTINUE_LISTENING_LIMIT caps the Continue Listening menu
+	CONTINUE_LISTENING_LIMIT = 25
+)
+
+// isAudiobook reports whether a track is in one of the audiobook folders
+func (app *MiyooPod) isAudiobook(track *Track) bool {
+	if track == nil {
+		return false
+	}
+	for _, dir := range app.AudiobookFolders {
+		if strings.HasPrefix(track.Path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// loadAudiobookProgress reads resume positions from disk. Missing file means
+// nothing was started yet.
+func (app *MiyooPod) loadAudiobookProgress() {
+	app.AudiobookProgress = make(map[string]*AudiobookProgress)
+
+	data, err := os.ReadFile(AUDIOBOOK_PROGRESS_PATH)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logMsg(fmt.Sprintf("WARNING: Failed to read audiobook progress: %v", err))
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, &app.AudiobookProgress); err != nil {
+		logMsg(fmt.Sprintf("WARNING: Failed to parse audiobook progress: %v", err))
+		app.AudiobookProgress = make(map[string]*AudiobookProgress)
+		return
+	}
+
+	logMsg(fmt.Sprintf("INFO: Loaded audiobook progress for %d files", len(app.AudiobookProgress)))
+}
+
+// saveAudiobookProgress writes resume positions to disk
+func (app *MiyooPod) saveAudiobookProgress() {
+	data, err := json.Marshal(app.AudiobookProgress)
+	if err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to marshal audiobook progress: %v", err))
+		return
+	}
+
+	if err := os.WriteFile(AUDIOBOOK_PROGRESS_PATH, data, 0644); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save audiobook progress: %v", err))
+	}
+}
+
+// recordAudiobookProgress remembers the position in the playing file if it
+// is an audiobook. Called by the playback poller, when the file is replaced
+// and on exit; finished is set when it played to the end. Positions in the
+// first second aren't recorded, so starting a file over and leaving it
+// straight away keeps the old position.
+func (app *MiyooPod) recordAudiobookProgress(finished bool) {
+	if app.Playing == nil || !app.isAudiobook(app.Playing.Track) {
+		return
+	}
+
+	track := app.Playing.Track
+	position := app.Playing.Position
+	duration := app.Playing.Duration
+	if duration <= 0 {
+		duration = track.Duration
+	}
+	if duration > 0 && duration-position <= AUDIOBOOK_FINISHED_LEFT {
+		finished = true
+	}
+	if finished {
+		position = duration
+	} else if position < 1 {
+		return
+	}
+
+	if app.AudiobookProgress == nil {
+		app.AudiobookProgress = make(map[string]*AudiobookProgress)
+	}
+	p, ok := app.AudiobookProgress[track.Path]
+	if !ok {
+		p = &AudiobookProgress{}
+		app.AudiobookProgress[track.Path] = p
+	} else if p.Finished == finished && p.Position > position-1 && p.Position < position+1 {
+		return // Paused, nothing new to write
+	}
+
+	p.Position = position
+	p.Duration = duration
+	p.Finished = finished
+	p.UpdatedAt = time.Now().Unix()
+	app.saveAudiobookProgress()
+}
+
+// audiobookResumePosition returns where to start an audiobook file: where it
+// was left, or 0 if it's new, finished or not an audiobook
+func (app *MiyooPod) audiobookResumePosition(track *Track) float64 {
+	if !app.isAudiobook(track) {
+		return 0
+	}
+	p, ok := app.AudiobookProgress[track.Path]
+	if !ok || p.Finished {
+		return 0
+	}
+	return p.Position
+}
+
+// resumeAudiobook seeks a file that was just started to where it was left
+func (app *MiyooPod) resumeAudiobook(track *Track) {
+	pos := app.audiobookResumePosition(track)
+	if pos <= 0 {
+		return
+	}
+	app.seekAudio(pos)
+	app.Playing.Position = pos
+	logMsg(fmt.Sprintf("INFO: Resuming %s at %s", track.Title, formatTime(pos)))
+}
+
+// audiobookFraction returns how much of a file has been heard (0 to 1), and
+// false if it isn't an audiobook file that was started
+func (app *MiyooPod) audiobookFraction(track *Track) (float64, bool) {
+	if !app.isAudiobook(track) {
+		return 0, false
+	}
+	p, ok := app.AudiobookProgress[track.Path]
+	if !ok {
+		return 0, false
+	}
+	if p.Finished {
+		return 1, true
+	}
+	duration := p.Duration
+	if duration <= 0 {
+		duration = track.Duration
+	}
+	if duration <= 0 {
+		return 0, false
+	}
+	return min(p.Position/duration, 1), true
+}
+
+// applyPlaybackSpeed sets the speed of the loaded file: the audiobook speed
+// for audiobook files, normal speed for everything else. A file the backend
+// can't speed up (only MP3s can be) plays at normal speed, and Now Playing
+// shows the audiobook speed greyed out.
+func (app *MiyooPod) applyPlaybackSpeed(track *Track) {
+	speed := 1.0
+	if app.isAudiobook(track) && app.AudiobookSpeed > 0 {
+		speed = app.AudiobookSpeed
+	}
+	blocked := false
+	if err := app.Audio.SetSpeed(speed); err != nil {
+		logMsg(fmt.Sprintf("WARNING: Playing %s at normal speed: %v", filepath.Base(track.Path), err))
+		blocked = speed != 1
+		speed = 1.0
+	}
+	if speed != app.Playing.Speed || blocked != app.Playing.SpeedBlocked {
+		app.Playing.Speed = speed
+		app.Playing.SpeedBlocked = blocked
+		app.NPCacheDirty = true
+	}
+}
+
+// cycleAudiobookSpeed steps through audiobookSpeeds, applying the new speed
+// to an audiobook that is playing
+func (app *MiyooPod) cycleAudiobookSpeed() {
+	next := audiobookSpeeds[0]
+	for i, s := range audiobookSpeeds {
+		if s == app.AudiobookSpeed && i+1 < len(audiobookSpeeds) {
+			next = audiobookSpeeds[i+1]
+			break
+		}
+	}
+	app.AudiobookSpeed = next
+
+	if app.Playing != nil && app.Playing.State != StateStopped && app.isAudiobook(app.Playing.Track) {
+		app.applyPlaybackSpeed(app.Playing.Track)
+	}
+	app.refreshSettingsMenu()
+	if app.Playing != nil && app.Playing.SpeedBlocked {
+		app.showError("Speed only works\nwith MP3 files")
+	}
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save audiobook speed: %v", err))
+	}
+}
+
+// formatSpeed formats a playback speed as "1.0x", "1.75x"
+func formatSpeed(speed float64) string {
+	s := fmt.Sprintf("%.2f", speed)
+	s = strings.TrimSuffix(s, "0")
+	return s + "x"
+}
+
+// skipSeconds jumps forward or back within the playing track (L2/R2)
+func (app *MiyooPod) skipSeconds(seconds float64) {
+	if app.Playing == nil || app.Playing.State == StateStopped {
+		return
+	}
+	app.mpvSeek(seconds)
+
+	// Update position immediately for responsive UI
+	state := app.audioState()
+	if state.Position >= 0 {
+		app.Playing.Position = state.Position
+	}
+
+	if app.CurrentScreen == ScreenNowPlaying {
+		app.updateProgressBarOnly()
+		app.updateLyricsOnly()
+	}
+}
+
+// continueListeningTracks returns the audiobook files that were started but
+// not finished, most recently listened to first
+func (app *MiyooPod) continueListeningTracks() []*Track {
+	var tracks []*Track
+	for path, p := range app.AudiobookProgress {
+		if p.Finished {
+			continue
+		}
+		if t, ok := app.Library.TracksByPath[path]; ok && app.isAudiobook(t) {
+			tracks = append(tracks, t)
+		}
+	}
+	sort.Slice(tracks, func(i, j int) bool {
+		a, b := app.AudiobookProgress[tracks[i].Path], app.AudiobookProgress[tracks[j].Path]
+		if a.UpdatedAt != b.UpdatedAt {
+			return a.UpdatedAt > b.UpdatedAt
+		}
+		return tracks[i].Path < tracks[j].Path
+	})
+	if len(tracks) > CONTINUE_LISTENING_LIMIT {
+		tracks = tracks[:CONTINUE_LISTENING_LIMIT]
+	}
+	return tracks
+}
+
+// buildContinueListeningMenuItems lists unfinished audiobook files. Playing
+// one queues the rest of its folder after it, so the next part follows.
+func (app *MiyooPod) buildContinueListeningMenuItems() []*MenuItem {
+	tracks := app.continueListeningTracks()
+	items := make([]*MenuItem, 0, len(tracks))
+	for _, track := range tracks {
+		t := track // capture
+		items = append(items, &MenuItem{
+			Label: t.Title,
+			Track: t,
+			Action: func() {
+				folder := app.folderTracks(t)
+				for i, ft := range folder {
+					if ft == t {
+						app.playTrackFromList(folder, i)
+						return
+					}
+				}
+			},
+		})
+	}
+	return items
+}
+
+// folderTracks returns the tracks in the same folder as track, by file name
+// as in the Folders menu
+func (app *MiyooPod) folderTracks(track *Track) []*Track {
+	dir := filepath.Dir(track.Path)
+	var tracks []*Track
+	for _, t := range app.Library.Tracks {
+		if filepath.Dir(t.Path) == dir {
+			tracks = append(tracks, t)
+		}
+	}
+	sortByKey(tracks, func(t *Track) string { return foldText(filepath.Base(t.Path)) })
+	return tracks
+}
+
+// drawAudiobookProgress underlines a menu row showing an audiobook file with
+// how much of it has been heard
+func (app *MiyooPod) drawAudiobookProgress(y int, track *Track, selected bool) {
+	fraction, ok := app.audiobookFraction(track)
+	if !ok {
+		return
+	}
+
+	dc := app.DC
+	x := float64(MENU_LEFT_PAD)
+	barY := float64(y + MENU_ITEM_HEIGHT - 5)
+	width := float64(SCREEN_WIDTH - MENU_LEFT_PAD - MENU_RIGHT_PAD - 15)
+
+	dc.SetHexColor(app.CurrentTheme.ProgBG)
+	dc.DrawRectangle(x, barY, width, 3)
+	dc.Fill()
+
+	if selected {
+		dc.SetHexColor(app.CurrentTheme.SelTxt)
+	} else {
+		dc.SetHexColor(app.CurrentTheme.Progress)
+	}
+	dc.DrawRectangle(x, barY, width*fraction, 3)
+	dc.Fill()
+}
+
+// addAudiobookFolder marks dir as an audiobook folder. A folder outside the
+// music folders is added to them too, which rescans the library.
+func (app *MiyooPod) addAudiobookFolder(menu *MenuScreen, dir string) {
+	app.returnToMenu(menu)
+
+	folders := normalizeMusicRoots([]string{dir})
+	if len(folders) == 0 {
+		return
+	}
+	dir = folders[0]
+	for _, f := range app.AudiobookFolders {
+		if f == dir {
+			app.showMessage("Already an audiobook folder", false)
+			return
+		}
+	}
+
+	inLibrary := false
+	for _, root := range MUSIC_ROOTS {
+		if strings.HasPrefix(dir, root) {
+			inLibrary = true
+			break
+		}
+	}
+	if !inLibrary && musicFromFlags {
+		app.showMessage("Music folders are set by -music", false)
+		return
+	}
+
+	app.AudiobookFolders = append(app.AudiobookFolders, dir)
+	logMsg(fmt.Sprintf("INFO: Audiobook folder added: %s", dir))
+
+	if !inLibrary {
+		// Saves the settings too
+		app.updateMusicRoots(append(append([]string{}, MUSIC_ROOTS...), dir))
+		return
+	}
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save audiobook folders: %v", err))
+	}
+	app.replaceRootMenu()
+}
+
+// removeAudiobookFolder stops treating dir as an audiobook folder. Its files
+// stay in the library, and their resume positions are kept.
+func (app *MiyooPod) removeAudiobookFolder(menu *MenuScreen, dir string) {
+	app.returnToMenu(menu)
+
+	folders := make([]string, 0, len(app.AudiobookFolders))
+	for _, f := range app.AudiobookFolders {
+		if f != dir {
+			folders = append(folders, f)
+		}
+	}
+	app.AudiobookFolders = folders
+	logMsg(fmt.Sprintf("INFO: Audiobook folder removed: %s", dir))
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save audiobook folders: %v", err))
+	}
+	app.replaceRootMenu()
+}
+
+// replaceRootMenu rebuilds the root menu under the screens open on top of it,
+// for changes that add or remove root items
+func (app *MiyooPod) replaceRootMenu() {
+	if app.RootMenu == nil || len(app.MenuStack) == 0 {
+		return
+	}
+	app.RootMenu = app.buildRootMenu()
+	app.MenuStack[0] = app.RootMenu
+	app.refreshRootMenu()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAudiobookResumesEachFile(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	app.AudiobookFolders = []string{"/music/Artist/"}
+	playQueue(t, app, 0)
+
+	sim.Advance(60 * time.Second)
+	app.syncAudioState()
+	app.nextTrack()
+	sim.Advance(30 * time.Second)
+	app.syncAudioState()
+
+	// Back to the first file: it picks up where it was left
+	app.playTrackFromList(app.Library.Tracks, 0)
+	if got := sim.State().Position; got != 60 {
+		t.Errorf("first file resumed at %v, want 60", got)
+	}
+	if app.Playing.Position != 60 {
+		t.Errorf("Now Playing position = %v, want 60", app.Playing.Position)
+	}
+	if got := app.audiobookResumePosition(app.Library.Tracks[1]); got != 30 {
+		t.Errorf("second file's position = %v, want 30", got)
+	}
+
+	// Playing to the end marks the file finished; it starts over next time
+	sim.Finish()
+	app.syncAudioState()
+	if got, _ := app.audiobookFraction(app.Library.Tracks[0]); got != 1 {
+		t.Errorf("finished file's progress = %v, want 1", got)
+	}
+	if got := app.audiobookResumePosition(app.Library.Tracks[0]); got != 0 {
+		t.Errorf("finished file resumes at %v, want 0", got)
+	}
+
+	// Positions survive a restart
+	saved := app.AudiobookProgress
+	app.loadAudiobookProgress()
+	if p := app.AudiobookProgress[trackPath(2)]; p == nil || p.Position != saved[trackPath(2)].Position {
+		t.Errorf("reloaded progress = %+v", p)
+	}
+}
+
+func TestAudiobookProgressOnlyForAudiobookFolders(t *testing.T) {
+	app, sim := newTestApp(t, 2)
+	app.AudiobookFolders = []string{"/podcasts/"}
+	playQueue(t, app, 0)
+
+	sim.Advance(60 * time.Second)
+	app.syncAudioState()
+	app.nextTrack()
+	if len(app.AudiobookProgress) != 0 {
+		t.Errorf("recorded music files: %v", app.AudiobookProgress)
+	}
+	app.playTrackFromList(app.Library.Tracks, 0)
+	if got := sim.State().Position; got != 0 {
+		t.Errorf("music file resumed at %v", got)
+	}
+}
+
+func TestContinueListeningOrder(t *testing.T) {
+	app, _ := newTestApp(t, 4)
+	app.AudiobookFolders = []string{"/music/"}
+	app.AudiobookProgress = map[string]*AudiobookProgress{
+		trackPath(1):      {Position: 10, UpdatedAt: 100},
+		trackPath(2):      {Position: 10, UpdatedAt: 300, Finished: true},
+		trackPath(3):      {Position: 10, UpdatedAt: 200},
+		"/music/gone.mp3": {Position: 10, UpdatedAt: 400},
+	}
+
+	items := app.buildContinueListeningMenuItems()
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	if fmt.Sprint(labels) != "[Track 3 Track 1]" {
+		t.Fatalf("items = %v, want the unfinished files, latest first", labels)
+	}
+
+	// Playing one queues its whole folder from there
+	items[0].Action()
+	if currentPath(app) != trackPath(3) || len(app.Queue.Tracks) != 4 || app.Queue.CurrentIndex != 2 {
+		t.Errorf("playing %s at %d of %d", currentPath(app), app.Queue.CurrentIndex, len(app.Queue.Tracks))
+	}
+}
+
+func TestAudiobookSpeedAndSkips(t *testing.T) {
+	SETTINGS_PATH = filepath.Join(t.TempDir(), "settings.json")
+	defer os.Remove(SETTINGS_PATH)
+
+	app, sim := newTestApp(t, 2)
+	app.AudiobookFolders = []string{"/music/"}
+	app.AudiobookSpeed = 1.5
+	playQueue(t, app, 0)
+
+	if sim.Speed() != 1.5 || app.Playing.Speed != 1.5 {
+		t.Fatalf("speed = %v (shown %v), want 1.5", sim.Speed(), app.Playing.Speed)
+	}
+	sim.Advance(40 * time.Second)
+	app.syncAudioState()
+	if app.Playing.Position != 60 {
+		t.Errorf("position after 40s at 1.5x = %v, want 60", app.Playing.Position)
+	}
+
+	app.skipSeconds(-AUDIOBOOK_SKIP_SECONDS)
+	if app.Playing.Position != 30 {
+		t.Errorf("position after skipping back = %v, want 30", app.Playing.Position)
+	}
+	app.skipSeconds(AUDIOBOOK_SKIP_SECONDS)
+	app.skipSeconds(AUDIOBOOK_SKIP_SECONDS)
+	if app.Playing.Position != 90 {
+		t.Errorf("position after skipping forward = %v, want 90", app.Playing.Position)
+	}
+
+	// Changing the setting applies to the playing file
+	app.cycleAudiobookSpeed()
+	if app.AudiobookSpeed != 1.75 || sim.Speed() != 1.75 {
+		t.Errorf("speed after cycling = %v (audio %v), want 1.75", app.AudiobookSpeed, sim.Speed())
+	}
+
+	// Music plays at normal speed
+	app.AudiobookFolders = nil
+	app.playTrackFromList(app.Library.Tracks, 1)
+	if sim.Speed() != 1 || app.Playing.Speed != 1 {
+		t.Errorf("music speed = %v, want 1", sim.Speed())
+	}
+}
+
+func TestAudiobookSpeedOnlyForMP3(t *testing.T) {
+	prevSettings := SETTINGS_PATH
+	SETTINGS_PATH = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() { SETTINGS_PATH = prevSettings })
+
+	app, sim := newTestApp(t, 0)
+	book := &Track{Path: "/music/Book/01.flac", Title: "Chapter 1", Duration: SIM_DEFAULT_DURATION}
+	app.Library.addTrack(book, nil)
+	app.AudiobookFolders = []string{"/music/"}
+	app.AudiobookSpeed = 1.5
+	playQueue(t, app, 0)
+
+	// The FLAC plays at normal speed, and the speed is shown greyed out
+	if sim.Speed() != 1 || app.Playing.Speed != 1 || !app.Playing.SpeedBlocked {
+		t.Errorf("speed %v (shown %v, blocked %v), want 1 and blocked", sim.Speed(), app.Playing.Speed, app.Playing.SpeedBlocked)
+	}
+
+	// Changing the speed says why it has no effect
+	app.cycleAudiobookSpeed()
+	if app.ErrorMessage == "" || sim.Speed() != 1 {
+		t.Errorf("after cycling: error %q, speed %v; want an error at 1", app.ErrorMessage, sim.Speed())
+	}
+
+	// Back at 1.0x there's nothing to grey out
+	app.AudiobookSpeed = 1.0
+	app.applyPlaybackSpeed(book)
+	if app.Playing.SpeedBlocked {
+		t.Error("still blocked at normal speed")
+	}
+}
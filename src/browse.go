@@ -0,0 +1,277 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// trackGroup is a named set of tracks used by the Genres and Composers menus
+type trackGroup struct {
+	Name   string
+	Tracks []*Track
+}
+
+// groupTracks buckets tracks by a case-insensitive key, keeping the first
+// spelling seen for display. Tracks with an empty key are left out.
+func groupTracks(tracks []*Track, keyOf func(*Track) string) []*trackGroup {
+	return groupTracksBy(tracks, func(t *Track) []string { return []string{keyOf(t)} })
+}
+
+// groupTracksBy is groupTracks for fields with several values (artists,
+// genres): a track joins the group of each of its keys
+func groupTracksBy(tracks []*Track, keysOf func(*Track) []string) []*trackGroup {
+	byKey := make(map[string]*trackGroup)
+	var groups []*trackGroup
+
+	for _, t := range tracks {
+		for _, name := range keysOf(t) {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				continue
+			}
+			key := strings.ToLower(name)
+			g, ok := byKey[key]
+			if !ok {
+				g = &trackGroup{Name: name}
+				byKey[key] = g
+				groups = append(groups, g)
+			}
+			if n := len(g.Tracks); n == 0 || g.Tracks[n-1] != t {
+				g.Tracks = append(g.Tracks, t)
+			}
+		}
+	}
+
+	sortByKey(groups, func(g *trackGroup) string { return sortKey(g.Name, "") })
+	return groups
+}
+
+// hasTrackField reports whether any library track has a non-empty value for field
+func (app *MiyooPod) hasTrackField(field func(*Track) string) bool {
+	for _, t := range app.Library.Tracks {
+		if strings.TrimSpace(field(t)) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// hasYears reports whether any library track has a year tag
+func (app *MiyooPod) hasYears() bool {
+	for _, t := range app.Library.Tracks {
+		if t.Year > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func trackGenre(t *Track) string    { return t.Genre }
+func trackComposer(t *Track) string { return t.Composer }
+
+// trackAlbumArtist returns the artist a track is filed under in the library
+func trackAlbumArtist(t *Track) string {
+	if t.AlbumArtist != "" {
+		return t.AlbumArtist
+	}
+	if t.VariousArtists {
+		return VARIOUS_ARTISTS
+	}
+	return trackArtists(t)[0] // The main artist, without featured ones
+}
+
+// albumYear returns the year of an album's first track with one, or 0
+func albumYear(album *Album) int {
+	for _, t := range album.Tracks {
+		if t != nil && t.Year > 0 {
+			return t.Year
+		}
+	}
+	return 0
+}
+
+// buildGenreMenuItems lists genres; each opens the artists with tracks in it
+func (app *MiyooPod) buildGenreMenuItems(root *MenuScreen) []*MenuItem {
+	genres := groupTracksBy(app.Library.Tracks, trackGenres)
+	items := make([]*MenuItem, 0, len(genres))
+	for _, genre := range genres {
+		g := genre // capture
+		artistMenu := &MenuScreen{
+			Title:  g.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildGenreArtistMenuItems(root, g)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      g.Name,
+			HasSubmenu: true,
+			Submenu:    artistMenu,
+			Tracks:     g.Tracks, // For Y-key queuing
+		})
+	}
+	return items
+}
+
+// buildGenreArtistMenuItems lists artists in a genre; each opens their albums
+// limited to the genre's tracks
+func (app *MiyooPod) buildGenreArtistMenuItems(root *MenuScreen, genre *trackGroup) []*MenuItem {
+	artists := groupTracks(genre.Tracks, trackAlbumArtist)
+	items := make([]*MenuItem, 0, len(artists))
+	for _, artist := range artists {
+		a := artist // capture
+		albumMenu := &MenuScreen{
+			Title:  a.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildTrackAlbumMenuItems(root, a.Tracks)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      a.Name,
+			HasSubmenu: true,
+			Submenu:    albumMenu,
+			Tracks:     a.Tracks, // For Y-key queuing
+		})
+	}
+	return items
+}
+
+// buildTrackAlbumMenuItems lists the albums a set of tracks belongs to. Each
+// album opens only the given tracks, in disc/track order.
+func (app *MiyooPod) buildTrackAlbumMenuItems(root *MenuScreen, tracks []*Track) []*MenuItem {
+	var albums []*Album
+	tracksByAlbum := make(map[*Album][]*Track)
+	for _, t := range tracks {
+		album := app.Library.AlbumsByKey[albumKeyFor(t)]
+		if album == nil {
+			continue
+		}
+		if _, seen := tracksByAlbum[album]; !seen {
+			albums = append(albums, album)
+		}
+		tracksByAlbum[album] = append(tracksByAlbum[album], t)
+	}
+
+	sortByKey(albums, albumSortKey)
+
+	items := make([]*MenuItem, 0, len(albums))
+	for _, album := range albums {
+		alb := album                    // capture
+		albTracks := tracksByAlbum[alb] // capture
+		trackMenu := &MenuScreen{
+			Title:  alb.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildTrackMenuItemsWithNumbers(albTracks, true)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      alb.Name,
+			HasSubmenu: true,
+			Submenu:    trackMenu,
+			Album:      alb, // Store album reference for preview
+		})
+	}
+	return items
+}
+
+// buildComposerMenuItems lists composers; each opens their tracks
+func (app *MiyooPod) buildComposerMenuItems(root *MenuScreen) []*MenuItem {
+	composers := groupTracks(app.Library.Tracks, trackComposer)
+	items := make([]*MenuItem, 0, len(composers))
+	for _, composer := range composers {
+		c := composer // capture
+		trackMenu := &MenuScreen{
+			Title:  c.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				tracks := make([]*Track, len(c.Tracks))
+				copy(tracks, c.Tracks)
+				sortByKey(tracks, trackSortKey)
+				return app.buildTrackMenuItems(tracks)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      c.Name,
+			HasSubmenu: true,
+			Submenu:    trackMenu,
+			Tracks:     c.Tracks, // For Y-key queuing
+		})
+	}
+	return items
+}
+
+// buildDecadeMenuItems lists decades, newest first; each opens its albums by year
+func (app *MiyooPod) buildDecadeMenuItems(root *MenuScreen) []*MenuItem {
+	albumsByDecade := make(map[int][]*Album)
+	for _, album := range app.Library.Albums {
+		if year := albumYear(album); year > 0 {
+			decade := year / 10 * 10
+			albumsByDecade[decade] = append(albumsByDecade[decade], album)
+		}
+	}
+
+	decades := make([]int, 0, len(albumsByDecade))
+	for d := range albumsByDecade {
+		decades = append(decades, d)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(decades)))
+
+	items := make([]*MenuItem, 0, len(decades))
+	for _, decade := range decades {
+		albums := albumsByDecade[decade] // capture
+		var tracks []*Track
+		for _, album := range albums {
+			tracks = append(tracks, album.Tracks...)
+		}
+
+		albumMenu := &MenuScreen{
+			Title:  fmt.Sprintf("%ds", decade),
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildYearAlbumMenuItems(root, albums)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      fmt.Sprintf("%ds", decade),
+			HasSubmenu: true,
+			Submenu:    albumMenu,
+			Tracks:     tracks, // For Y-key queuing
+		})
+	}
+	return items
+}
+
+// buildYearAlbumMenuItems lists albums sorted by year, labelled with the year
+func (app *MiyooPod) buildYearAlbumMenuItems(root *MenuScreen, albums []*Album) []*MenuItem {
+	sorted := make([]*Album, len(albums))
+	copy(sorted, albums)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		yi, yj := albumYear(sorted[i]), albumYear(sorted[j])
+		if yi != yj {
+			return yi < yj
+		}
+		return albumSortKey(sorted[i]) < albumSortKey(sorted[j])
+	})
+
+	items := make([]*MenuItem, 0, len(sorted))
+	for _, album := range sorted {
+		alb := album // capture
+		trackMenu := &MenuScreen{
+			Title:  alb.Name + " - " + alb.Artist,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildTrackMenuItemsWithNumbers(alb.Tracks, true)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      fmt.Sprintf("%d  %s - %s", albumYear(alb), alb.Name, alb.Artist),
+			HasSubmenu: true,
+			Submenu:    trackMenu,
+			Album:      alb, // Store album reference for preview
+		})
+	}
+	return items
+}
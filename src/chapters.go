@@ -0,0 +1,166 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/dhowden/tag"
+)
+
+// Chapter is a named start position within a file, from an ID3 CHAP frame
+type Chapter struct {
+	Title string  `json:"title"`
+	Start float64 `json:"start"` // Seconds from the start of the file
+}
+
+// readChapters reads the chapter markers of an MP3's ID3v2 tag: its CHAP
+// frames, in the order of the top-level CTOC table of contents when there is
+// one, otherwise by start time
+func readChapters(track *Track, m tag.Metadata, r io.ReadSeeker) {
+	track.Chapters = nil
+	if m.Format() != tag.ID3v2_3 && m.Format() != tag.ID3v2_4 {
+		return
+	}
+
+	frames, version := readID3FrameList(r, "CHAP", "CTOC")
+	byID := make(map[string]Chapter)
+	var all []Chapter
+	var toc []string
+	for _, f := range frames {
+		// Both start with a NUL-terminated element ID
+		id, rest := splitID3String(0, f.Body)
+
+		switch f.ID {
+		case "CHAP":
+			// Start and end time (ms), start and end byte offset, sub-frames
+			if len(rest) < 16 {
+				continue
+			}
+			ch := Chapter{Start: float64(binary.BigEndian.Uint32(rest[:4])) / 1000}
+			for _, sub := range splitID3Frames(rest[16:], version) {
+				if sub.ID == "TIT2" {
+					if v := decodeID3Text(sub.Body[0], sub.Body[1:]); len(v) > 0 {
+						ch.Title = v[0]
+					}
+				}
+			}
+			byID[id] = ch
+			all = append(all, ch)
+		case "CTOC":
+			// Flags (0x02: top level), entry count, child element IDs
+			if len(rest) < 2 || rest[0]&0x02 == 0 || toc != nil {
+				continue
+			}
+			count := int(rest[1])
+			rest = rest[2:]
+			toc = []string{}
+			for i := 0; i < count && len(rest) > 0; i++ {
+				var child string
+				child, rest = splitID3String(0, rest)
+				toc = append(toc, child)
+			}
+		}
+	}
+
+	var chapters []Chapter
+	for _, id := range toc {
+		if ch, ok := byID[id]; ok {
+			chapters = append(chapters, ch)
+		}
+	}
+	if len(chapters) == 0 {
+		// No usable table of contents (or one listing only sub-tables)
+		chapters = all
+		sort.SliceStable(chapters, func(i, j int) bool { return chapters[i].Start < chapters[j].Start })
+	}
+
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = fmt.Sprintf("Chapter %d", i+1)
+		}
+	}
+	track.Chapters = chapters
+}
+
+// chapterIndex returns the chapter playing at pos, or -1 if the track has no
+// chapters. Positions a moment before a chapter's start count as in it, since
+// seeks don't always land exactly.
+func chapterIndex(track *Track, pos float64) int {
+	if track == nil || len(track.Chapters) == 0 {
+		return -1
+	}
+	idx := 0
+	for i, ch := range track.Chapters {
+		if ch.Start <= pos+0.5 {
+			idx = i
+		}
+	}
+	return idx
+}
+
+// playingChapter returns the index of the chapter playing, or -1
+func (app *MiyooPod) playingChapter() int {
+	if app.Playing == nil || app.Playing.State == StateStopped {
+		return -1
+	}
+	return chapterIndex(app.Playing.Track, app.Playing.Position)
+}
+
+// prevChapter is L on a track with chapters: it restarts the chapter, or
+// goes to the previous one within its first 3 seconds, like prevTrack does
+// with tracks. Before the first chapter it falls back to prevTrack.
+func (app *MiyooPod) prevChapter() {
+	idx := app.playingChapter()
+	if idx < 0 {
+		app.prevTrack()
+		return
+	}
+	chapters := app.Playing.Track.Chapters
+	if app.Playing.Position-chapters[idx].Start <= 3.0 {
+		if idx == 0 {
+			app.prevTrack()
+			return
+		}
+		idx--
+	}
+	app.seekToChapter(idx)
+}
+
+// nextChapter is R on a track with chapters; after the last chapter it
+// falls back to nextTrack
+func (app *MiyooPod) nextChapter() {
+	idx := app.playingChapter()
+	if idx < 0 || idx+1 >= len(app.Playing.Track.Chapters) {
+		app.nextTrack()
+		return
+	}
+	app.seekToChapter(idx + 1)
+}
+
+// seekToChapter jumps to the start of chapter idx of the playing track
+func (app *MiyooPod) seekToChapter(idx int) {
+	ch := app.Playing.Track.Chapters[idx]
+	app.seekAudio(ch.Start)
+	app.Playing.Position = ch.Start
+	app.ChapterShown = idx
+	app.NPCacheDirty = true
+
+	logMsg(fmt.Sprintf("INFO: Chapter %d/%d: %s", idx+1, len(app.Playing.Track.Chapters), ch.Title))
+}
+
+// checkChapterChange redraws Now Playing, whose cached background holds the
+// chapter title, when playback moves into another chapter. Called by the
+// playback poller every second.
+func (app *MiyooPod) checkChapterChange() {
+	idx := app.playingChapter()
+	if idx == app.ChapterShown {
+		return
+	}
+	app.ChapterShown = idx
+	app.NPCacheDirty = true
+	if app.CurrentScreen == ScreenNowPlaying {
+		app.requestRedraw()
+	}
+}
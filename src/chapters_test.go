@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dhowden/tag"
+)
+
+func TestReadChapters(t *testing.T) {
+	// ID3v2.3 sizes are plain big-endian
+	frame := func(id string, body []byte) []byte {
+		n := len(body)
+		return append(append([]byte(id), byte(n>>24), byte(n>>16), byte(n>>8), byte(n), 0, 0), body...)
+	}
+	chap := func(id string, startMs int, title string) []byte {
+		body := append([]byte(id), 0, byte(startMs>>24), byte(startMs>>16), byte(startMs>>8), byte(startMs))
+		body = append(body, 0, 0, 0, 0, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF, 0xFF)
+		if title != "" {
+			body = append(body, frame("TIT2", append([]byte{0}, title...))...)
+		}
+		return frame("CHAP", body)
+	}
+
+	var frames []byte
+	frames = append(frames, frame("TIT2", []byte("\x00Book"))...)
+	frames = append(frames, chap("c2", 754500, "The Middle")...)
+	frames = append(frames, chap("c1", 0, "Introduction")...)
+	frames = append(frames, chap("c3", 1500000, "")...)
+	// Top-level, ordered table of contents
+	frames = append(frames, frame("CTOC", []byte("toc\x00\x03\x03c1\x00c2\x00c3\x00"))...)
+	frames = append(frames, make([]byte, 16)...) // Padding
+
+	n := len(frames)
+	data := append([]byte{'I', 'D', '3', 3, 0, 0, byte(n >> 21 & 0x7F), byte(n >> 14 & 0x7F), byte(n >> 7 & 0x7F), byte(n & 0x7F)}, frames...)
+
+	r := bytes.NewReader(data)
+	m, err := tag.ReadFrom(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	track := &Track{}
+	readChapters(track, m, r)
+
+	want := "[{Introduction 0} {The Middle 754.5} {Chapter 3 1500}]"
+	if got := fmt.Sprint(track.Chapters); got != want {
+		t.Errorf("chapters = %s, want %s", got, want)
+	}
+}
+
+func TestChapterNavigation(t *testing.T) {
+	app, sim := newTestApp(t, 2)
+	app.Library.Tracks[0].Chapters = []Chapter{{"One", 0}, {"Two", 60}, {"Three", 120}}
+	playQueue(t, app, 0)
+	app.setScreen(ScreenNowPlaying)
+
+	tap := func(key Key) {
+		direction := 1
+		if key == L {
+			direction = -1
+		}
+		app.seekKeyPressed(direction)
+		app.handleKeyRelease(key)
+	}
+
+	sim.Advance(70 * time.Second)
+	app.syncAudioState()
+	tap(R)
+	if app.Playing.Position != 120 || app.playingChapter() != 2 {
+		t.Fatalf("R went to %v, want chapter three at 120", app.Playing.Position)
+	}
+
+	// Within 3 seconds of a chapter start L goes to the previous one,
+	// later it restarts the chapter
+	tap(L)
+	if app.Playing.Position != 60 {
+		t.Errorf("L at a chapter start went to %v, want 60", app.Playing.Position)
+	}
+	sim.Advance(10 * time.Second)
+	app.syncAudioState()
+	tap(L)
+	if app.Playing.Position != 60 {
+		t.Errorf("L mid-chapter went to %v, want 60", app.Playing.Position)
+	}
+
+	// R in the last chapter moves on to the next track
+	app.seekToChapter(2)
+	tap(R)
+	if currentPath(app) != trackPath(2) {
+		t.Errorf("R in the last chapter played %s, want the next track", currentPath(app))
+	}
+}
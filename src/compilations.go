@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// VARIOUS_ARTISTS is the album artist compilations are filed under
+const VARIOUS_ARTISTS = "Various Artists"
+
+// readCompilationFlag reports whether the iTunes compilation flag is set
+// (ID3 TCMP/TCP, MP4 cpil, Vorbis COMPILATION)
+func readCompilationFlag(m tag.Metadata) bool {
+	for key, value := range m.Raw() {
+		switch strings.ToLower(key) {
+		case "tcmp", "tcp", "cpil", "compilation":
+		default:
+			continue
+		}
+		switch v := value.(type) {
+		case int:
+			return v != 0
+		case string:
+			s := strings.TrimSpace(v)
+			return s != "" && s != "0"
+		}
+	}
+	return false
+}
+
+// markCompilations decides which tracks are filed under Various Artists.
+// Tracks without an album artist qualify when they are flagged as a
+// compilation, or when their folder holds an album whose tracks are spread
+// over several artists with none of them on more than half.
+func markCompilations(tracks []*Track) {
+	type albumGroup struct {
+		tracks  []*Track
+		artists map[string]int
+		flagged bool
+	}
+	groups := make(map[string]*albumGroup)
+
+	for _, t := range tracks {
+		t.VariousArtists = false
+		if t.AlbumArtist != "" || t.Album == "" || t.Album == "Unknown Album" {
+			continue
+		}
+		key := filepath.Dir(t.Path) + "|" + strings.ToLower(t.Album)
+		g, ok := groups[key]
+		if !ok {
+			g = &albumGroup{artists: make(map[string]int)}
+			groups[key] = g
+		}
+		g.tracks = append(g.tracks, t)
+		g.artists[strings.ToLower(trackArtists(t)[0])]++
+		g.flagged = g.flagged || t.Compilation
+	}
+
+	for _, g := range groups {
+		mixed := len(g.artists) > 1
+		for _, n := range g.artists {
+			if n*2 > len(g.tracks) {
+				mixed = false // Mostly one artist, e.g. a few guest tracks
+			}
+		}
+		if g.flagged || mixed {
+			for _, t := range g.tracks {
+				t.VariousArtists = true
+			}
+		}
+	}
+}
+
+// isCompilationTrack reports whether a track is filed under Various Artists,
+// whether detected or tagged that way
+func isCompilationTrack(t *Track) bool {
+	return strings.EqualFold(trackAlbumArtist(t), VARIOUS_ARTISTS)
+}
+
+// compilationAlbums returns the albums filed under Various Artists
+func (app *MiyooPod) compilationAlbums() []*Album {
+	var albums []*Album
+	for _, album := range app.Library.Albums {
+		if strings.EqualFold(album.Artist, VARIOUS_ARTISTS) {
+			albums = append(albums, album)
+		}
+	}
+	return albums
+}
+
+// buildCompilationMenuItems lists compilation albums; each opens its tracks
+// labelled with their artists
+func (app *MiyooPod) buildCompilationMenuItems(root *MenuScreen) []*MenuItem {
+	albums := app.compilationAlbums()
+	items := make([]*MenuItem, 0, len(albums))
+	for _, album := range albums {
+		alb := album // capture
+		trackMenu := &MenuScreen{
+			Title:  alb.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				items := app.buildTrackMenuItemsWithNumbers(alb.Tracks, true)
+				for _, item := range items {
+					item.Label += " - " + item.Track.Artist
+				}
+				return items
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      alb.Name,
+			HasSubmenu: true,
+			Submenu:    trackMenu,
+			Album:      alb, // Store album reference for preview
+		})
+	}
+	return items
+}
+
+// onlyCompilations reports whether every track is filed under Various Artists
+func onlyCompilations(tracks []*Track) bool {
+	for _, t := range tracks {
+		if !isCompilationTrack(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// toggleCompilationArtists shows or hides compilation-only artists (and
+// Various Artists itself) in the Artists menu
+func (app *MiyooPod) toggleCompilationArtists() {
+	app.HideCompilationArtists = !app.HideCompilationArtists
+	app.refreshSettingsMenu()
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save compilation artists setting: %v", err))
+	}
+}
+
+// sortArtistItems orders Artists menu items the way the library orders
+// artists; artists without albums use their tracks' artist sort tag when it
+// names only them
+func sortArtistItems(items []*MenuItem) {
+	sortByKey(items, func(item *MenuItem) string {
+		if item.Artist != nil {
+			return artistSortKey(item.Artist)
+		}
+		for _, t := range item.Tracks {
+			if len(t.Artists) <= 1 && strings.EqualFold(t.Artist, item.Label) {
+				return sortKey(item.Label, t.SortArtist)
+			}
+		}
+		return sortKey(item.Label, "")
+	})
+}
@@ -0,0 +1,48 @@
+package main
+
+import "testing"
+
+func TestMarkCompilations(t *testing.T) {
+	track := func(path, artist, album, albumArtist string, flagged bool) *Track {
+		return &Track{Path: path, Artist: artist, Album: album, AlbumArtist: albumArtist, Compilation: flagged}
+	}
+	mixed := []*Track{
+		track("/m/Hits/01.mp3", "A", "Hits", "", false),
+		track("/m/Hits/02.mp3", "B", "Hits", "", false),
+		track("/m/Hits/03.mp3", "C", "Hits", "", false),
+	}
+	guest := []*Track{
+		track("/m/Solo/01.mp3", "Solo", "Solo", "", false),
+		track("/m/Solo/02.mp3", "Solo", "Solo", "", false),
+		track("/m/Solo/03.mp3", "Solo feat. Guest", "Solo", "", false),
+	}
+	flagged := []*Track{
+		track("/m/Flagged/01.mp3", "D", "Flagged", "", true),
+		track("/m/Flagged/02.mp3", "D", "Flagged", "", false),
+	}
+	tagged := []*Track{
+		track("/m/Tagged/01.mp3", "E", "Tagged", "Compiler", false),
+		track("/m/Tagged/02.mp3", "F", "Tagged", "Compiler", true),
+	}
+	// Same album name in another folder is a different album
+	elsewhere := []*Track{track("/m/Other/01.mp3", "A", "Hits", "", false)}
+
+	var all []*Track
+	for _, set := range [][]*Track{mixed, guest, flagged, tagged, elsewhere} {
+		all = append(all, set...)
+	}
+	markCompilations(all)
+
+	check := func(name string, tracks []*Track, wantArtist string) {
+		for _, tr := range tracks {
+			if got := trackAlbumArtist(tr); got != wantArtist {
+				t.Errorf("%s: %s filed under %q, want %q", name, tr.Path, got, wantArtist)
+			}
+		}
+	}
+	check("mixed artists", mixed, VARIOUS_ARTISTS)
+	check("guest track", guest[:2], "Solo")
+	check("compilation flag", flagged, VARIOUS_ARTISTS)
+	check("album artist tag", tagged, "Compiler")
+	check("other folder", elsewhere, "A")
+}
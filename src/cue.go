@@ -0,0 +1,448 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cueSheet is a parsed .cue file: album details and its audio tracks
+type cueSheet struct {
+	Title      string
+	Performer  string
+	Songwriter string
+	Genre      string
+	Year       int
+	AlbumGain  float64 // REM REPLAYGAIN_ALBUM_GAIN (dB), 0 when absent
+	Tracks     []cueTrack
+}
+
+// cueTrack is one TRACK of a cue sheet
+type cueTrack struct {
+	File       string // Audio file as written in the sheet's FILE line
+	Number     int
+	Title      string
+	Performer  string
+	Songwriter string
+	Start      float64 // INDEX 01, in seconds into File
+	Gain       float64 // REM REPLAYGAIN_TRACK_GAIN (dB), 0 when absent
+}
+
+// isCueFile reports whether a path is a cue sheet
+func isCueFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".cue")
+}
+
+// readCueSheet reads and parses a cue sheet
+func readCueSheet(path string) (*cueSheet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Cue sheets written by Windows rippers are often Latin-1
+	text := string(data)
+	if !utf8.ValidString(text) {
+		text = decodeID3String(0, data)
+	}
+	sheet := parseCueSheet(text)
+	if len(sheet.Tracks) == 0 {
+		return nil, fmt.Errorf("no audio tracks")
+	}
+	return sheet, nil
+}
+
+// parseCueSheet parses the text of a cue sheet. Only audio tracks with an
+// INDEX 01 are kept; pregaps (INDEX 00) belong to the previous track, even
+// when they sit at the start of a track's file or the end of the one before.
+func parseCueSheet(text string) *cueSheet {
+	sheet := &cueSheet{}
+	text = strings.TrimPrefix(text, "\uFEFF")
+
+	file := ""
+	var track *cueTrack // Track being read, nil before the first
+	audio := false
+	hasStart := false
+	finish := func() {
+		if track != nil && audio && hasStart {
+			sheet.Tracks = append(sheet.Tracks, *track)
+		}
+		track = nil
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		fields := cueFields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		arg := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+			return ""
+		}
+
+		switch strings.ToUpper(fields[0]) {
+		case "FILE":
+			file = strings.ReplaceAll(arg(1), `\`, "/")
+			// A track whose INDEX 01 comes after the FILE line starts in the
+			// new file; only its pregap is in the old one ("gaps appended")
+			if track != nil && !hasStart {
+				track.File = file
+			} else {
+				finish()
+			}
+		case "TRACK":
+			finish()
+			number, _ := strconv.Atoi(arg(1))
+			track = &cueTrack{File: file, Number: number}
+			audio = strings.EqualFold(arg(2), "AUDIO")
+			hasStart = false
+		case "INDEX":
+			if track != nil && arg(1) == "01" {
+				if start, ok := parseCueTime(arg(2)); ok {
+					track.Start = start
+					hasStart = true
+				}
+			}
+		case "TITLE":
+			if track != nil {
+				track.Title = arg(1)
+			} else {
+				sheet.Title = arg(1)
+			}
+		case "PERFORMER":
+			if track != nil {
+				track.Performer = arg(1)
+			} else {
+				sheet.Performer = arg(1)
+			}
+		case "SONGWRITER":
+			if track != nil {
+				track.Songwriter = arg(1)
+			} else {
+				sheet.Songwriter = arg(1)
+			}
+		case "REM":
+			value := strings.Join(fields[min(2, len(fields)):], " ")
+			switch strings.ToUpper(arg(1)) {
+			case "GENRE":
+				sheet.Genre = value
+			case "DATE":
+				if len(value) >= 4 {
+					sheet.Year, _ = strconv.Atoi(value[:4])
+				}
+			case "REPLAYGAIN_ALBUM_GAIN":
+				sheet.AlbumGain, _ = parseGainDB(value)
+			case "REPLAYGAIN_TRACK_GAIN":
+				if track != nil {
+					track.Gain, _ = parseGainDB(value)
+				}
+			}
+		}
+	}
+	finish()
+	return sheet
+}
+
+// cueFields splits a cue sheet line into its command and arguments, keeping
+// quoted arguments whole
+func cueFields(line string) []string {
+	var fields []string
+	line = strings.TrimSpace(line)
+	for line != "" {
+		var field string
+		if line[0] == '"' {
+			end := strings.IndexByte(line[1:], '"')
+			if end < 0 {
+				field, line = line[1:], ""
+			} else {
+				field, line = line[1:end+1], line[end+2:]
+			}
+		} else if i := strings.IndexAny(line, " \t"); i >= 0 {
+			field, line = line[:i], line[i:]
+		} else {
+			field, line = line, ""
+		}
+		fields = append(fields, field)
+		line = strings.TrimLeft(line, " \t")
+	}
+	return fields
+}
+
+// parseCueTime parses an mm:ss:ff cue time (75 frames a second) into seconds
+func parseCueTime(s string) (float64, bool) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return 0, false
+	}
+	var n [3]int
+	for i, p := range parts {
+		v, err := strconv.Atoi(p)
+		if err != nil || v < 0 {
+			return 0, false
+		}
+		n[i] = v
+	}
+	return float64(n[0]*60+n[1]) + float64(n[2])/75, true
+}
+
+// cueTrackPath is the library path of track number of a file split by a cue
+// sheet. Cue sheets have at most 99 tracks, so the paths sort in track order.
+func cueTrackPath(file string, number int) string {
+	return fmt.Sprintf("%s#%02d", file, number)
+}
+
+// trackFile returns the audio file a track plays from: its own path, or the
+// file a cue sheet split it out of
+func trackFile(t *Track) string {
+	if t.File != "" {
+		return t.File
+	}
+	return t.Path
+}
+
+// splitFile returns the scanned file a cue track was split from, or a bare
+// track for the file if the library no longer has it
+func (lib *Library) splitFile(t *Track) *Track {
+	for _, file := range lib.SplitFiles {
+		if file.Path == t.File {
+			return file
+		}
+	}
+	return &Track{Path: t.File, Artist: t.AlbumArtist, Title: t.Album}
+}
+
+// splitByCueSheets replaces the scanned files that a cue sheet divides into
+// several tracks with one track per cue track. It returns the new track list
+// and the files that were split, which the library keeps so a rescan can
+// reuse their tags.
+func splitByCueSheets(scanned []*Track, cues []string) (tracks, split []*Track) {
+	if len(cues) == 0 {
+		return scanned, nil
+	}
+
+	byPath := make(map[string]*Track, len(scanned))
+	byFold := make(map[string]*Track, len(scanned))
+	for _, t := range scanned {
+		byPath[t.Path] = t
+		byFold[strings.ToLower(t.Path)] = t
+	}
+	// resolve finds the scanned file a FILE line names. Sheets often name
+	// the WAV the album was ripped to, so other audio extensions are tried.
+	resolve := func(dir, name string) *Track {
+		path := filepath.Join(dir, name)
+		stem := strings.TrimSuffix(path, filepath.Ext(path))
+		candidates := []string{path}
+		for ext := range audioFormats {
+			candidates = append(candidates, stem+ext)
+		}
+		for _, p := range candidates {
+			if t := byPath[p]; t != nil {
+				return t
+			}
+			if t := byFold[strings.ToLower(p)]; t != nil {
+				return t
+			}
+		}
+		return nil
+	}
+
+	replaced := make(map[string][]*Track)
+	for _, cue := range cues {
+		sheet, err := readCueSheet(cue)
+		if err != nil {
+			logMsg(fmt.Sprintf("WARNING: Skipping cue sheet %s: %v", cue, err))
+			continue
+		}
+
+		// Tracks grouped by the file they are in, in sheet order
+		var files []*Track
+		groups := make(map[*Track][]cueTrack)
+		missing := make(map[string]bool)
+		for _, ct := range sheet.Tracks {
+			file := resolve(filepath.Dir(cue), ct.File)
+			if file == nil {
+				if !missing[ct.File] {
+					missing[ct.File] = true
+					logMsg(fmt.Sprintf("WARNING: Cue sheet %s: file not found: %s", cue, ct.File))
+				}
+				continue
+			}
+			if _, seen := groups[file]; !seen {
+				files = append(files, file)
+			}
+			groups[file] = append(groups[file], ct)
+		}
+
+		for _, file := range files {
+			// A file holding a single track keeps its own tags
+			if len(groups[file]) < 2 {
+				continue
+			}
+			if _, done := replaced[file.Path]; done {
+				logMsg(fmt.Sprintf("WARNING: Cue sheet %s: %s is already split by another cue sheet", cue, file.Path))
+				continue
+			}
+			replaced[file.Path] = cueTracks(sheet, groups[file], file)
+		}
+	}
+
+	for _, t := range scanned {
+		if virtual, ok := replaced[t.Path]; ok {
+			tracks = append(tracks, virtual...)
+			split = append(split, t)
+		} else {
+			tracks = append(tracks, t)
+		}
+	}
+	if len(split) > 0 {
+		logMsg(fmt.Sprintf("INFO: Cue sheets split %d files into %d tracks",
+			len(split), len(tracks)-len(scanned)+len(split)))
+	}
+	return tracks, split
+}
+
+// cueTracks builds the tracks a cue sheet splits file into. Each starts as a
+// copy of the file's track, so the file's tags fill in whatever the sheet
+// leaves out.
+func cueTracks(sheet *cueSheet, cts []cueTrack, file *Track) []*Track {
+	tracks := make([]*Track, 0, len(cts))
+	for i, ct := range cts {
+		t := *file
+		t.Path = cueTrackPath(file.Path, ct.Number)
+		t.File = file.Path
+		t.Start = ct.Start
+		t.End = 0 // The last track plays to the end of the file
+		if i+1 < len(cts) {
+			t.End = cts[i+1].Start
+		}
+		t.Duration = 0
+		if t.End > 0 {
+			t.Duration = t.End - t.Start
+		} else if file.Duration > t.Start {
+			t.Duration = file.Duration - t.Start
+		}
+		t.Chapters = nil
+
+		t.Title = ct.Title
+		if t.Title == "" {
+			t.Title = fmt.Sprintf("Track %02d", ct.Number)
+		}
+		t.SortTitle = ""
+		t.TrackNum = ct.Number
+		t.TrackTotal = len(sheet.Tracks)
+		if sheet.Title != "" {
+			t.Album, t.SortAlbum = sheet.Title, ""
+		}
+		if sheet.Performer != "" {
+			t.AlbumArtist, t.SortAlbumArtist = sheet.Performer, ""
+		}
+		if artist := firstNonEmpty(ct.Performer, sheet.Performer); artist != "" && artist != t.Artist {
+			t.Artist, t.SortArtist = artist, ""
+			t.Artists = nil
+			if artists := splitArtists(artist); len(artists) > 1 {
+				t.Artists = artists
+			}
+		}
+		if composer := firstNonEmpty(ct.Songwriter, sheet.Songwriter); composer != "" {
+			t.Composer = composer
+		}
+		if sheet.Genre != "" && sheet.Genre != t.Genre {
+			t.Genre = sheet.Genre
+			t.Genres = nil
+			if genres := splitGenres(sheet.Genre); len(genres) > 1 {
+				t.Genres = genres
+			}
+		}
+		if sheet.Year > 0 {
+			t.Year = sheet.Year
+		}
+
+		// The file's own gain covers the whole album
+		if sheet.AlbumGain != 0 {
+			t.AlbumGain = sheet.AlbumGain
+		} else if t.AlbumGain == 0 {
+			t.AlbumGain, t.AlbumPeak = file.TrackGain, file.TrackPeak
+		}
+		if ct.Gain != 0 {
+			t.TrackGain = ct.Gain
+		}
+
+		tracks = append(tracks, &t)
+	}
+	return tracks
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// trackState converts a backend state, whose position is in the file, into
+// one for track: position from the track's start, duration of the track.
+// Tracks that are whole files are returned unchanged.
+func trackState(track *Track, state AudioStateSnapshot) AudioStateSnapshot {
+	if track == nil || track.File == "" {
+		return state
+	}
+	if state.IsPlaying || state.IsPaused {
+		state.Position = math.Max(state.Position-track.Start, 0)
+	}
+	if track.End > 0 {
+		state.Duration = track.End - track.Start
+	} else if state.Duration > track.Start {
+		state.Duration -= track.Start
+	}
+	return state
+}
+
+// audioState is Audio.State for the playing track (see trackState). A cue
+// track that plays on into the next one in its file is reported like the
+// backend reports a gapless switch, Advanced; one that doesn't is stopped at
+// its end by the backend (see trackEnd), which reports it Finished.
+func (app *MiyooPod) audioState() AudioStateSnapshot {
+	track := app.Playing.Track
+	state := trackState(track, app.Audio.State())
+	if track == nil || track.End <= 0 || state.Advanced || state.Finished {
+		return state
+	}
+	if (state.IsPlaying || state.IsPaused) && state.Position >= state.Duration && app.PreloadedPath != "" {
+		state.Advanced = true
+	}
+	return state
+}
+
+// trackEnd returns where the backend should end track in its file: the end
+// of a cue track that ends mid-file, unless next carries on from it, or 0 to
+// play to the end of the file
+func trackEnd(track, next *Track) float64 {
+	if track == nil || track.End <= 0 || continuesInFile(track, next) {
+		return 0
+	}
+	return track.End
+}
+
+// seekAudio seeks to a position in the playing track
+func (app *MiyooPod) seekAudio(position float64) {
+	app.seekListen(position)
+	if track := app.Playing.Track; track != nil {
+		position += track.Start
+	}
+	app.Audio.Seek(position)
+}
+
+// continuesInFile reports whether next is the cue track that follows track
+// in the same file, so playback can run on into it without loading anything
+func continuesInFile(track, next *Track) bool {
+	return track != nil && next != nil && track.File != "" && next.File == track.File &&
+		track.End > 0 && math.Abs(next.Start-track.End) < 0.01
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCueSheet(t *testing.T) {
+	sheet := parseCueSheet("\uFEFFREM GENRE \"Progressive Rock\"\r\n" +
+		"REM DATE 1973-03-01\r\n" +
+		"REM REPLAYGAIN_ALBUM_GAIN -7.50 dB\r\n" +
+		"PERFORMER \"The Band\"\r\n" +
+		"TITLE \"Live at the Hall\"\r\n" +
+		"FILE \"Disc\\Live.wav\" WAVE\r\n" +
+		"  TRACK 01 AUDIO\r\n" +
+		"    TITLE \"Intro\"\r\n" +
+		"    INDEX 01 00:00:00\r\n" +
+		"  TRACK 02 AUDIO\r\n" +
+		"    TITLE \"Second Song\"\r\n" +
+		"    PERFORMER \"The Band feat. Guest\"\r\n" +
+		"    REM REPLAYGAIN_TRACK_GAIN -6.25 dB\r\n" +
+		"    INDEX 00 03:20:00\r\n" +
+		"    INDEX 01 03:22:37\r\n" +
+		"  TRACK 03 MODE1/2352\r\n" +
+		"    INDEX 01 10:00:00\r\n")
+
+	if sheet.Title != "Live at the Hall" || sheet.Performer != "The Band" || sheet.Genre != "Progressive Rock" ||
+		sheet.Year != 1973 || sheet.AlbumGain != -7.5 {
+		t.Errorf("sheet = %+v", sheet)
+	}
+	var got []string
+	for _, ct := range sheet.Tracks {
+		got = append(got, fmt.Sprintf("%s %d %q %q %.2f %.2f", ct.File, ct.Number, ct.Title, ct.Performer, ct.Start, ct.Gain))
+	}
+	want := `[Disc/Live.wav 1 "Intro" "" 0.00 0.00 Disc/Live.wav 2 "Second Song" "The Band feat. Guest" 202.49 -6.25]`
+	if fmt.Sprint(got) != want {
+		t.Errorf("tracks = %v\nwant     %s", got, want)
+	}
+}
+
+func TestParseCueSheetFilePerTrack(t *testing.T) {
+	// EAC "gaps appended": each pregap sits at the end of the previous file,
+	// so a TRACK's INDEX 00 comes before the FILE holding its INDEX 01
+	sheet := parseCueSheet("FILE \"01 Intro.flac\" WAVE\n" +
+		"  TRACK 01 AUDIO\n" +
+		"    TITLE \"Intro\"\n" +
+		"    INDEX 01 00:00:00\n" +
+		"  TRACK 02 AUDIO\n" +
+		"    TITLE \"Second Song\"\n" +
+		"    INDEX 00 02:58:10\n" +
+		"FILE \"02 Second Song.flac\" WAVE\n" +
+		"    INDEX 01 00:00:00\n" +
+		"  TRACK 03 AUDIO\n" +
+		"    TITLE \"Finale\"\n" +
+		"FILE \"03 Finale.flac\" WAVE\n" +
+		"    INDEX 01 00:00:00\n")
+
+	var got []string
+	for _, ct := range sheet.Tracks {
+		got = append(got, fmt.Sprintf("%s %d %q %.2f", ct.File, ct.Number, ct.Title, ct.Start))
+	}
+	want := `[01 Intro.flac 1 "Intro" 0.00 02 Second Song.flac 2 "Second Song" 0.00 03 Finale.flac 3 "Finale" 0.00]`
+	if fmt.Sprint(got) != want {
+		t.Errorf("tracks = %v\nwant     %s", got, want)
+	}
+}
+
+// writeCueAlbum writes Live/Concert.mp3 and a cue sheet splitting it into
+// three tracks, 10 minutes long in all
+func writeCueAlbum(t *testing.T, root string, sim *simAudio) string {
+	t.Helper()
+
+	dir := filepath.Join(root, "Live")
+	os.MkdirAll(dir, 0755)
+	file := filepath.Join(dir, "Concert.mp3")
+	os.WriteFile(file, []byte("not really audio"), 0644)
+	sim.SetDuration(file, 600)
+
+	// Named after the WAV it was ripped to, as cue sheets often are
+	os.WriteFile(filepath.Join(dir, "Concert.cue"), []byte(`PERFORMER "Live Band"
+TITLE "Concert"
+FILE "Concert.wav" WAVE
+  TRACK 01 AUDIO
+    TITLE "Opening"
+    INDEX 01 00:00:00
+  TRACK 02 AUDIO
+    TITLE "Ballad"
+    INDEX 01 03:20:00
+  TRACK 03 AUDIO
+    TITLE "Encore"
+    INDEX 01 07:30:00
+`), 0644)
+	return file
+}
+
+func TestCueSheetSplitsFileInScan(t *testing.T) {
+	root := useTempMusicRoot(t, 2)
+	app, sim := newTestApp(t, 0)
+	file := writeCueAlbum(t, root, sim)
+
+	runScan(t, app, sim)
+
+	if got := len(app.Library.Tracks); got != 5 {
+		t.Fatalf("library has %d tracks, want 5", got)
+	}
+	var got []string
+	for n := 1; n <= 3; n++ {
+		track := app.Library.TracksByPath[cueTrackPath(file, n)]
+		if track == nil {
+			t.Fatalf("track %d of the cue sheet missing", n)
+		}
+		got = append(got, fmt.Sprintf("%d %s %s/%s %.0f-%.0f %.0f",
+			track.TrackNum, track.Title, track.AlbumArtist, track.Album, track.Start, track.End, track.Duration))
+	}
+	want := "[1 Opening Live Band/Concert 0-200 200 2 Ballad Live Band/Concert 200-450 250 3 Encore Live Band/Concert 450-0 150]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("cue tracks = %v\nwant         %s", got, want)
+	}
+	if app.Library.TracksByPath[file] != nil {
+		t.Errorf("the split file is still listed as a track")
+	}
+	if album := app.Library.AlbumsByKey["Live Band|Concert"]; album == nil || len(album.Tracks) != 3 {
+		t.Errorf("album Concert = %+v, want its 3 tracks", album)
+	}
+
+	// The split file is scanned once, like any other
+	runScan(t, app, sim)
+	if app.LibScanAdded != 0 || app.LibScanUpdated != 0 || app.LibScanRemoved != 0 {
+		t.Errorf("rescan: %d new, %d changed, %d removed; want none",
+			app.LibScanAdded, app.LibScanUpdated, app.LibScanRemoved)
+	}
+	if len(app.Library.Tracks) != 5 || len(app.Library.SplitFiles) != 1 {
+		t.Errorf("rescan: %d tracks, %d split files; want 5, 1", len(app.Library.Tracks), len(app.Library.SplitFiles))
+	}
+}
+
+func TestCueTracksSaveToPlaylistsAsTheirFile(t *testing.T) {
+	root := useTempMusicRoot(t, 1)
+	app, sim := newTestApp(t, 0)
+	file := writeCueAlbum(t, root, sim)
+	runScan(t, app, sim)
+
+	cue := func(n int) *Track { return app.Library.TracksByPath[cueTrackPath(file, n)] }
+	other := app.Library.TracksByPath[filepath.Join(root, "Artist", "Album", "01.mp3")]
+	for _, ext := range []string{".m3u", ".xspf"} {
+		pl := &Playlist{Name: "Mix", Path: filepath.Join(root, "Mix"+ext),
+			Tracks: []*Track{cue(2), cue(3), other, cue(1)}}
+		if err := app.savePlaylist(pl); err != nil {
+			t.Fatalf("savePlaylist %s: %v", ext, err)
+		}
+
+		// Other players can't address a part of a file
+		data, _ := os.ReadFile(pl.Path)
+		if strings.Contains(string(data), "#0") || strings.Contains(string(data), "%23") {
+			t.Errorf("%s names cue tracks:\n%s", ext, data)
+		}
+
+		// Read back, each entry plays the whole file
+		loaded := &Playlist{Path: pl.Path}
+		parsePlaylist(loaded, newPlaylistResolver(app.Library))
+		var got []string
+		for _, track := range loaded.Tracks {
+			got = append(got, musicRelPath(track.Path))
+		}
+		want := "[Live/Concert.mp3 Artist/Album/01.mp3 Live/Concert.mp3]"
+		if fmt.Sprint(got) != want || len(loaded.Missing) != 0 {
+			t.Errorf("%s read back as %v, missing %v; want %s", ext, got, loaded.Missing, want)
+		}
+	}
+}
+
+// cueQueue plays the tracks of a file split like writeCueAlbum's, in the
+// given order (1-based track numbers)
+func cueQueue(t *testing.T, app *MiyooPod, sim *simAudio, order ...int) []*Track {
+	t.Helper()
+
+	file := "/music/Live/Concert.mp3"
+	sim.SetDuration(file, 600)
+	sheet := &cueSheet{Title: "Concert", Tracks: []cueTrack{
+		{Number: 1, Title: "Opening", Start: 0},
+		{Number: 2, Title: "Ballad", Start: 200},
+		{Number: 3, Title: "Encore", Start: 450},
+	}}
+	tracks := cueTracks(sheet, sheet.Tracks, &Track{Path: file, Artist: "Live Band", Duration: 600})
+
+	var queue []*Track
+	for _, n := range order {
+		queue = append(queue, tracks[n-1])
+	}
+	app.playTrackFromList(queue, 0)
+	if app.Playing.State != StatePlaying {
+		t.Fatalf("playback did not start")
+	}
+	return tracks
+}
+
+func TestCueTrackPlaysOnIntoTheNext(t *testing.T) {
+	app, sim := newTestApp(t, 0)
+	tracks := cueQueue(t, app, sim, 2, 3, 1)
+
+	sim.Advance(100 * time.Second)
+	app.syncAudioState()
+	if app.Playing.Position != 100 || app.Playing.Duration != 250 {
+		t.Errorf("Ballad at %v of %v, want 100 of 250", app.Playing.Position, app.Playing.Duration)
+	}
+
+	// Into the Encore without reopening the file
+	sim.Advance(160 * time.Second)
+	app.syncAudioState()
+	app.syncAudioState()
+	if app.Playing.Track != tracks[2] || app.Playing.Position != 10 {
+		t.Errorf("playing %s at %v, want Encore at 10", app.Playing.Track.Title, app.Playing.Position)
+	}
+	if got := len(sim.Loads()); got != 1 {
+		t.Errorf("file loaded %d times, want once", got)
+	}
+
+	// The Encore plays to the end of the file, so the Opening is gapless
+	waitForPreload(t, sim, tracks[0].File)
+	sim.Finish()
+	app.syncAudioState()
+	if app.Playing.Track != tracks[0] {
+		t.Fatalf("playing %s after the Encore, want Opening", app.Playing.Track.Title)
+	}
+
+	// The last track of the queue stops at its end, not the file's
+	sim.Advance(201 * time.Second)
+	app.syncAudioState()
+	if app.Playing.State != StateStopped || sim.Current() != "" {
+		t.Errorf("state %v, audio %q after the queue; want stopped", app.Playing.State, sim.Current())
+	}
+}
+
+func TestCueTrackOutOfOrderSeeksToItsStart(t *testing.T) {
+	app, sim := newTestApp(t, 0)
+	tracks := cueQueue(t, app, sim, 1, 3)
+
+	if sim.Preloaded() != "" || app.PreloadedPath != "" {
+		t.Errorf("preloaded %q for a track that starts mid-file", sim.Preloaded())
+	}
+	// The backend stops at the Opening's end, not at the next poll
+	sim.Advance(201 * time.Second)
+	if sim.Current() != "" {
+		t.Errorf("the file played on past the Opening's end")
+	}
+	app.syncAudioState()
+	app.syncAudioState()
+	if app.Playing.Track != tracks[2] || app.Playing.Position != 0 {
+		t.Errorf("playing %s at %v, want Encore at 0", app.Playing.Track.Title, app.Playing.Position)
+	}
+	if got := len(sim.Loads()); got != 2 {
+		t.Errorf("file loaded %d times, want twice", got)
+	}
+
+	// Seeking is within the track
+	app.mpvSeek(30)
+	app.syncAudioState()
+	if app.Playing.Position != 30 {
+		t.Errorf("seek 30s went to %v", app.Playing.Position)
+	}
+}
@@ -0,0 +1,122 @@
+//go:build desktop
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Desktop builds (go build -tags desktop) run in a normal SDL window for
+// development. PC keys are translated to the device keycodes in keys.go; the
+// device's own keycodes (arrows, Space, Enter, Esc, ...) still work as-is.
+const DESKTOP_BUILD = true
+
+// SDL keycodes of the extra PC keys
+const (
+	sdlkMinus       = 45
+	sdlkEquals      = 61
+	sdlk1           = 49
+	sdlk2           = 50
+	sdlkA           = 97
+	sdlkP           = 112
+	sdlkQ           = 113
+	sdlkS           = 115
+	sdlkW           = 119
+	sdlkX           = 120
+	sdlkZ           = 122
+	sdlkRShift      = 1073742053
+	sdlkKeypadMinus = 1073741910
+	sdlkKeypadPlus  = 1073741911
+)
+
+// desktopKeys maps PC keys onto device buttons
+var desktopKeys = map[int]Key{
+	sdlkX:      A,
+	sdlkZ:      B,
+	sdlkS:      X,
+	sdlkA:      Y,
+	sdlkQ:      L,
+	sdlkW:      R,
+	sdlk1:      L2,
+	sdlk2:      R2,
+	sdlkRShift: SELECT,
+}
+
+// defaultPaths uses ~/Music and keeps app data in ~/.local/share/miyoopod
+func defaultPaths() (music, data string) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", ""
+	}
+	data = filepath.Join(home, ".local", "share", "miyoopod")
+	if xdg := os.Getenv("XDG_DATA_HOME"); xdg != "" {
+		data = filepath.Join(xdg, "miyoopod")
+	}
+	return filepath.Join(home, "Music"), data
+}
+
+// folderBrowseRoot starts the folder browser at the home directory
+func folderBrowseRoot() string {
+	if home, err := os.UserHomeDir(); err == nil {
+		return home
+	}
+	return "/"
+}
+
+// translateKeyEvent maps a pollEvents result (keycode, or -(keycode+1) on
+// release) to device keycodes. The power and volume keys, which the device
+// reads from /dev/input/event0, are handled here and swallowed.
+func translateKeyEvent(event int) int {
+	if event == int(NONE) {
+		return event
+	}
+	released := event < 0
+	sym := event
+	if released {
+		sym = -event - 1
+	}
+
+	app := globalApp
+	switch sym {
+	case sdlkP:
+		if released {
+			app.handlePowerButtonRelease()
+		} else {
+			app.handlePowerButtonPress()
+		}
+		return int(NONE)
+	case sdlkEquals, sdlkKeypadPlus:
+		if !released {
+			app.handleVolumeUp()
+		}
+		return int(NONE)
+	case sdlkMinus, sdlkKeypadMinus:
+		if !released {
+			app.handleVolumeDown()
+		}
+		return int(NONE)
+	case int(MENU):
+		// MENU + volume adjusts brightness, as on the device
+		app.MenuKeyPressed = !released
+	}
+
+	if key, ok := desktopKeys[sym]; ok {
+		sym = int(key)
+	}
+	if released {
+		return -sym - 1
+	}
+	return sym
+}
+
+// applySystemVolume has no MI_AO device to drive, so it scales the mixer
+func (app *MiyooPod) applySystemVolume(percent int) {
+	app.Audio.SetVolume(percent)
+}
+
+// mixerVolume is the SDL2_mixer volume in percent while not fading, which is
+// the system volume here
+func (app *MiyooPod) mixerVolume() int {
+	return app.SystemVolume
+}
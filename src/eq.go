@@ -0,0 +1,306 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// The equalizer has ten octave bands, each a peaking biquad. The filters are
+// designed here and run by the audio backend on the mixed output (apply_eq in
+// audio.inc). Each preset's curve can be edited on the Equalizer screen; edits
+// are saved in settings per preset.
+
+// eqBandFreqs are the centre frequencies of the bands (Hz)
+var eqBandFreqs = []float64{31, 62, 125, 250, 500, 1000, 2000, 4000, 8000, 16000}
+
+const (
+	EQ_MAX_GAIN = 12.0 // Band gain limit either way (dB)
+	EQ_STEP     = 1.0  // Gain change per UP/DOWN press (dB)
+	EQ_Q        = 1.41 // One octave wide, so neighbouring bands blend
+)
+
+// eqPreset is a named equalizer curve
+type eqPreset struct {
+	Key   string    // Saved in settings
+	Name  string    // Shown in Settings and on the Equalizer screen
+	Gains []float64 // Default curve, dB per band
+}
+
+var eqPresets = []eqPreset{
+	{"flat", "Flat", []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+	{"bass_boost", "Bass Boost", []float64{6, 6, 5, 3, 1, 0, 0, 0, 0, 0}},
+	{"treble_boost", "Treble Boost", []float64{0, 0, 0, 0, 0, 0, 1, 3, 5, 6}},
+	{"vocal", "Vocal", []float64{-3, -2, -1, 0, 2, 3, 3, 2, 0, -1}},
+	// The built-in speaker can't move air below ~150 Hz: cutting that range
+	// keeps it from distorting, and the upper bass stands in for it
+	{"speaker", "Speaker Compensation", []float64{-8, -6, 0, 4, 3, 0, -1, 1, 3, 2}},
+	{"earbuds", "Earbuds", []float64{4, 3, 2, 0, -1, 0, 1, 2, 3, 2}},
+	{"custom", "Custom", []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 0}},
+}
+
+// eqPresetIndex returns the index of the preset with the given key, or 0 (Flat)
+func eqPresetIndex(key string) int {
+	for i, p := range eqPresets {
+		if p.Key == key {
+			return i
+		}
+	}
+	return 0
+}
+
+// eqGains returns the selected preset's curve, with any saved edits
+func (app *MiyooPod) eqGains() []float64 {
+	preset := eqPresets[app.EQPreset]
+	if gains, ok := app.EQCurves[preset.Key]; ok {
+		return gains
+	}
+	return preset.Gains
+}
+
+// setEQCurve stores an edited curve for a preset, or forgets it when it's
+// back to the preset's default
+func (app *MiyooPod) setEQCurve(idx int, gains []float64) {
+	preset := eqPresets[idx]
+	if app.EQCurves == nil {
+		app.EQCurves = make(map[string][]float64)
+	}
+	same := true
+	for i, g := range gains {
+		if g != preset.Gains[i] {
+			same = false
+			break
+		}
+	}
+	if same {
+		delete(app.EQCurves, preset.Key)
+	} else {
+		app.EQCurves[preset.Key] = gains
+	}
+}
+
+// restoreEQCurves loads saved curves, dropping any that don't fit the bands
+func (app *MiyooPod) restoreEQCurves(curves map[string][]float64) {
+	app.EQCurves = make(map[string][]float64)
+	for key, gains := range curves {
+		idx := eqPresetIndex(key)
+		if eqPresets[idx].Key != key || len(gains) != len(eqBandFreqs) {
+			logMsg(fmt.Sprintf("WARNING: Ignoring saved equalizer curve %q", key))
+			continue
+		}
+		clamped := make([]float64, len(gains))
+		for i, g := range gains {
+			clamped[i] = math.Max(-EQ_MAX_GAIN, math.Min(EQ_MAX_GAIN, g))
+		}
+		app.setEQCurve(idx, clamped)
+	}
+}
+
+// applyEqualizer hands the selected curve to the audio backend
+func (app *MiyooPod) applyEqualizer() {
+	app.Audio.SetEqualizer(app.eqGains())
+}
+
+// equalizerChanged applies an edit from the Equalizer screen and saves it.
+// Edits come quickly, so the settings are written in the background.
+func (app *MiyooPod) equalizerChanged() {
+	app.applyEqualizer()
+	app.saveSettingsAsync()
+}
+
+// equalizerLabel returns the Settings label for the selected preset
+func (app *MiyooPod) equalizerLabel() string {
+	return "Equalizer: " + eqPresets[app.EQPreset].Name
+}
+
+// selectEQPreset switches to the preset delta places on from the selected one
+func (app *MiyooPod) selectEQPreset(delta int) {
+	n := len(eqPresets)
+	app.EQPreset = ((app.EQPreset+delta)%n + n) % n
+	app.equalizerChanged()
+}
+
+// adjustEQBand changes the selected band by delta dB. Flat stays flat:
+// editing it switches to the Custom curve and edits that instead.
+func (app *MiyooPod) adjustEQBand(delta float64) {
+	if eqPresets[app.EQPreset].Key == "flat" {
+		app.EQPreset = eqPresetIndex("custom")
+	}
+	gains := append([]float64(nil), app.eqGains()...)
+	gains[app.EQBand] = math.Max(-EQ_MAX_GAIN, math.Min(EQ_MAX_GAIN, gains[app.EQBand]+delta))
+
+	app.setEQCurve(app.EQPreset, gains)
+	app.equalizerChanged()
+}
+
+// resetEQPreset restores the selected preset's default curve
+func (app *MiyooPod) resetEQPreset() {
+	delete(app.EQCurves, eqPresets[app.EQPreset].Key)
+	app.equalizerChanged()
+}
+
+// openEqualizer shows the Equalizer screen
+func (app *MiyooPod) openEqualizer() {
+	app.EQBand = 0
+	app.setScreen(ScreenEqualizer)
+	app.drawCurrentScreen()
+}
+
+// closeEqualizer returns to Settings, showing the preset chosen
+func (app *MiyooPod) closeEqualizer() {
+	app.refreshSettingsMenu()
+	logMsg(fmt.Sprintf("INFO: Equalizer: %s %v", eqPresets[app.EQPreset].Name, app.eqGains()))
+	app.setScreen(ScreenMenu)
+	app.drawCurrentScreen()
+}
+
+// handleEqualizerKey processes key input on the Equalizer screen.
+// LEFT/RIGHT pick a band, UP/DOWN set its gain, A switches preset and X
+// restores the preset's default curve. Changes are heard and saved right
+// away.
+func (app *MiyooPod) handleEqualizerKey(key Key) {
+	switch key {
+	case LEFT:
+		if app.EQBand > 0 {
+			app.EQBand--
+		}
+	case RIGHT:
+		if app.EQBand < len(eqBandFreqs)-1 {
+			app.EQBand++
+		}
+	case UP:
+		app.adjustEQBand(EQ_STEP)
+	case DOWN:
+		app.adjustEQBand(-EQ_STEP)
+	case A:
+		app.selectEQPreset(1)
+	case X:
+		app.resetEQPreset()
+	case B, MENU:
+		app.closeEqualizer()
+		return
+	default:
+		return
+	}
+
+	app.drawCurrentScreen()
+}
+
+// formatEQFreq formats a band frequency for its label: "62", "1k", "16k"
+func formatEQFreq(freq float64) string {
+	if freq >= 1000 {
+		return fmt.Sprintf("%gk", freq/1000)
+	}
+	return fmt.Sprintf("%g", freq)
+}
+
+// drawEqualizerScreen renders the preset name and a slider per band
+func (app *MiyooPod) drawEqualizerScreen() {
+	dc := app.DC
+	dc.SetHexColor(app.CurrentTheme.BG)
+	dc.Clear()
+
+	app.drawHeader("Equalizer")
+
+	dc.SetFontFace(app.FontMenu)
+	dc.SetHexColor(app.CurrentTheme.ItemTxt)
+	dc.DrawStringAnchored(eqPresets[app.EQPreset].Name, SCREEN_WIDTH/2, float64(MENU_TOP_Y)+MENU_ITEM_HEIGHT/2, 0.5, 0.5)
+
+	// Sliders run from +12 dB at the top to -12 dB at the bottom
+	const top, bottom = 150.0, 380.0
+	zeroY := (top + bottom) / 2
+	gainY := func(g float64) float64 { return zeroY - g/EQ_MAX_GAIN*(bottom-top)/2 }
+	colW := float64(SCREEN_WIDTH) / float64(len(eqBandFreqs))
+
+	dc.SetHexColor(app.CurrentTheme.Dim)
+	dc.SetLineWidth(1)
+	dc.DrawLine(colW/4, zeroY, SCREEN_WIDTH-colW/4, zeroY)
+	dc.Stroke()
+
+	gains := app.eqGains()
+	for i, freq := range eqBandFreqs {
+		x := colW*float64(i) + colW/2
+		selected := i == app.EQBand
+		y := gainY(gains[i])
+
+		if selected {
+			dc.SetHexColor(app.CurrentTheme.SelBG)
+			dc.DrawRoundedRectangle(x-colW/2+4, top-44, colW-8, bottom-top+88, 6)
+			dc.Fill()
+		}
+
+		// Track, and the part between 0 dB and the knob
+		dc.SetHexColor(app.CurrentTheme.ProgBG)
+		dc.DrawRectangle(x-3, top, 6, bottom-top)
+		dc.Fill()
+		dc.SetHexColor(app.CurrentTheme.Progress)
+		dc.DrawRectangle(x-3, math.Min(y, zeroY), 6, math.Abs(y-zeroY))
+		dc.Fill()
+
+		// Knob
+		if selected {
+			dc.SetHexColor(app.CurrentTheme.SelTxt)
+		} else {
+			dc.SetHexColor(app.CurrentTheme.Accent)
+		}
+		dc.DrawRoundedRectangle(x-16, y-6, 32, 12, 4)
+		dc.Fill()
+
+		dc.SetFontFace(app.FontSmall)
+		if selected {
+			dc.SetHexColor(app.CurrentTheme.SelTxt)
+		} else {
+			dc.SetHexColor(app.CurrentTheme.Dim)
+		}
+		dc.DrawStringAnchored(fmt.Sprintf("%+.0f", gains[i]), x, top-22, 0.5, 0.5)
+		dc.DrawStringAnchored(formatEQFreq(freq), x, bottom+22, 0.5, 0.5)
+	}
+}
+
+// --- Filter design ---
+
+// biquad is a second-order filter section, normalised so a0 = 1:
+// y[n] = b0 x[n] + b1 x[n-1] + b2 x[n-2] - a1 y[n-1] - a2 y[n-2]
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+}
+
+// peakingBiquad boosts or cuts gainDB around freq, with bandwidth set by q
+// (peaking EQ from the RBJ Audio EQ Cookbook)
+func peakingBiquad(freq, q, gainDB, rate float64) biquad {
+	a := math.Pow(10, gainDB/40)
+	w0 := 2 * math.Pi * freq / rate
+	alpha := math.Sin(w0) / (2 * q)
+	cos := math.Cos(w0)
+
+	a0 := 1 + alpha/a
+	return biquad{
+		b0: (1 + alpha*a) / a0,
+		b1: -2 * cos / a0,
+		b2: (1 - alpha*a) / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha/a) / a0,
+	}
+}
+
+// designEqualizer returns a section per band for the gains (dB per band of
+// eqBandFreqs) at the sample rate, and a linear preamp that lowers the input
+// by the largest boost so boosted bands can't clip. Flat bands, and bands too
+// close to the Nyquist frequency to shape, get a pass-through section, so
+// each band keeps its place. Returns no sections when every band is flat.
+func designEqualizer(gains []float64, rate float64) ([]biquad, float64) {
+	sections := make([]biquad, len(eqBandFreqs))
+	active := false
+	maxBoost := 0.0
+	for i, freq := range eqBandFreqs {
+		sections[i] = biquad{b0: 1}
+		if i < len(gains) && gains[i] != 0 && freq < rate*0.45 {
+			sections[i] = peakingBiquad(freq, EQ_Q, gains[i], rate)
+			maxBoost = math.Max(maxBoost, gains[i])
+			active = true
+		}
+	}
+	if !active {
+		return nil, 1
+	}
+	return sections, math.Pow(10, -maxBoost/20)
+}
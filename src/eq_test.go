@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const EQ_TEST_RATE = 44100.0
+
+// sineGain measures the gain (dB) the equalizer applies to a sine at freq,
+// filtering it the way apply_eq in audio.inc does
+func sineGain(gains []float64, freq float64) float64 {
+	sections, preamp := designEqualizer(gains, EQ_TEST_RATE)
+	z := make([][2]float64, len(sections))
+
+	var in, out float64
+	n := int(EQ_TEST_RATE)
+	for i := 0; i < n; i++ {
+		x := math.Sin(2 * math.Pi * freq * float64(i) / EQ_TEST_RATE)
+		sample := x
+		x *= preamp
+		for j, s := range sections {
+			y := s.b0*x + z[j][0]
+			z[j][0] = s.b1*x - s.a1*y + z[j][1]
+			z[j][1] = s.b2*x - s.a2*y
+			x = y
+		}
+		// Skip the first half second, while the filters settle
+		if i >= n/2 {
+			in += sample * sample
+			out += x * x
+		}
+	}
+	return 10 * math.Log10(out/in)
+}
+
+func TestEqualizerBandGains(t *testing.T) {
+	for _, tc := range []struct {
+		band   int
+		gain   float64
+		freq   float64
+		wantDB float64
+	}{
+		// A boost lowers everything by its size, so nothing clips...
+		{band: 1, gain: 9, freq: 62, wantDB: 0},
+		{band: 1, gain: 9, freq: 1000, wantDB: -9},
+		{band: 5, gain: 6, freq: 1000, wantDB: 0},
+		{band: 5, gain: 6, freq: 62, wantDB: -6},
+		{band: 5, gain: 6, freq: 16000, wantDB: -6},
+		{band: 8, gain: 12, freq: 8000, wantDB: 0},
+		// ...while a cut leaves the rest alone
+		{band: 3, gain: -6, freq: 250, wantDB: -6},
+		{band: 3, gain: -6, freq: 4000, wantDB: 0},
+	} {
+		gains := make([]float64, len(eqBandFreqs))
+		gains[tc.band] = tc.gain
+		if got := sineGain(gains, tc.freq); math.Abs(got-tc.wantDB) > 0.3 {
+			t.Errorf("%+.0f dB at %s Hz: %.0f Hz sine changed by %.2f dB, want %.0f",
+				tc.gain, formatEQFreq(eqBandFreqs[tc.band]), tc.freq, got, tc.wantDB)
+		}
+	}
+
+	// Neighbouring bands blend into a smooth shelf
+	bass := eqPresets[eqPresetIndex("bass_boost")].Gains
+	low, mid, high := sineGain(bass, 62), sineGain(bass, 250), sineGain(bass, 4000)
+	if !(low > mid && mid > high) || low-high < 5 {
+		t.Errorf("Bass Boost: 62 Hz %.1f dB, 250 Hz %.1f dB, 4 kHz %.1f dB; want falling by 5 dB or more", low, mid, high)
+	}
+}
+
+func TestEqualizerDesign(t *testing.T) {
+	// Flat turns the filters off
+	if sections, preamp := designEqualizer(make([]float64, len(eqBandFreqs)), EQ_TEST_RATE); sections != nil || preamp != 1 {
+		t.Errorf("flat curve: %d sections, preamp %v; want none, 1", len(sections), preamp)
+	}
+
+	// A band over 0.45 of the sample rate is left out, but keeps its place
+	gains := make([]float64, len(eqBandFreqs))
+	gains[9] = 6
+	gains[0] = 3
+	sections, _ := designEqualizer(gains, 32000)
+	if len(sections) != len(eqBandFreqs) || sections[9] != (biquad{b0: 1}) || sections[0] == (biquad{b0: 1}) {
+		t.Errorf("16k band at 32 kHz: sections %v", sections)
+	}
+
+	// Every section is stable at the extremes
+	for _, g := range []float64{EQ_MAX_GAIN, -EQ_MAX_GAIN} {
+		for i := range gains {
+			gains[i] = g
+		}
+		sections, _ := designEqualizer(gains, EQ_TEST_RATE)
+		for i, s := range sections {
+			if math.Abs(s.a2) >= 1 || math.Abs(s.a1) >= 1+s.a2 {
+				t.Errorf("%+.0f dB at %s Hz is unstable: %+v", g, formatEQFreq(eqBandFreqs[i]), s)
+			}
+		}
+	}
+}
+
+func TestEqualizerScreen(t *testing.T) {
+	prevSettings, prevAssets := SETTINGS_PATH, ASSETS_DIR
+	SETTINGS_PATH = filepath.Join(t.TempDir(), "settings.json")
+	ASSETS_DIR = t.TempDir() // Restoring the theme writes the icon
+	t.Cleanup(func() { SETTINGS_PATH, ASSETS_DIR = prevSettings, prevAssets })
+
+	app, sim := newTestApp(t, 0)
+	app.openEqualizer()
+	if app.CurrentScreen != ScreenEqualizer {
+		t.Fatalf("screen = %v, want equalizer", app.CurrentScreen)
+	}
+
+	// Editing Flat edits the Custom curve
+	app.handleKey(RIGHT)
+	app.handleKey(RIGHT)
+	app.handleKey(UP)
+	app.handleKey(UP)
+	if got := fmt.Sprint(sim.Equalizer()); got != "[0 0 2 0 0 0 0 0 0 0]" {
+		t.Errorf("curve after +2 dB at 125 Hz = %s", got)
+	}
+	if eqPresets[app.EQPreset].Name != "Custom" {
+		t.Errorf("preset = %s, want Custom", eqPresets[app.EQPreset].Name)
+	}
+
+	// Presets keep their own edits
+	app.EQPreset = eqPresetIndex("bass_boost")
+	app.handleKey(DOWN)
+	bass := fmt.Sprint(sim.Equalizer())
+	if bass != "[6 6 4 3 1 0 0 0 0 0]" {
+		t.Errorf("Bass Boost after -1 dB at 125 Hz = %s", bass)
+	}
+	app.handleKey(A)
+	if got := eqPresets[app.EQPreset].Name; got != "Treble Boost" {
+		t.Errorf("A switched to %s, want Treble Boost", got)
+	}
+	app.handleKey(B)
+	if app.CurrentScreen != ScreenMenu {
+		t.Errorf("B went to %v, want the menu", app.CurrentScreen)
+	}
+
+	// Edits are saved in the background
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		data, _ := os.ReadFile(SETTINGS_PATH)
+		if strings.Contains(string(data), `"eq_preset": "treble_boost"`) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("settings not saved: %s", data)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Everything is restored on the next start
+	restored, sim2 := newTestApp(t, 0)
+	if err := restored.loadSettings(); err != nil {
+		t.Fatalf("loadSettings: %v", err)
+	}
+	if got := eqPresets[restored.EQPreset].Name; got != "Treble Boost" {
+		t.Errorf("restored preset %s, want Treble Boost", got)
+	}
+	if got := fmt.Sprint(sim2.Equalizer()); got != fmt.Sprint(eqPresets[restored.EQPreset].Gains) {
+		t.Errorf("restored curve %s", got)
+	}
+	restored.EQPreset = eqPresetIndex("bass_boost")
+	if got := fmt.Sprint(restored.eqGains()); got != bass {
+		t.Errorf("restored Bass Boost %s, want %s", got, bass)
+	}
+
+	// X restores the preset's default curve
+	restored.openEqualizer()
+	restored.handleKey(X)
+	if got := fmt.Sprint(sim2.Equalizer()); got != fmt.Sprint(eqPresets[restored.EQPreset].Gains) || len(restored.EQCurves) != 1 {
+		t.Errorf("after reset: curve %s, edited curves %v", got, restored.EQCurves)
+	}
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// folderNode is a directory in the Folders menu. The tree is built from the
+// indexed track paths, so browsing never reads the SD card.
+type folderNode struct {
+	Name    string
+	Path    string
+	Folders []*folderNode
+	Tracks  []*Track // Tracks directly in this folder, by file name
+}
+
+// buildFolderTree groups tracks by directory under each music root. Roots
+// without tracks are left out; tracks outside every root are ignored.
+func buildFolderTree(tracks []*Track, roots []string) []*folderNode {
+	nodes := make(map[string]*folderNode)
+	var top []*folderNode
+
+	for _, root := range roots {
+		dir := filepath.Clean(root)
+		node := &folderNode{Name: root, Path: dir}
+		nodes[dir] = node
+		top = append(top, node)
+	}
+
+	// folderFor returns the node for dir, creating it and its parents up to
+	// the root that contains it
+	var folderFor func(dir string) *folderNode
+	folderFor = func(dir string) *folderNode {
+		if node, ok := nodes[dir]; ok {
+			return node
+		}
+		node := &folderNode{Name: filepath.Base(dir), Path: dir}
+		nodes[dir] = node
+		parent := folderFor(filepath.Dir(dir))
+		parent.Folders = append(parent.Folders, node)
+		return node
+	}
+
+	for _, t := range tracks {
+		if musicRelPath(t.Path) == t.Path {
+			continue // Not under a music root
+		}
+		node := folderFor(filepath.Dir(t.Path))
+		node.Tracks = append(node.Tracks, t)
+	}
+
+	var kept []*folderNode
+	for _, node := range top {
+		node.sort()
+		if len(node.Tracks) > 0 || len(node.Folders) > 0 {
+			kept = append(kept, node)
+		}
+	}
+	return kept
+}
+
+// sort orders subfolders and tracks by name, ignoring case and accents.
+// Articles are kept: this is a view of the files as they are named.
+func (node *folderNode) sort() {
+	sortByKey(node.Folders, func(f *folderNode) string { return foldText(f.Name) })
+	sortByKey(node.Tracks, func(t *Track) string { return foldText(filepath.Base(t.Path)) })
+	for _, sub := range node.Folders {
+		sub.sort()
+	}
+}
+
+// allTracks returns the folder's tracks followed by those of its subfolders
+func (node *folderNode) allTracks() []*Track {
+	tracks := append([]*Track{}, node.Tracks...)
+	for _, sub := range node.Folders {
+		tracks = append(tracks, sub.allTracks()...)
+	}
+	return tracks
+}
+
+// buildFolderRootMenuItems lists the music folders, or the contents of the
+// only one
+func (app *MiyooPod) buildFolderRootMenuItems(root *MenuScreen) []*MenuItem {
+	folders := buildFolderTree(app.Library.Tracks, MUSIC_ROOTS)
+	if len(folders) == 1 {
+		return app.buildFolderMenuItems(root, folders[0])
+	}
+
+	items := make([]*MenuItem, 0, len(folders))
+	for _, folder := range folders {
+		items = append(items, app.folderMenuItem(root, folder))
+	}
+	return items
+}
+
+// folderMenuItem returns the menu item that opens a folder
+func (app *MiyooPod) folderMenuItem(root *MenuScreen, folder *folderNode) *MenuItem {
+	f := folder // capture
+	return &MenuItem{
+		Label:      f.Name,
+		HasSubmenu: true,
+		Submenu: &MenuScreen{
+			Title:  f.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildFolderMenuItems(root, f)
+			},
+		},
+		Tracks: f.allTracks(), // For Y-key queuing
+	}
+}
+
+// buildFolderMenuItems lists a folder's play actions, subfolders and tracks.
+// Tracks are labelled by file name, since the folder view is for files whose
+// tags can't be trusted.
+func (app *MiyooPod) buildFolderMenuItems(root *MenuScreen, folder *folderNode) []*MenuItem {
+	items := make([]*MenuItem, 0, len(folder.Folders)+len(folder.Tracks)+2)
+
+	if len(folder.Tracks) > 0 {
+		tracks := folder.Tracks // capture
+		items = append(items, &MenuItem{
+			Label:  "Play Folder",
+			Tracks: tracks,
+			Action: func() {
+				app.playTrackFromList(tracks, 0)
+			},
+		})
+	}
+	if len(folder.Folders) > 0 {
+		all := folder.allTracks() // capture
+		items = append(items, &MenuItem{
+			Label:  "Play Folder and Subfolders",
+			Tracks: all,
+			Action: func() {
+				app.playTrackFromList(all, 0)
+			},
+		})
+	}
+
+	for _, sub := range folder.Folders {
+		items = append(items, app.folderMenuItem(root, sub))
+	}
+
+	for i, track := range folder.Tracks {
+		t := track          // capture
+		idx := i            // capture
+		ts := folder.Tracks // capture
+		name := filepath.Base(t.Path)
+		label := strings.TrimSuffix(name, filepath.Ext(name))
+		if t.File != "" {
+			// Split out of a file by a cue sheet, which is all that names it
+			label = fmt.Sprintf("%02d %s", t.TrackNum, t.Title)
+		}
+		items = append(items, &MenuItem{
+			Label: label,
+			Track: t,
+			Action: func() {
+				app.playTrackFromList(ts, idx)
+			},
+		})
+	}
+	return items
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestFolderTree(t *testing.T) {
+	prevRoots := MUSIC_ROOTS
+	t.Cleanup(func() { MUSIC_ROOTS = prevRoots })
+	MUSIC_ROOTS = []string{"/music/", "/books/"}
+
+	paths := []string{
+		"/music/B Band/Live/02.mp3",
+		"/music/B Band/Live/01.mp3",
+		"/music/a band/song.flac",
+		"/music/loose.mp3",
+		"/elsewhere/ignored.mp3",
+	}
+	var tracks []*Track
+	for _, p := range paths {
+		tracks = append(tracks, &Track{Path: p})
+	}
+
+	roots := buildFolderTree(tracks, MUSIC_ROOTS)
+	if len(roots) != 1 || roots[0].Name != "/music/" {
+		t.Fatalf("expected only the non-empty /music/ root, got %d", len(roots))
+	}
+	music := roots[0]
+	if len(music.Tracks) != 1 || music.Tracks[0].Path != "/music/loose.mp3" {
+		t.Errorf("root tracks wrong: %v", music.Tracks)
+	}
+	if len(music.Folders) != 2 || music.Folders[0].Name != "a band" || music.Folders[1].Name != "B Band" {
+		t.Fatalf("subfolders not sorted case-insensitively")
+	}
+
+	var got []string
+	for _, track := range music.allTracks() {
+		got = append(got, track.Path)
+	}
+	want := []string{
+		"/music/loose.mp3",
+		"/music/a band/song.flac",
+		"/music/B Band/Live/01.mp3",
+		"/music/B Band/Live/02.mp3",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("allTracks = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("allTracks[%d] = %s, want %s", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+// readID3TextFrames returns the values of the given text frames (e.g. "TPE1")
+// from an ID3v2.3 or ID3v2.4 tag at the start of r. tag.ReadFrom joins the
+// NUL-separated values of ID3v2.4 multi-value frames into one run-together
+// string, so they are read again here.
+func readID3TextFrames(r io.ReadSeeker, ids ...string) map[string][]string {
+	frames := readID3Frames(r, ids...)
+	if frames == nil {
+		return nil
+	}
+	values := make(map[string][]string)
+	for id, body := range frames {
+		values[id] = decodeID3Text(body[0], body[1:])
+	}
+	return values
+}
+
+// readID3Frames returns the bodies of the given frames from an ID3v2.3 or
+// ID3v2.4 tag at the start of r, for frames tag.ReadFrom doesn't expose.
+// Returns nil for other tags, and for tags using whole-tag
+// unsynchronisation, which are rare enough to skip.
+func readID3Frames(r io.ReadSeeker, ids ...string) map[string][]byte {
+	frames, _ := readID3FrameList(r, ids...)
+	if frames == nil {
+		return nil
+	}
+	bodies := make(map[string][]byte)
+	for _, f := range frames {
+		bodies[f.ID] = f.Body
+	}
+	return bodies
+}
+
+// id3Frame is one frame of an ID3v2 tag, with its per-frame encodings undone
+type id3Frame struct {
+	ID   string
+	Body []byte
+}
+
+// readID3FrameList is readID3Frames for frames that may repeat (CHAP): it
+// returns every matching frame in tag order, with the tag's major version,
+// which embedded frames need (see splitID3Frames)
+func readID3FrameList(r io.ReadSeeker, ids ...string) ([]id3Frame, byte) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, 0
+	}
+	header := make([]byte, 10)
+	if _, err := io.ReadFull(r, header); err != nil || string(header[:3]) != "ID3" {
+		return nil, 0
+	}
+	version, flags := header[3], header[5]
+	if (version != 3 && version != 4) || flags&0x80 != 0 {
+		return nil, 0
+	}
+	end := int64(10 + syncsafe(header[6:10]))
+
+	pos := int64(10)
+	if flags&0x40 != 0 {
+		// Extended header: v2.4 counts its own size field, v2.3 doesn't
+		ext := make([]byte, 4)
+		if _, err := io.ReadFull(r, ext); err != nil {
+			return nil, 0
+		}
+		if version == 4 {
+			pos += int64(syncsafe(ext))
+		} else {
+			pos += 4 + int64(binary.BigEndian.Uint32(ext))
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	frames := []id3Frame{}
+	frame := make([]byte, 10)
+	for pos+10 <= end {
+		if _, err := r.Seek(pos, io.SeekStart); err != nil {
+			break
+		}
+		if _, err := io.ReadFull(r, frame); err != nil || frame[0] == 0 {
+			break // Padding
+		}
+		id := string(frame[:4])
+		size := int64(binary.BigEndian.Uint32(frame[4:8]))
+		if version == 4 {
+			size = int64(syncsafe(frame[4:8]))
+		}
+		pos += 10 + size
+		if !wanted[id] || size == 0 || pos > end {
+			continue
+		}
+
+		body := make([]byte, size)
+		if _, err := io.ReadFull(r, body); err != nil {
+			break
+		}
+		if body = frameBody(body, version, frame[9]); body != nil {
+			frames = append(frames, id3Frame{ID: id, Body: body})
+		}
+	}
+	return frames, version
+}
+
+// splitID3Frames parses the frames embedded at the end of a CHAP or CTOC
+// frame body, which are laid out like those of the tag itself
+func splitID3Frames(b []byte, version byte) []id3Frame {
+	var frames []id3Frame
+	for len(b) >= 10 && b[0] != 0 {
+		size := int(binary.BigEndian.Uint32(b[4:8]))
+		if version == 4 {
+			size = syncsafe(b[4:8])
+		}
+		if size < 0 || 10+size > len(b) {
+			break
+		}
+		if body := frameBody(b[10:10+size], version, b[9]); body != nil {
+			frames = append(frames, id3Frame{ID: string(b[:4]), Body: body})
+		}
+		b = b[10+size:]
+	}
+	return frames
+}
+
+// frameBody undoes the per-frame encodings of a frame, or returns nil
+// for compressed or encrypted frames
+func frameBody(body []byte, version, flags byte) []byte {
+	if version == 3 {
+		if flags&0xC0 != 0 {
+			return nil
+		}
+		return body
+	}
+	if flags&0x0C != 0 {
+		return nil
+	}
+	if flags&0x01 != 0 { // Data length indicator
+		if len(body) < 4 {
+			return nil
+		}
+		body = body[4:]
+	}
+	if flags&0x02 != 0 { // Unsynchronised: 0xFF 0x00 was written for 0xFF
+		body = bytes.ReplaceAll(body, []byte{0xFF, 0x00}, []byte{0xFF})
+	}
+	if len(body) == 0 {
+		return nil
+	}
+	return body
+}
+
+// decodeID3Text decodes text in the given ID3 encoding and splits it on NULs
+func decodeID3Text(encoding byte, b []byte) []string {
+	var values []string
+	for _, v := range strings.Split(decodeID3String(encoding, b), "\x00") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// decodeID3String decodes text in the given ID3 encoding, NULs included
+func decodeID3String(encoding byte, b []byte) string {
+	var text string
+	switch encoding {
+	case 0: // ISO-8859-1
+		runes := make([]rune, len(b))
+		for i, c := range b {
+			runes[i] = rune(c)
+		}
+		text = string(runes)
+	case 1, 2: // UTF-16, with a BOM or big-endian
+		order := binary.ByteOrder(binary.BigEndian)
+		units := make([]uint16, 0, len(b)/2)
+		for i := 0; i+1 < len(b); i += 2 {
+			u := order.Uint16(b[i:])
+			switch {
+			case u == 0xFEFF:
+				continue
+			case u == 0xFFFE:
+				order = binary.LittleEndian // BOM read the wrong way round
+				continue
+			}
+			units = append(units, u)
+		}
+		text = string(utf16.Decode(units))
+	default: // UTF-8
+		text = string(b)
+	}
+	return text
+}
+
+// splitID3String cuts one NUL-terminated string in the given encoding off the
+// front of b, returning it and the rest
+func splitID3String(encoding byte, b []byte) (string, []byte) {
+	if encoding == 1 || encoding == 2 {
+		// UTF-16 strings end with a two-byte NUL on an even offset
+		for i := 0; i+1 < len(b); i += 2 {
+			if b[i] == 0 && b[i+1] == 0 {
+				return decodeID3String(encoding, b[:i]), b[i+2:]
+			}
+		}
+		return decodeID3String(encoding, b), nil
+	}
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		return decodeID3String(encoding, b[:i]), b[i+1:]
+	}
+	return decodeID3String(encoding, b), nil
+}
+
+// syncsafe decodes a 28-bit ID3v2 size stored 7 bits per byte
+func syncsafe(b []byte) int {
+	return int(b[0]&0x7F)<<21 | int(b[1]&0x7F)<<14 | int(b[2]&0x7F)<<7 | int(b[3]&0x7F)
+}
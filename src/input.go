@@ -29,7 +29,8 @@ const (
 )
 
 // startPowerButtonMonitor reads power button and volume events directly from /dev/input/event0
-// This bypasses SDL2 which doesn't have power button mapping in the prebuilt library
+// This bypasses SDL2 which doesn't have power button mapping in the prebuilt library.
+// Events are handled on the main loop.
 func (app *MiyooPod) startPowerButtonMonitor() {
 	go func() {
 		file, err := os.Open("/dev/input/event0")
@@ -42,7 +43,7 @@ func (app *MiyooPod) startPowerButtonMonitor() {
 		logMsg("Power button monitor started on /dev/input/event0")
 
 		var ev inputEvent
-		for app.Running {
+		for app.Running.Load() {
 			err := binary.Read(file, binary.LittleEndian, &ev)
 			if err != nil {
 				logMsg(fmt.Sprintf("ERROR: Reading input event: %v", err))
@@ -55,37 +56,43 @@ func (app *MiyooPod) startPowerButtonMonitor() {
 				continue
 			}
 
-			// Handle power button
-			if ev.Code == KEY_POWER {
-				if ev.Value == 1 { // Key pressed
-					logMsg("DEBUG: Power button PRESSED (from /dev/input/event0)")
-					app.handlePowerButtonPress()
-				} else if ev.Value == 0 { // Key released
-					logMsg("DEBUG: Power button RELEASED (from /dev/input/event0)")
-					app.handlePowerButtonRelease()
-				}
-			}
+			code, value := ev.Code, ev.Value
+			app.post(func() { app.handleInputEvent(code, value) })
+		}
+	}()
+}
 
-			// Handle volume up
-			if ev.Code == KEY_VOLUMEUP && ev.Value == 1 {
-				app.handleVolumeUp()
-			}
+// handleInputEvent handles a key event read from /dev/input/event0
+func (app *MiyooPod) handleInputEvent(code uint16, value int32) {
+	// Handle power button
+	if code == KEY_POWER {
+		if value == 1 { // Key pressed
+			logMsg("DEBUG: Power button PRESSED (from /dev/input/event0)")
+			app.handlePowerButtonPress()
+		} else if value == 0 { // Key released
+			logMsg("DEBUG: Power button RELEASED (from /dev/input/event0)")
+			app.handlePowerButtonRelease()
+		}
+	}
 
-			// Handle volume down
-			if ev.Code == KEY_VOLUMEDOWN && ev.Value == 1 {
-				app.handleVolumeDown()
-			}
+	// Handle volume up
+	if code == KEY_VOLUMEUP && value == 1 {
+		app.handleVolumeUp()
+	}
 
-			// Track MENU key state for brightness control
-			if ev.Code == KEY_ESC {
-				if ev.Value == 1 { // Pressed
-					app.MenuKeyPressed = true
-				} else if ev.Value == 0 { // Released
-					app.MenuKeyPressed = false
-				}
-			}
+	// Handle volume down
+	if code == KEY_VOLUMEDOWN && value == 1 {
+		app.handleVolumeDown()
+	}
+
+	// Track MENU key state for brightness control
+	if code == KEY_ESC {
+		if value == 1 { // Pressed
+			app.MenuKeyPressed = true
+		} else if value == 0 { // Released
+			app.MenuKeyPressed = false
 		}
-	}()
+	}
 }
 
 func (app *MiyooPod) handlePowerButtonPress() {
@@ -93,7 +100,7 @@ func (app *MiyooPod) handlePowerButtonPress() {
 		app.PowerButtonPressed = true
 		app.PowerButtonPressTime = time.Now()
 		// Start monitoring for long hold
-		go app.monitorPowerButtonHold()
+		app.monitorPowerButtonHold()
 	}
 }
 
@@ -9,7 +9,6 @@ import (
 	_ "image/png"
 	"os"
 	"path/filepath"
-	"sort"
 	"strings"
 	"time"
 
@@ -17,8 +16,35 @@ import (
 	"github.com/fogleman/gg"
 )
 
+// TRACK_TAG_VERSION is bumped whenever scanTrack starts reading new fields, so
+// an incremental rescan re-tags tracks cached by an older version
+const TRACK_TAG_VERSION = 6
+
+// audioFormats maps the audio file extensions indexed by the library scan to
+// the format name shown in errors
+var audioFormats = map[string]string{
+	".mp3":  "MP3",
+	".flac": "FLAC",
+	".ogg":  "Ogg Vorbis",
+	".opus": "Opus",
+}
+
+// isAudioFile reports whether a path has one of the indexed audio extensions
+func isAudioFile(path string) bool {
+	_, ok := audioFormats[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// audioFormatName returns the display name for an audio extension
+func audioFormatName(ext string) string {
+	if name, ok := audioFormats[strings.ToLower(ext)]; ok {
+		return name
+	}
+	return strings.ToUpper(strings.TrimPrefix(ext, "."))
+}
+
 // startLibraryScan launches a background library scan, switching to the scan screen.
-// The onComplete callback (if non-nil) is called when the scan finishes.
+// The onComplete callback (if non-nil) is called on the main loop when the scan finishes.
 func (app *MiyooPod) startLibraryScan(onComplete func()) {
 	if app.LibScanRunning {
 		return
@@ -31,135 +57,240 @@ func (app *MiyooPod) startLibraryScan(onComplete func()) {
 	app.LibScanStatus = "Starting scan..."
 	app.LibScanElapsed = ""
 	app.LibScanPhase = "scanning"
+	app.LibScanAdded = 0
+	app.LibScanUpdated = 0
+	app.LibScanRemoved = 0
+	app.LibScanUnresolved = 0
 
 	app.setScreen(ScreenLibraryScan)
 	app.drawCurrentScreen()
 
-	go app.runLibraryScan(onComplete)
+	// Hand the scan what it needs from the current library up front, so it
+	// never reads albums the main loop may still be updating
+	var prevTracks map[string]*Track
+	prevArt := make(map[string]string)
+	if app.Library != nil {
+		// Files as they were scanned: a file split by a cue sheet rather
+		// than the tracks made from it
+		prevTracks = make(map[string]*Track, len(app.Library.Tracks))
+		for path, track := range app.Library.TracksByPath {
+			if track.File == "" {
+				prevTracks[path] = track
+			}
+		}
+		for _, track := range app.Library.SplitFiles {
+			prevTracks[track.Path] = track
+		}
+		for key, album := range app.Library.AlbumsByKey {
+			if album.ArtPath != "" {
+				prevArt[key] = album.ArtPath
+			}
+		}
+	}
+
+	go app.runLibraryScan(prevTracks, prevArt, onComplete)
 }
 
 // runLibraryScan is the background goroutine that performs the actual library scan.
-// IMPORTANT: Never call draw functions from here — only update state and requestRedraw.
-func (app *MiyooPod) runLibraryScan(onComplete func()) {
+// Files whose mtime and size match the previous scan keep their cached metadata;
+// only new or changed files are re-tagged. Albums, artists and playlists are
+// rebuilt from the merged track set.
+// The new library is built privately and installed on the main loop when
+// complete; progress is posted. Never touch app state or draw from here.
+func (app *MiyooPod) runLibraryScan(prevTracks map[string]*Track, prevArt map[string]string, onComplete func()) {
 	start := time.Now()
 	logMsg("INFO: Scanning music library...")
 
-	app.Library = &Library{
+	lib := &Library{
 		TracksByPath:  make(map[string]*Track),
 		AlbumsByKey:   make(map[string]*Album),
 		ArtistsByName: make(map[string]*Artist),
 	}
 
+	progress := func(update func()) {
+		app.post(func() {
+			update()
+			app.requestRedraw()
+		})
+	}
+
+	var scanned []*Track
+	var cues []string
 	fileCount := 0
+	added, updated, reused := 0, 0, 0
+	folder := ""
 
-	filepath.Walk(MUSIC_ROOT, func(path string, info os.FileInfo, err error) error {
-		if err != nil || !app.Running {
+	walk := func(path string, info os.FileInfo, err error) error {
+		if err != nil || !app.Running.Load() {
 			return nil
 		}
 		if info.IsDir() {
-			app.LibScanFolder = path
+			folder = path
 			return nil
 		}
 
-		ext := strings.ToLower(filepath.Ext(path))
-		switch ext {
-		case ".mp3":
-			app.scanTrack(path)
-			fileCount++
-			app.LibScanCount = fileCount
+		switch {
+		case isAudioFile(path):
+			old := prevTracks[path]
+
+			track := old
+			if old == nil || old.ModTime != info.ModTime().UnixNano() || old.Size != info.Size() ||
+				old.TagVersion != TRACK_TAG_VERSION {
+				track = app.scanTrack(path, info)
+				if track == nil {
+					return nil
+				}
+				if old == nil {
+					// On a first scan everything is new; the file date is a better guess
+					if len(prevTracks) == 0 {
+						track.AddedAt = info.ModTime().Unix()
+					} else {
+						track.AddedAt = time.Now().Unix()
+					}
+					added++
+				} else {
+					track.AddedAt = old.AddedAt
+					updated++
+				}
+			} else {
+				// Copy so saving the new library never reads a track the
+				// main loop is still updating (e.g. a duration learned on play)
+				copied := *old
+				track = &copied
+				reused++
+			}
+			scanned = append(scanned, track)
 
+			fileCount++
 			if fileCount%5 == 0 {
-				app.LibScanStatus = fmt.Sprintf("Found %d songs...", fileCount)
-				app.requestRedraw()
+				count, dir := fileCount, folder
+				progress(func() {
+					app.LibScanCount = count
+					app.LibScanFolder = dir
+					app.LibScanStatus = fmt.Sprintf("Found %d songs...", count)
+				})
 			}
 
-		case ".m3u", ".m3u8":
-			app.Library.Playlists = append(app.Library.Playlists, &Playlist{
-				Name: strings.TrimSuffix(filepath.Base(path), ext),
+		case isCueFile(path):
+			cues = append(cues, path)
+
+		case isPlaylistFile(path):
+			lib.Playlists = append(lib.Playlists, &Playlist{
+				Name: strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
 				Path: path,
 			})
 		}
 
 		return nil
-	})
+	}
+	for _, root := range MUSIC_ROOTS {
+		// A root on a card or partition that isn't mounted is skipped; its
+		// tracks drop out of the library until it is back
+		if _, err := os.Stat(root); err != nil {
+			logMsg(fmt.Sprintf("WARNING: Music folder unavailable: %s (%v)", root, err))
+			continue
+		}
+		filepath.Walk(root, walk)
+	}
 
-	// Sort phase
-	app.LibScanPhase = "sorting"
-	app.LibScanStatus = "Sorting library..."
-	app.requestRedraw()
+	// Cue sheets may come before or after their audio files in the walk
+	tracks, split := splitByCueSheets(scanned, cues)
+	lib.SplitFiles = split
 
-	sort.Slice(app.Library.Tracks, func(i, j int) bool {
-		return strings.ToLower(app.Library.Tracks[i].Title) < strings.ToLower(app.Library.Tracks[j].Title)
-	})
-	sort.Slice(app.Library.Albums, func(i, j int) bool {
-		return strings.ToLower(app.Library.Albums[i].Name) < strings.ToLower(app.Library.Albums[j].Name)
-	})
-	sort.Slice(app.Library.Artists, func(i, j int) bool {
-		return strings.ToLower(app.Library.Artists[i].Name) < strings.ToLower(app.Library.Artists[j].Name)
-	})
-	for _, album := range app.Library.Albums {
-		sort.Slice(album.Tracks, func(i, j int) bool {
-			if album.Tracks[i].DiscNum != album.Tracks[j].DiscNum {
-				return album.Tracks[i].DiscNum < album.Tracks[j].DiscNum
-			}
-			return album.Tracks[i].TrackNum < album.Tracks[j].TrackNum
-		})
+	// Compilations are decided per folder, so only once every track is known
+	markCompilations(tracks)
+	for _, track := range tracks {
+		lib.addTrack(track, prevArt)
 	}
-	for _, artist := range app.Library.Artists {
-		sort.Slice(artist.Albums, func(i, j int) bool {
-			return strings.ToLower(artist.Albums[i].Name) < strings.ToLower(artist.Albums[j].Name)
-		})
+
+	removed := len(prevTracks) - reused - updated
+	if removed < 0 {
+		removed = 0
 	}
+	logMsg(fmt.Sprintf("INFO: Scan diff: %d new, %d changed, %d removed, %d unchanged",
+		added, updated, removed, reused))
+
+	// Sort phase
+	progress(func() {
+		app.LibScanCount = fileCount
+		app.LibScanPhase = "sorting"
+		app.LibScanStatus = "Sorting library..."
+	})
+
+	lib.sort()
 
 	// Parse playlists
-	for _, pl := range app.Library.Playlists {
-		app.parsePlaylist(pl)
-	}
+	unresolved := lib.parsePlaylists()
 
 	// Decode album art
-	app.LibScanPhase = "decoding"
-	app.LibScanStatus = "Decoding album art..."
-	app.requestRedraw()
+	progress(func() {
+		app.LibScanPhase = "decoding"
+		app.LibScanStatus = "Decoding album art..."
+	})
 
-	app.decodeAlbumArt()
+	covers := make(map[string]image.Image)
+	app.decodeAlbumArt(lib.Albums, covers)
 
 	// Save to JSON
-	app.LibScanPhase = "saving"
-	app.LibScanStatus = "Saving library..."
-	app.requestRedraw()
+	progress(func() {
+		app.LibScanPhase = "saving"
+		app.LibScanStatus = "Saving library..."
+	})
 
-	if err := app.saveLibraryJSON(); err != nil {
+	if err := writeLibraryJSON(lib); err != nil {
 		logMsg(fmt.Sprintf("WARNING: Failed to save library: %v", err))
 	}
 
 	elapsed := time.Since(start)
-	app.LibScanElapsed = elapsed.Truncate(time.Second).String()
-	app.LibScanStatus = fmt.Sprintf("%d tracks, %d albums, %d artists",
-		len(app.Library.Tracks), len(app.Library.Albums), len(app.Library.Artists))
-
 	logMsg(fmt.Sprintf("INFO: Library scan complete: %d tracks, %d albums, %d artists, %d playlists",
-		len(app.Library.Tracks), len(app.Library.Albums), len(app.Library.Artists), len(app.Library.Playlists)))
+		len(lib.Tracks), len(lib.Albums), len(lib.Artists), len(lib.Playlists)))
 
-	app.LibScanRunning = false
+	app.post(func() {
+		app.Library = lib
+		for key, img := range covers {
+			app.Coverflow.CoverCache[key] = img
+		}
+		app.initDefaultArt()
+
+		app.LibScanAdded = added
+		app.LibScanUpdated = updated
+		app.LibScanRemoved = removed
+		app.LibScanUnresolved = unresolved
+		app.LibScanElapsed = elapsed.Truncate(time.Second).String()
+		app.LibScanStatus = fmt.Sprintf("%d tracks, %d albums, %d artists",
+			len(lib.Tracks), len(lib.Albums), len(lib.Artists))
+		app.LibScanRunning = false
+
+		// Call onComplete before marking done — ensures menu is rebuilt before user can navigate away
+		if onComplete != nil {
+			onComplete()
+		}
 
-	// Call onComplete before marking done — ensures menu is rebuilt before user can navigate away
-	if onComplete != nil {
-		onComplete()
-	}
+		app.LibScanDone = true
+		app.requestRedraw()
+	})
+}
 
-	app.LibScanDone = true
-	app.requestRedraw()
+// albumKeyFor returns the AlbumsByKey key of the album a track belongs to
+func albumKeyFor(track *Track) string {
+	return trackAlbumArtist(track) + "|" + track.Album
 }
 
-// scanTrack reads metadata from a single audio file
-func (app *MiyooPod) scanTrack(path string) {
+// scanTrack reads metadata from a single audio file. Returns nil if the file
+// can't be opened.
+func (app *MiyooPod) scanTrack(path string, info os.FileInfo) *Track {
 	f, err := os.Open(path)
 	if err != nil {
-		return
+		return nil
 	}
 	defer f.Close()
 
-	track := &Track{Path: path}
+	track := &Track{
+		Path:       path,
+		ModTime:    info.ModTime().UnixNano(),
+		Size:       info.Size(),
+		TagVersion: TRACK_TAG_VERSION,
+	}
 
 	m, err := tag.ReadFrom(f)
 	if err == nil {
@@ -171,6 +302,12 @@ func (app *MiyooPod) scanTrack(path string) {
 		track.DiscNum, _ = m.Disc()
 		track.Year = m.Year()
 		track.Genre = m.Genre()
+		track.Composer = m.Composer()
+		track.Compilation = readCompilationFlag(m)
+		readSortTags(track, m)
+		readMultiValueTags(track, m, f)
+		readReplayGain(track, m)
+		readChapters(track, m, f)
 
 		if pic := m.Picture(); pic != nil {
 			track.HasArt = true
@@ -185,7 +322,7 @@ func (app *MiyooPod) scanTrack(path string) {
 	}
 
 	// Extract duration using SDL_mixer
-	track.Duration = audioGetDurationForFile(path)
+	track.Duration = app.Audio.DurationForFile(path)
 	if track.Duration == 0 {
 		logMsg(fmt.Sprintf("[SCAN] Warning: Could not extract duration for: %s", filepath.Base(path)))
 	}
@@ -201,63 +338,48 @@ func (app *MiyooPod) scanTrack(path string) {
 		track.Album = "Unknown Album"
 	}
 
+	return track
+}
+
+// addTrack registers a track and links it into its album and artist,
+// creating them as needed. prevArt maps album keys from the previous scan to
+// their saved artwork path (may be nil), so those albums keep their art.
+func (lib *Library) addTrack(track *Track, prevArt map[string]string) {
 	// Register track
-	app.Library.Tracks = append(app.Library.Tracks, track)
-	app.Library.TracksByPath[path] = track
+	lib.Tracks = append(lib.Tracks, track)
+	lib.TracksByPath[track.Path] = track
 
 	// Build album key
-	albumArtist := track.AlbumArtist
-	if albumArtist == "" {
-		albumArtist = track.Artist
-	}
-	albumKey := albumArtist + "|" + track.Album
+	albumArtist := trackAlbumArtist(track)
+	albumKey := albumKeyFor(track)
 
 	// Register or get album
-	album, exists := app.Library.AlbumsByKey[albumKey]
+	album, exists := lib.AlbumsByKey[albumKey]
 	if !exists {
 		album = &Album{
 			Name:   track.Album,
 			Artist: albumArtist,
 		}
-		app.Library.AlbumsByKey[albumKey] = album
-		app.Library.Albums = append(app.Library.Albums, album)
+		album.ArtPath = prevArt[albumKey]
+		lib.AlbumsByKey[albumKey] = album
+		lib.Albums = append(lib.Albums, album)
 	}
 	album.Tracks = append(album.Tracks, track)
 
 	// Extract art for album (first track with art wins)
 	if track.HasArt && album.ArtData == nil && album.ArtPath == "" {
-		logMsg(fmt.Sprintf("[EXTRACT] Attempting to extract art for album: %s - %s from %s",
-			album.Artist, album.Name, filepath.Base(track.Path)))
-		f.Seek(0, 0)
-		if m2, err2 := tag.ReadFrom(f); err2 == nil {
-			if pic := m2.Picture(); pic != nil {
-				album.ArtData = pic.Data
-				album.ArtExt = pic.Ext
-				logMsg(fmt.Sprintf("[EXTRACT] ✓ SUCCESS: %s - %s | Source: %s | Size: %d bytes, Type: %s, Ext: %s",
-					album.Artist, album.Name, filepath.Base(track.Path), len(pic.Data), pic.MIMEType, pic.Ext))
-
-				// Save to disk to avoid re-extraction on next startup
-				if err := app.saveAlbumArtwork(album); err != nil {
-					logMsg(fmt.Sprintf("[EXTRACT] Warning: Failed to save artwork to disk: %v", err))
-				}
-			} else {
-				logMsg(fmt.Sprintf("[EXTRACT] ✗ FAILED: Track has art flag but Picture() returned nil: %s | Format: %T",
-					track.Path, m2))
-			}
-		} else {
-			logMsg(fmt.Sprintf("[EXTRACT] ✗ FAILED: Re-read tag error: %s | Error: %v", track.Path, err2))
-		}
+		extractTrackArt(album, track)
 	} else if !track.HasArt && album.ArtData == nil && album.ArtPath == "" {
 		logMsg(fmt.Sprintf("[EXTRACT] Skipping %s - track.HasArt=false, album %s - %s has no art yet",
 			filepath.Base(track.Path), album.Artist, album.Name))
 	}
 
 	// Register artist
-	artist, exists := app.Library.ArtistsByName[albumArtist]
+	artist, exists := lib.ArtistsByName[albumArtist]
 	if !exists {
 		artist = &Artist{Name: albumArtist}
-		app.Library.ArtistsByName[albumArtist] = artist
-		app.Library.Artists = append(app.Library.Artists, artist)
+		lib.ArtistsByName[albumArtist] = artist
+		lib.Artists = append(lib.Artists, artist)
 	}
 
 	// Avoid duplicate album refs on same artist
@@ -273,6 +395,42 @@ func (app *MiyooPod) scanTrack(path string) {
 	}
 }
 
+// extractTrackArt reads the embedded picture from a track and saves it as the album's artwork
+func extractTrackArt(album *Album, track *Track) {
+	logMsg(fmt.Sprintf("[EXTRACT] Attempting to extract art for album: %s - %s from %s",
+		album.Artist, album.Name, filepath.Base(track.Path)))
+
+	f, err := os.Open(trackFile(track))
+	if err != nil {
+		logMsg(fmt.Sprintf("[EXTRACT] ✗ FAILED: Open error: %s | Error: %v", track.Path, err))
+		return
+	}
+	defer f.Close()
+
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		logMsg(fmt.Sprintf("[EXTRACT] ✗ FAILED: Re-read tag error: %s | Error: %v", track.Path, err))
+		return
+	}
+
+	pic := m.Picture()
+	if pic == nil {
+		logMsg(fmt.Sprintf("[EXTRACT] ✗ FAILED: Track has art flag but Picture() returned nil: %s | Format: %T",
+			track.Path, m))
+		return
+	}
+
+	album.ArtData = pic.Data
+	album.ArtExt = pic.Ext
+	logMsg(fmt.Sprintf("[EXTRACT] ✓ SUCCESS: %s - %s | Source: %s | Size: %d bytes, Type: %s, Ext: %s",
+		album.Artist, album.Name, filepath.Base(track.Path), len(pic.Data), pic.MIMEType, pic.Ext))
+
+	// Save to disk to avoid re-extraction on next startup
+	if err := saveAlbumArtwork(album); err != nil {
+		logMsg(fmt.Sprintf("[EXTRACT] Warning: Failed to save artwork to disk: %v", err))
+	}
+}
+
 // fetchMissingAlbumArt fetches album artwork from MusicBrainz for albums without embedded art
 func (app *MiyooPod) fetchMissingAlbumArt() {
 	missingCount := 0
@@ -294,7 +452,8 @@ func (app *MiyooPod) fetchMissingAlbumArt() {
 
 	for _, album := range app.Library.Albums {
 		if album.ArtData == nil && album.Name != "" && album.Artist != "" {
-			if app.fetchAlbumArtFromMusicBrainz(album) {
+			if path, ok := fetchAlbumArtFromMusicBrainz(album.Artist, album.Name, nil); ok {
+				album.ArtPath = path
 				fetchedCount++
 			}
 		}
@@ -319,9 +478,28 @@ func (app *MiyooPod) decodeArtwork(artData []byte, artExt string) image.Image {
 	return img
 }
 
-// decodeAlbumArt decodes raw art bytes into images for all albums
-// NOW: Only decode on-demand to save memory and startup time
-func (app *MiyooPod) decodeAlbumArt() {
+// coverCacheKey is the CoverCache key for an album's cover at a given size
+func coverCacheKey(album *Album, size int) string {
+	return fmt.Sprintf("%s|%s_%d", album.Artist, album.Name, size)
+}
+
+// renderCover decodes artwork bytes and scales them to a size x size cover
+func renderCover(artData []byte, size int) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(artData))
+	if err != nil {
+		return nil, err
+	}
+	srcBounds := img.Bounds()
+	dc := gg.NewContext(size, size)
+	dc.Scale(float64(size)/float64(srcBounds.Dx()), float64(size)/float64(srcBounds.Dy()))
+	dc.DrawImage(img, 0, 0)
+	return dc.Image(), nil
+}
+
+// decodeAlbumArt loads covers for the given albums into cache, from the RGBA
+// pixel cache when possible. Touches only its arguments, so the library scan
+// can run it on albums that aren't installed yet.
+func (app *MiyooPod) decodeAlbumArt(albums []*Album, cache map[string]image.Image) {
 	start := time.Now()
 
 	size := COVER_CENTER_SIZE
@@ -330,14 +508,14 @@ func (app *MiyooPod) decodeAlbumArt() {
 	noArtCount := 0
 	rgbaCacheHits := 0
 
-	for i, album := range app.Library.Albums {
-		key := fmt.Sprintf("%s|%s_%d", album.Artist, album.Name, size)
+	for i, album := range albums {
+		key := coverCacheKey(album, size)
 
 		// Fast path: check for pre-resized RGBA pixel cache
 		rgbaPath := app.rgbaCachePath(album)
 		if rgbaPath != "" {
 			if img := app.loadRGBACache(rgbaPath, size); img != nil {
-				app.Coverflow.CoverCache[key] = img
+				cache[key] = img
 				album.ArtData = nil
 				album.ArtImg = nil
 				rgbaCacheHits++
@@ -360,10 +538,9 @@ func (app *MiyooPod) decodeAlbumArt() {
 		}
 
 		logMsg(fmt.Sprintf("[ART] Decoding %d/%d: %s - %s (%d bytes)",
-			i+1, len(app.Library.Albums), album.Artist, album.Name, len(album.ArtData)))
+			i+1, len(albums), album.Artist, album.Name, len(album.ArtData)))
 
-		reader := bytes.NewReader(album.ArtData)
-		img, _, err := image.Decode(reader)
+		resized, err := renderCover(album.ArtData, size)
 		if err != nil {
 			failCount++
 			logMsg(fmt.Sprintf("WARNING: Failed to decode art for %s - %s: %v", album.Artist, album.Name, err))
@@ -371,16 +548,7 @@ func (app *MiyooPod) decodeAlbumArt() {
 		}
 
 		successCount++
-
-		// Resize to target size
-		srcBounds := img.Bounds()
-		dc := gg.NewContext(size, size)
-		sx := float64(size) / float64(srcBounds.Dx())
-		sy := float64(size) / float64(srcBounds.Dy())
-		dc.Scale(sx, sy)
-		dc.DrawImage(img, 0, 0)
-		resized := dc.Image()
-		app.Coverflow.CoverCache[key] = resized
+		cache[key] = resized
 
 		// Save RGBA cache for next startup
 		if rgbaPath != "" {
@@ -395,8 +563,12 @@ func (app *MiyooPod) decodeAlbumArt() {
 
 	logMsg(fmt.Sprintf("INFO: Album art: %d cached (%d RGBA fast), %d decoded, %d failed, %d no art | %v",
 		successCount, rgbaCacheHits, successCount-rgbaCacheHits, failCount, noArtCount, time.Since(start)))
+}
 
-	// Generate default album art
+// initDefaultArt renders the placeholder cover for albums without art.
+// Main loop only: it draws with the shared UI font.
+func (app *MiyooPod) initDefaultArt() {
+	size := COVER_CENTER_SIZE
 	dc := gg.NewContext(size, size)
 	dc.SetHexColor("#333333")
 	dc.Clear()
@@ -414,7 +586,11 @@ func (app *MiyooPod) rgbaCachePath(album *Album) string {
 	if album.ArtPath == "" && album.ArtData == nil {
 		return ""
 	}
-	hash := generateAlbumCacheKey(album.Artist, album.Name)
+	return rgbaCachePathFor(album.Artist, album.Name)
+}
+
+func rgbaCachePathFor(artist, name string) string {
+	hash := generateAlbumCacheKey(artist, name)
 	return fmt.Sprintf("%s%s_%d.rgba", ARTWORK_DIR, hash, COVER_CENTER_SIZE)
 }
 
@@ -441,72 +617,92 @@ func (app *MiyooPod) saveRGBACache(path string, img *image.RGBA) {
 	}
 }
 
-// deferredArtExtraction runs in background after startup to extract album art
-// from MP3 tags for albums that don't have cached artwork yet.
+// deferredArtExtraction runs after startup to extract album art from
+// embedded tags for albums that don't have cached artwork yet. Tag reading
+// and decoding happen in the background; each result is installed on the
+// main loop.
 func (app *MiyooPod) deferredArtExtraction() {
-	extracted := 0
+	type artJob struct {
+		album *Album
+		paths []string
+	}
+	var jobs []artJob
 	for _, album := range app.Library.Albums {
 		// Skip albums that already have art (loaded from cache or RGBA)
 		if album.ArtData != nil || album.ArtPath != "" {
 			continue
 		}
-
-		// Try extracting from MP3 files
+		job := artJob{album: album}
 		for _, track := range album.Tracks {
-			f, err := os.Open(track.Path)
-			if err != nil {
-				continue
-			}
-			m, err := tag.ReadFrom(f)
-			if err != nil {
-				f.Close()
-				continue
-			}
-			pic := m.Picture()
-			f.Close()
-			if pic == nil {
-				continue
+			job.paths = append(job.paths, trackFile(track))
+		}
+		jobs = append(jobs, job)
+	}
+	if len(jobs) == 0 {
+		return
+	}
+
+	go func() {
+		extracted := 0
+		for _, job := range jobs {
+			if !app.Running.Load() {
+				return
 			}
+			artist, name := job.album.Artist, job.album.Name
 
-			album.ArtData = pic.Data
-			album.ArtExt = pic.Ext
-
-			// Save to disk for next time
-			if err := app.saveAlbumArtwork(album); err == nil {
-				// Also generate RGBA cache
-				rgbaPath := app.rgbaCachePath(album)
-				if rgbaPath != "" {
-					reader := bytes.NewReader(pic.Data)
-					if img, _, err := image.Decode(reader); err == nil {
-						size := COVER_CENTER_SIZE
-						dc := gg.NewContext(size, size)
-						srcBounds := img.Bounds()
-						dc.Scale(float64(size)/float64(srcBounds.Dx()), float64(size)/float64(srcBounds.Dy()))
-						dc.DrawImage(img, 0, 0)
-						resized := dc.Image()
-
-						key := fmt.Sprintf("%s|%s_%d", album.Artist, album.Name, size)
-						app.Coverflow.CoverCache[key] = resized
-
-						if rgba, ok := resized.(*image.RGBA); ok {
-							app.saveRGBACache(rgbaPath, rgba)
-						}
+			// Try extracting from the album's audio files
+			for _, path := range job.paths {
+				pic := readEmbeddedPicture(path)
+				if pic == nil {
+					continue
+				}
+
+				// Save to disk for next time, with an RGBA cache for the cover
+				artPath, err := writeAlbumArtwork(artist, name, pic.Ext, pic.Data)
+				if err != nil {
+					break
+				}
+				var cover image.Image
+				if img, err := renderCover(pic.Data, COVER_CENTER_SIZE); err == nil {
+					cover = img
+					if rgba, ok := img.(*image.RGBA); ok {
+						app.saveRGBACache(rgbaCachePathFor(artist, name), rgba)
 					}
 				}
+
+				album := job.album
+				app.post(func() {
+					album.ArtPath = artPath
+					album.ArtExt = pic.Ext
+					if cover != nil {
+						app.Coverflow.CoverCache[coverCacheKey(album, COVER_CENTER_SIZE)] = cover
+					}
+					app.NPCacheDirty = true
+					app.requestRedraw()
+				})
+				extracted++
+				break // Got art for this album
 			}
+		}
 
-			album.ArtData = nil
-			extracted++
-			app.requestRedraw()
-			break // Got art for this album
+		if extracted > 0 {
+			logMsg(fmt.Sprintf("INFO: Background art extraction: found art for %d albums", extracted))
 		}
-	}
+	}()
+}
 
-	if extracted > 0 {
-		logMsg(fmt.Sprintf("INFO: Background art extraction: found art for %d albums", extracted))
-		app.NPCacheDirty = true
-		app.requestRedraw()
+// readEmbeddedPicture returns the picture embedded in an audio file's tags, or nil
+func readEmbeddedPicture(path string) *tag.Picture {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
 	}
+	defer f.Close()
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		return nil
+	}
+	return m.Picture()
 }
 
 // drawLibraryScanScreen renders the library scan progress screen.
@@ -620,6 +816,22 @@ func (app *MiyooPod) drawLibraryScanResults() {
 			dc.DrawStringAnchored("Playlists", float64(MENU_LEFT_PAD), textY, 0, 0.5)
 			dc.SetHexColor(app.CurrentTheme.Accent)
 			dc.DrawStringAnchored(fmt.Sprintf("%d", len(app.Library.Playlists)), float64(SCREEN_WIDTH-MENU_RIGHT_PAD), textY, 1, 0.5)
+			y += MENU_ITEM_HEIGHT
+		}
+
+		// Changes since the previous scan
+		dc.SetFontFace(app.FontSmall)
+		dc.SetHexColor(app.CurrentTheme.Dim)
+		textY = float64(y) + float64(MENU_ITEM_HEIGHT)/2
+		dc.DrawStringAnchored(fmt.Sprintf("%d new, %d changed, %d removed",
+			app.LibScanAdded, app.LibScanUpdated, app.LibScanRemoved), float64(MENU_LEFT_PAD), textY, 0, 0.5)
+		y += MENU_ITEM_HEIGHT
+
+		// Playlist entries that point at missing files
+		if app.LibScanUnresolved > 0 {
+			textY = float64(y) + float64(MENU_ITEM_HEIGHT)/2
+			dc.DrawStringAnchored(fmt.Sprintf("%d playlist entries not found (see log)", app.LibScanUnresolved),
+				float64(MENU_LEFT_PAD), textY, 0, 0.5)
 		}
 	}
 }
@@ -658,20 +870,26 @@ func (app *MiyooPod) handleLibraryScanKey(key Key) {
 
 // saveLibraryJSON writes the library to a JSON file
 func (app *MiyooPod) saveLibraryJSON() error {
-	if app.Library == nil {
+	return writeLibraryJSON(app.Library)
+}
+
+// writeLibraryJSON writes lib to the library file. Used directly by the
+// library scan, which saves before installing the new library.
+func writeLibraryJSON(lib *Library) error {
+	if lib == nil {
 		return fmt.Errorf("library is nil")
 	}
 
 	logMsg("Saving library to JSON...")
 	start := time.Now()
 
-	data, err := json.MarshalIndent(app.Library, "", "  ")
+	lib.Version = LIBRARY_SCHEMA_VERSION
+	data, err := json.MarshalIndent(lib, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal library: %v", err)
 	}
 
-	err = os.WriteFile(LIBRARY_JSON_PATH, data, 0644)
-	if err != nil {
+	if err := writeLibraryFile(data); err != nil {
 		return fmt.Errorf("failed to write library file: %v", err)
 	}
 
@@ -679,25 +897,15 @@ func (app *MiyooPod) saveLibraryJSON() error {
 	return nil
 }
 
-// loadLibraryJSON loads the library from a JSON file
+// loadLibraryJSON loads the library from a JSON file, or from its backup if
+// the file is damaged (see readLibraryWithRecovery)
 func (app *MiyooPod) loadLibraryJSON() error {
 	logMsg("Loading library from JSON...")
 	start := time.Now()
 
-	data, err := os.ReadFile(LIBRARY_JSON_PATH)
+	lib, err := readLibraryWithRecovery()
 	if err != nil {
-		return fmt.Errorf("failed to read library file: %v", err)
-	}
-
-	lib := &Library{
-		TracksByPath:  make(map[string]*Track),
-		AlbumsByKey:   make(map[string]*Album),
-		ArtistsByName: make(map[string]*Artist),
-	}
-
-	err = json.Unmarshal(data, lib)
-	if err != nil {
-		return fmt.Errorf("failed to unmarshal library: %v", err)
+		return err
 	}
 
 	app.Library = lib
@@ -727,20 +935,14 @@ func (app *MiyooPod) loadLibraryJSON() error {
 
 	// Rebuild track-album relationships and extract album art
 	for _, track := range lib.Tracks {
-		albumArtist := track.AlbumArtist
-		if albumArtist == "" {
-			albumArtist = track.Artist
-		}
-		albumKey := albumArtist + "|" + track.Album
-
-		if album, exists := lib.AlbumsByKey[albumKey]; exists {
+		if album, exists := lib.AlbumsByKey[albumKeyFor(track)]; exists {
 			album.Tracks = append(album.Tracks, track)
 		}
 	}
 
 	// Artwork loading is handled by decodeAlbumArt() which checks RGBA pixel cache first
 	// (fast file read), falling back to raw image decode only when needed.
-	// MP3 art extraction for albums without any cached art is deferred to background.
+	// Tag art extraction for albums without any cached art is deferred to background.
 
 	// Rebuild artist-album relationships
 	for _, album := range lib.Albums {
@@ -759,13 +961,15 @@ func (app *MiyooPod) loadLibraryJSON() error {
 		}
 	}
 
+	// Sort again, in case the article setting changed since the library was saved
+	lib.sort()
+
 	// Parse playlists (they're just references, need to be re-read)
-	for _, pl := range lib.Playlists {
-		app.parsePlaylist(pl)
-	}
+	lib.parsePlaylists()
 
 	// Decode album art
-	app.decodeAlbumArt()
+	app.decodeAlbumArt(lib.Albums, app.Coverflow.CoverCache)
+	app.initDefaultArt()
 
 	logMsg(fmt.Sprintf("INFO: Library loaded from JSON: %d tracks, %d albums, %d artists, %d playlists in %v",
 		len(lib.Tracks), len(lib.Albums), len(lib.Artists), len(lib.Playlists), time.Since(start)))
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// LIBRARY_SCHEMA_VERSION is the layout of the library file. Bump it when a
+// change needs more than new fields with usable zero values, and add a step
+// to libraryMigrations to carry older files forward.
+const LIBRARY_SCHEMA_VERSION = 1
+
+// libraryMigrations[v] upgrades a decoded library file from version v to v+1.
+// Files written before the schema was versioned are version 0.
+var libraryMigrations = []func(doc map[string]json.RawMessage) error{
+	// 0 -> 1: same layout, now stamped with its version
+	func(doc map[string]json.RawMessage) error { return nil },
+}
+
+// libraryWriteMu keeps the scan and the main loop from writing the library
+// file at the same time
+var libraryWriteMu sync.Mutex
+
+// libraryBackupPath is the last good copy of the library file, kept by
+// writeLibraryFile and used when the library file is damaged or missing
+func libraryBackupPath() string {
+	return LIBRARY_JSON_PATH + ".bak"
+}
+
+// writeLibraryFile replaces the library file without ever leaving a partial
+// one: data goes to a temp file that is synced before being renamed into
+// place, and the previous file is kept as the backup.
+func writeLibraryFile(data []byte) error {
+	libraryWriteMu.Lock()
+	defer libraryWriteMu.Unlock()
+
+	tmp := LIBRARY_JSON_PATH + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	// The current file was written the same way, so it is complete
+	if err := os.Rename(LIBRARY_JSON_PATH, libraryBackupPath()); err != nil && !os.IsNotExist(err) {
+		logMsg(fmt.Sprintf("WARNING: Could not keep library backup: %v", err))
+	}
+	if err := os.Rename(tmp, LIBRARY_JSON_PATH); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	syncDir(filepath.Dir(LIBRARY_JSON_PATH))
+	return nil
+}
+
+// syncDir flushes a directory's entries so a rename survives power loss.
+// Best effort: not every filesystem supports it.
+func syncDir(dir string) {
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+}
+
+// readLibraryFile decodes the library file at path, migrating older versions
+// and checking the result is whole
+func readLibraryFile(path string) (*Library, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, fmt.Errorf("damaged library file: %v", err)
+	}
+	if header.Version > LIBRARY_SCHEMA_VERSION {
+		return nil, fmt.Errorf("library file is version %d, newer than this build (%d)",
+			header.Version, LIBRARY_SCHEMA_VERSION)
+	}
+	if header.Version < LIBRARY_SCHEMA_VERSION {
+		if data, err = migrateLibraryFile(data, header.Version); err != nil {
+			return nil, err
+		}
+	}
+
+	lib := &Library{
+		TracksByPath:  make(map[string]*Track),
+		AlbumsByKey:   make(map[string]*Album),
+		ArtistsByName: make(map[string]*Artist),
+	}
+	if err := json.Unmarshal(data, lib); err != nil {
+		return nil, fmt.Errorf("damaged library file: %v", err)
+	}
+	if err := lib.validate(); err != nil {
+		return nil, fmt.Errorf("damaged library file: %v", err)
+	}
+	return lib, nil
+}
+
+// migrateLibraryFile runs the migrations from version up to the current one
+func migrateLibraryFile(data []byte, version int) ([]byte, error) {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("damaged library file: %v", err)
+	}
+	for v := version; v < LIBRARY_SCHEMA_VERSION; v++ {
+		if err := libraryMigrations[v](doc); err != nil {
+			return nil, fmt.Errorf("migrating library file from version %d: %v", v, err)
+		}
+	}
+	doc["version"] = json.RawMessage(fmt.Sprint(LIBRARY_SCHEMA_VERSION))
+
+	logMsg(fmt.Sprintf("INFO: Migrated library file from version %d to %d", version, LIBRARY_SCHEMA_VERSION))
+	return json.Marshal(doc)
+}
+
+// validate rejects libraries that decoded but can't be used, such as a file
+// cut short inside a list or zeroed entries
+func (lib *Library) validate() error {
+	for i, t := range lib.Tracks {
+		if t == nil || t.Path == "" {
+			return fmt.Errorf("track %d has no path", i)
+		}
+	}
+	for i, album := range lib.Albums {
+		if album == nil {
+			return fmt.Errorf("album %d is empty", i)
+		}
+	}
+	for i, artist := range lib.Artists {
+		if artist == nil {
+			return fmt.Errorf("artist %d is empty", i)
+		}
+	}
+	return nil
+}
+
+// readLibraryWithRecovery reads the library file, falling back to the backup
+// when it is missing or damaged. A recovered backup is written back as the
+// library file.
+func readLibraryWithRecovery() (*Library, error) {
+	lib, err := readLibraryFile(LIBRARY_JSON_PATH)
+	if err == nil {
+		return lib, nil
+	}
+
+	backup, backupErr := readLibraryFile(libraryBackupPath())
+	if backupErr != nil {
+		if os.IsNotExist(err) && os.IsNotExist(backupErr) {
+			return nil, fmt.Errorf("failed to read library file: %v", err)
+		}
+		return nil, fmt.Errorf("library file unusable (%v), backup unusable (%v)", err, backupErr)
+	}
+
+	logMsg(fmt.Sprintf("WARNING: Library file unusable (%v); recovered from backup", err))
+	if data, err := os.ReadFile(libraryBackupPath()); err == nil {
+		// Restore the backup without rotating the damaged file over it
+		if err := os.WriteFile(LIBRARY_JSON_PATH+".tmp", data, 0644); err == nil {
+			os.Rename(LIBRARY_JSON_PATH+".tmp", LIBRARY_JSON_PATH)
+		}
+	}
+	return backup, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// useTempMusicRoot points the library at a fresh folder holding n untagged
+// tracks and restores the previous paths when the test ends
+func useTempMusicRoot(t *testing.T, n int) string {
+	t.Helper()
+
+	root := t.TempDir()
+	prevRoots, prevRoot := MUSIC_ROOTS, MUSIC_ROOT
+	prevJSON, prevArt := LIBRARY_JSON_PATH, ARTWORK_DIR
+	t.Cleanup(func() {
+		MUSIC_ROOTS, MUSIC_ROOT = prevRoots, prevRoot
+		LIBRARY_JSON_PATH, ARTWORK_DIR = prevJSON, prevArt
+	})
+	setMusicRoots([]string{root})
+	LIBRARY_JSON_PATH = filepath.Join(root, "library.json")
+	ARTWORK_DIR = filepath.Join(root, "artwork") + string(filepath.Separator)
+
+	writeUntaggedTracks(t, filepath.Join(root, "Artist", "Album"), n)
+	return root
+}
+
+// writeUntaggedTracks creates n placeholder .mp3 files in dir
+func writeUntaggedTracks(t *testing.T, dir string, n int) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= n; i++ {
+		os.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.mp3", i)), []byte("not really audio"), 0644)
+	}
+}
+
+// runScan starts a library scan and runs the main loop until it completes,
+// redrawing the scan screen and playing as it goes
+func runScan(t *testing.T, app *MiyooPod, sim *simAudio) {
+	t.Helper()
+
+	app.startLibraryScan(nil)
+	poller := &playbackPoller{}
+	deadline := time.Now().Add(5 * time.Second)
+	for !app.LibScanDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("scan did not finish (status %q)", app.LibScanStatus)
+		}
+		app.runPosted()
+		app.drawCurrentScreen()
+		sim.Advance(100 * time.Millisecond)
+		app.pollPlayback(poller)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLibraryScanInstallsOnMainLoop(t *testing.T) {
+	useTempMusicRoot(t, 12)
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 0)
+	previous := app.Library
+
+	runScan(t, app, sim)
+
+	if app.Library == previous {
+		t.Fatalf("scan did not install a new library")
+	}
+	if got := len(app.Library.Tracks); got != 12 {
+		t.Fatalf("library has %d tracks, want 12", got)
+	}
+	if app.LibScanAdded != 12 || app.LibScanRunning {
+		t.Errorf("added %d, running %v; want 12, false", app.LibScanAdded, app.LibScanRunning)
+	}
+	if app.Playing.Track == nil || app.Playing.Track.Path != trackPath(1) {
+		t.Errorf("scan changed the playing track")
+	}
+}
+
+func TestLibraryRescanReusesUnchangedTracks(t *testing.T) {
+	useTempMusicRoot(t, 6)
+	app, sim := newTestApp(t, 0)
+
+	runScan(t, app, sim)
+	first := app.Library
+	runScan(t, app, sim)
+
+	if app.LibScanAdded != 0 || app.LibScanUpdated != 0 || app.LibScanRemoved != 0 {
+		t.Errorf("rescan: %d new, %d changed, %d removed; want none",
+			app.LibScanAdded, app.LibScanUpdated, app.LibScanRemoved)
+	}
+	for _, track := range app.Library.Tracks {
+		old := first.TracksByPath[track.Path]
+		if old == nil {
+			t.Fatalf("%s missing from the first scan", track.Path)
+		}
+		if old == track {
+			t.Errorf("%s shared with the previous library instead of copied", track.Path)
+		}
+	}
+}
+
+func TestPostRunsOnMainLoopInOrder(t *testing.T) {
+	app, _ := newTestApp(t, 0)
+
+	done := make(chan struct{})
+	go func() {
+		for i := 1; i <= 10; i++ {
+			n := i
+			app.post(func() { app.LibScanCount = n })
+		}
+		close(done)
+	}()
+	<-done
+
+	app.runPosted()
+	if app.LibScanCount != 10 {
+		t.Errorf("LibScanCount = %d after running posted work, want 10", app.LibScanCount)
+	}
+}
+
+func TestLibraryScanWalksAllRoots(t *testing.T) {
+	music := useTempMusicRoot(t, 4)
+	books := t.TempDir()
+	writeUntaggedTracks(t, filepath.Join(books, "Author", "Book"), 3)
+	missing := filepath.Join(t.TempDir(), "unmounted")
+	setMusicRoots([]string{music, books, missing})
+
+	app, sim := newTestApp(t, 0)
+	runScan(t, app, sim)
+
+	if got := len(app.Library.Tracks); got != 7 {
+		t.Fatalf("library has %d tracks, want 7 from both roots", got)
+	}
+	if _, ok := app.Library.TracksByPath[filepath.Join(books, "Author", "Book", "02.mp3")]; !ok {
+		t.Errorf("track from the second root missing")
+	}
+}
+
+func TestNormalizeMusicRoots(t *testing.T) {
+	got := normalizeMusicRoots([]string{"/music/a", " ", "/books", "/music/a/sub", "/music/", "/books/"})
+	want := []string{"/books/", "/music/"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("normalizeMusicRoots = %v, want %v", got, want)
+	}
+}
+
+func TestLibraryFileRecoversFromBackup(t *testing.T) {
+	useTempMusicRoot(t, 5)
+	app, sim := newTestApp(t, 0)
+	runScan(t, app, sim)
+	if err := app.saveLibraryJSON(); err != nil { // Keeps the scan's file as the backup
+		t.Fatal(err)
+	}
+
+	// Power lost mid-write on a filesystem without atomic renames
+	data, _ := os.ReadFile(LIBRARY_JSON_PATH)
+	os.WriteFile(LIBRARY_JSON_PATH, data[:len(data)/2], 0644)
+
+	if err := app.loadLibraryJSON(); err != nil {
+		t.Fatalf("load with a truncated file: %v", err)
+	}
+	if got := len(app.Library.Tracks); got != 5 {
+		t.Fatalf("recovered library has %d tracks, want 5", got)
+	}
+	if _, err := readLibraryFile(LIBRARY_JSON_PATH); err != nil {
+		t.Errorf("library file not restored from the backup: %v", err)
+	}
+
+	// Zeroed file with no backup left
+	os.WriteFile(LIBRARY_JSON_PATH, make([]byte, 512), 0644)
+	os.Remove(libraryBackupPath())
+	if err := app.loadLibraryJSON(); err == nil {
+		t.Errorf("zeroed library file loaded without error")
+	}
+}
+
+func TestLibraryFileMigratesUnversioned(t *testing.T) {
+	useTempMusicRoot(t, 0)
+	app, _ := newTestApp(t, 0)
+
+	os.WriteFile(LIBRARY_JSON_PATH, []byte(`{"tracks": [{"path": "/m/a.mp3", "title": "A", "artist": "X", "album": "Y"}],
+		"albums": [{"name": "Y", "artist": "X"}], "artists": [{"name": "X"}], "playlists": null}`), 0644)
+	if err := app.loadLibraryJSON(); err != nil {
+		t.Fatalf("load unversioned file: %v", err)
+	}
+	if app.Library.Version != LIBRARY_SCHEMA_VERSION || len(app.Library.Albums[0].Tracks) != 1 {
+		t.Errorf("version %d with %d album tracks, want %d with 1",
+			app.Library.Version, len(app.Library.Albums[0].Tracks), LIBRARY_SCHEMA_VERSION)
+	}
+
+	os.WriteFile(LIBRARY_JSON_PATH, []byte(fmt.Sprintf(`{"version": %d, "tracks": []}`, LIBRARY_SCHEMA_VERSION+1)), 0644)
+	if err := app.loadLibraryJSON(); err == nil {
+		t.Errorf("loaded a library file from a newer version")
+	}
+}
@@ -1,13 +1,10 @@
 package main
 
-/*
-#include <stdlib.h>
-*/
-import "C"
 import (
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -36,7 +33,7 @@ func init() {
 //	logMsg("INFO: Playback started") -> sent to PostHog as info (if enabled), written to file (if enabled)
 func logMsg(message string) {
 	// Write to local log file if enabled
-	if globalApp != nil && globalApp.LocalLogsEnabled {
+	if globalApp != nil && globalApp.LocalLogsEnabled.Load() {
 		logFile.WriteString(time.Now().Format("2006-01-02 15:04:05.999") + " - " + message + "\n")
 	}
 
@@ -85,50 +82,38 @@ func extractLogAttributes(message string) map[string]interface{} {
 	}
 
 	// Add current app context
-	if globalApp != nil {
-		if globalApp.CurrentTheme.Name != "" {
-			attrs["theme"] = globalApp.CurrentTheme.Name
+	if ctx := currentLogContext.Load(); ctx != nil {
+		if ctx.theme != "" {
+			attrs["theme"] = ctx.theme
 		}
-		if globalApp.Queue != nil {
-			if globalApp.Queue.Shuffle {
-				attrs["shuffle"] = "enabled"
-			} else {
-				attrs["shuffle"] = "disabled"
-			}
+		if ctx.shuffle != "" {
+			attrs["shuffle"] = ctx.shuffle
 		}
 	}
 
 	return attrs
 }
 
-//export GoLogMsg
-func GoLogMsg(cMsg *C.char) {
-	msg := C.GoString(cMsg)
-	logMsg(msg)
+// logContext is the app state attached to captured logs. logMsg runs on any
+// goroutine, so it reads this snapshot instead of the app itself.
+type logContext struct {
+	theme   string
+	shuffle string
 }
 
-//export DetectDevice
-func DetectDevice(width, height C.int) {
-	if globalApp == nil {
-		return
-	}
-
-	w := int(width)
-	h := int(height)
-
-	globalApp.DisplayWidth = w
-	globalApp.DisplayHeight = h
+var currentLogContext atomic.Pointer[logContext]
 
-	// Determine device model based on resolution
-	if w == 640 && h == 480 {
-		globalApp.DeviceModel = "miyoo-mini-plus"
-	} else if w == 750 && h == 560 {
-		// Both v4 and flip have the same resolution
-		// We'll label them as v4 by default, could be either
-		globalApp.DeviceModel = "miyoo-mini-v4"
-	} else {
-		globalApp.DeviceModel = fmt.Sprintf("unknown-%dx%d", w, h)
+// updateLogContext refreshes the log snapshot. Called by the main loop.
+func (app *MiyooPod) updateLogContext() {
+	ctx := logContext{theme: app.CurrentTheme.Name}
+	if app.Queue != nil {
+		if app.Queue.Shuffle {
+			ctx.shuffle = "enabled"
+		} else {
+			ctx.shuffle = "disabled"
+		}
+	}
+	if prev := currentLogContext.Load(); prev == nil || *prev != ctx {
+		currentLogContext.Store(&ctx)
 	}
-
-	logMsg(fmt.Sprintf("INFO: Device detected: %s (%dx%d)", globalApp.DeviceModel, w, h))
 }
@@ -0,0 +1,43 @@
+//go:build !headless
+
+package main
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+import "fmt"
+
+// Callbacks for main.inc
+
+//export GoLogMsg
+func GoLogMsg(cMsg *C.char) {
+	msg := C.GoString(cMsg)
+	logMsg(msg)
+}
+
+//export DetectDevice
+func DetectDevice(width, height C.int) {
+	if globalApp == nil {
+		return
+	}
+
+	w := int(width)
+	h := int(height)
+
+	globalApp.DisplayWidth = w
+	globalApp.DisplayHeight = h
+
+	// Determine device model based on resolution
+	if w == 640 && h == 480 {
+		globalApp.DeviceModel = "miyoo-mini-plus"
+	} else if w == 750 && h == 560 {
+		// Both v4 and flip have the same resolution
+		// We'll label them as v4 by default, could be either
+		globalApp.DeviceModel = "miyoo-mini-v4"
+	} else {
+		globalApp.DeviceModel = fmt.Sprintf("unknown-%dx%d", w, h)
+	}
+
+	logMsg(fmt.Sprintf("INFO: Device detected: %s (%dx%d)", globalApp.DeviceModel, w, h))
+}
@@ -103,7 +103,7 @@ func (app *MiyooPod) generateIconPNG() error {
 	app.DC = originalDC
 
 	// Ensure assets directory exists
-	assetsDir := "./assets"
+	assetsDir := ASSETS_DIR
 	if err := os.MkdirAll(assetsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create assets directory: %v", err)
 	}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dhowden/tag"
+	"github.com/fogleman/gg"
+)
+
+// Lyrics panel on Now Playing, between the track line and the progress bar
+const (
+	LYRICS_PANEL_Y0 = HEADER_HEIGHT + 55
+	LYRICS_PANEL_Y1 = PROGRESS_REGION_Y0 - 6
+	LYRICS_ROW_H    = 30
+	LYRICS_MARGIN   = 30
+)
+
+// LyricLine is one line of lyrics. Time is when it starts, in seconds; it is
+// -1 for unsynced lyrics. Text may hold several rows separated by newlines.
+type LyricLine struct {
+	Time float64
+	Text string
+}
+
+// Lyrics are a track's lyrics, in time order when Synced
+type Lyrics struct {
+	Lines  []LyricLine
+	Synced bool
+}
+
+// loadLyrics finds lyrics for the track at path: a sidecar .lrc file, else
+// embedded synced lyrics (ID3 SYLT), else embedded plain or LRC-formatted
+// lyrics (ID3 USLT, MP4 ©lyr, Vorbis LYRICS). Returns nil if there are none.
+func loadLyrics(path string) *Lyrics {
+	base := strings.TrimSuffix(path, filepath.Ext(path))
+	for _, ext := range []string{".lrc", ".LRC"} {
+		data, err := os.ReadFile(base + ext)
+		if err != nil {
+			continue
+		}
+		// Older LRC files are often Latin-1 rather than UTF-8
+		text := string(data)
+		if !utf8.ValidString(text) {
+			text = decodeID3String(0, data)
+		}
+		if lyrics := parseLRC(text); lyrics != nil {
+			return lyrics
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	m, err := tag.ReadFrom(f)
+	if err != nil {
+		return nil
+	}
+	if m.Format() == tag.ID3v2_3 || m.Format() == tag.ID3v2_4 {
+		if body, ok := readID3Frames(f, "SYLT")["SYLT"]; ok {
+			if lyrics := parseSYLT(body); lyrics != nil {
+				return lyrics
+			}
+		}
+	}
+	return parseLRC(m.Lyrics())
+}
+
+// lrcWordTime matches the per-word timestamps of enhanced LRC ("<00:12.34>")
+var lrcWordTime = regexp.MustCompile(`<\d+:\d+(?:[.:]\d+)?>`)
+
+// parseLRC parses LRC lyrics:
+//
+//	[ar:Artist]              metadata tags, ignored apart from offset
+//	[offset:+250]            milliseconds to show every line earlier
+//	[00:12.30]Line           mm:ss.xx, mm:ss.xxx, mm:ss:xx or mm:ss
+//	[00:45.00][01:30.00]Line one line repeated at several times
+//	Second row               untimed lines continue the line above
+//
+// Text without any timestamps is returned as unsynced lyrics. Returns nil for
+// empty text.
+func parseLRC(text string) *Lyrics {
+	text = strings.TrimPrefix(text, "\uFEFF")
+
+	var synced, plain []LyricLine
+	var offset float64
+	var last []int // Indices in synced of the lines from the last timed row
+
+	for _, row := range strings.Split(text, "\n") {
+		row = strings.TrimSpace(strings.TrimRight(row, "\r"))
+
+		var times []float64
+		isTag := false
+		for strings.HasPrefix(row, "[") {
+			end := strings.Index(row, "]")
+			if end < 0 {
+				break
+			}
+			inside := row[1:end]
+			if t, ok := parseLRCTime(inside); ok {
+				times = append(times, t)
+			} else if key, value, ok := strings.Cut(inside, ":"); ok && isLRCTagKey(key) {
+				if strings.EqualFold(key, "offset") {
+					if ms, err := strconv.Atoi(strings.TrimSpace(value)); err == nil {
+						offset = float64(ms) / 1000
+					}
+				}
+				isTag = true
+			} else {
+				break // Part of the text, e.g. "[Chorus]"
+			}
+			row = strings.TrimSpace(row[end+1:])
+		}
+		row = strings.TrimSpace(lrcWordTime.ReplaceAllString(row, ""))
+
+		switch {
+		case len(times) > 0:
+			last = last[:0]
+			for _, t := range times {
+				last = append(last, len(synced))
+				synced = append(synced, LyricLine{Time: t, Text: row})
+			}
+		case row == "":
+		case len(last) > 0:
+			for _, i := range last {
+				synced[i].Text += "\n" + row
+			}
+		default:
+			if !isTag {
+				plain = append(plain, LyricLine{Time: -1, Text: row})
+			}
+		}
+	}
+
+	if len(synced) == 0 {
+		if len(plain) == 0 {
+			return nil
+		}
+		return &Lyrics{Lines: plain}
+	}
+	for i := range synced {
+		synced[i].Time -= offset
+		if synced[i].Time < 0 {
+			synced[i].Time = 0
+		}
+	}
+	sort.SliceStable(synced, func(i, j int) bool { return synced[i].Time < synced[j].Time })
+	return &Lyrics{Lines: synced, Synced: true}
+}
+
+// parseLRCTime parses an LRC timestamp: mm:ss, mm:ss.xx(x) or mm:ss:xx
+func parseLRCTime(s string) (float64, bool) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, false
+	}
+	min, err := strconv.Atoi(parts[0])
+	if err != nil || min < 0 {
+		return 0, false
+	}
+	if len(parts) == 3 {
+		// mm:ss:xx, with hundredths after a colon
+		parts[1] += "." + parts[2]
+	}
+	sec, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || sec < 0 || sec >= 60 {
+		return 0, false
+	}
+	return float64(min)*60 + sec, true
+}
+
+// isLRCTagKey reports whether key names an LRC metadata tag, as opposed to
+// bracketed lyric text
+func isLRCTagKey(key string) bool {
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "ar", "al", "ti", "au", "by", "re", "ve", "length", "offset", "la", "id", "#":
+		return true
+	}
+	return false
+}
+
+// parseSYLT parses an ID3 SYLT frame with millisecond timestamps. Taggers
+// either store one entry per line or one per syllable with a newline at the
+// start of each line; both come out as one LyricLine per line.
+func parseSYLT(body []byte) *Lyrics {
+	if len(body) < 6 {
+		return nil
+	}
+	encoding, format := body[0], body[4]
+	if format != 2 {
+		return nil // MPEG frame timestamps need the frame rate; rare
+	}
+	_, rest := splitID3String(encoding, body[6:]) // Content descriptor
+
+	type entry struct {
+		text string
+		ms   uint32
+	}
+	var entries []entry
+	syllables := false
+	for len(rest) > 0 {
+		var text string
+		text, rest = splitID3String(encoding, rest)
+		if len(rest) < 4 {
+			break
+		}
+		entries = append(entries, entry{text, binary.BigEndian.Uint32(rest)})
+		rest = rest[4:]
+		if strings.HasPrefix(text, "\n") || strings.HasPrefix(text, "\r") {
+			syllables = true
+		}
+	}
+
+	var lines []LyricLine
+	for i, e := range entries {
+		if syllables && i > 0 && !strings.HasPrefix(e.text, "\n") && !strings.HasPrefix(e.text, "\r") {
+			lines[len(lines)-1].Text += e.text
+			continue
+		}
+		lines = append(lines, LyricLine{Time: float64(e.ms) / 1000, Text: e.text})
+	}
+	for i := range lines {
+		lines[i].Text = strings.TrimSpace(lines[i].Text)
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time < lines[j].Time })
+	return &Lyrics{Lines: lines, Synced: true}
+}
+
+// lineAt returns the index of the line sung at position, or -1 before the
+// first. Unsynced lyrics scroll evenly through the track instead.
+func (l *Lyrics) lineAt(position, duration float64) int {
+	if len(l.Lines) == 0 {
+		return -1
+	}
+	if !l.Synced {
+		if duration <= 0 {
+			return 0
+		}
+		i := int(position / duration * float64(len(l.Lines)))
+		if i >= len(l.Lines) {
+			i = len(l.Lines) - 1
+		}
+		return i
+	}
+	return sort.Search(len(l.Lines), func(i int) bool { return l.Lines[i].Time > position }) - 1
+}
+
+// toggleLyrics switches Now Playing between the cover flow and the lyrics
+func (app *MiyooPod) toggleLyrics() {
+	app.ShowLyrics = !app.ShowLyrics
+	app.NPCacheDirty = true
+}
+
+// ensureLyrics starts loading lyrics for the playing track if they aren't
+// loaded or loading yet. The file is read in the background; the result is
+// installed on the main loop if the track is still playing.
+func (app *MiyooPod) ensureLyrics() {
+	track := app.Playing.Track
+	if track == nil || app.LyricsTrack == track {
+		return
+	}
+	app.LyricsTrack = track
+	app.Lyrics = nil
+	app.LyricsSprites = nil
+	app.LyricsLoading = true
+
+	go func() {
+		// A cue track's file holds the whole album, so its lyrics don't fit
+		var lyrics *Lyrics
+		if track.File == "" {
+			lyrics = loadLyrics(track.Path)
+		}
+		app.post(func() {
+			if app.LyricsTrack != track {
+				return
+			}
+			app.Lyrics = lyrics
+			app.LyricsLoading = false
+			if lyrics != nil {
+				logMsg(fmt.Sprintf("INFO: Loaded %d lines of lyrics for %s (synced: %v)",
+					len(lyrics.Lines), filepath.Base(track.Path), lyrics.Synced))
+			}
+			if app.ShowLyrics {
+				app.NPCacheDirty = true
+				app.requestRedraw()
+			}
+		})
+	}()
+}
+
+// renderLyricsFull draws the static parts of the lyrics view into the frame
+// cached as NowPlayingBG: header, track line, and a message when there are no
+// lines to show. The lines themselves are blitted on top by drawLyricsLines.
+func (app *MiyooPod) renderLyricsFull() {
+	dc := app.DC
+	track := app.Playing.Track
+
+	dc.SetHexColor(app.CurrentTheme.BG)
+	dc.Clear()
+	app.drawHeader("Lyrics")
+
+	dc.SetFontFace(app.FontSmall)
+	dc.SetHexColor(app.CurrentTheme.Dim)
+	line := app.truncateText(track.Title+" - "+track.Artist, float64(SCREEN_WIDTH-2*LYRICS_MARGIN), app.FontSmall)
+	dc.DrawStringAnchored(line, SCREEN_WIDTH/2, float64(HEADER_HEIGHT+25), 0.5, 0.5)
+
+	message := ""
+	switch {
+	case app.LyricsLoading:
+		message = "Loading lyrics..."
+	case app.Lyrics == nil:
+		message = "No lyrics"
+	}
+	if message != "" {
+		dc.SetFontFace(app.FontMenu)
+		dc.DrawStringAnchored(message, SCREEN_WIDTH/2, float64(LYRICS_PANEL_Y0+LYRICS_PANEL_Y1)/2, 0.5, 0.5)
+	}
+}
+
+// buildLyricsSprites pre-renders each line, word-wrapped to the panel, as a
+// white-on-transparent sprite to be tinted when blitted (like DigitSprites)
+func (app *MiyooPod) buildLyricsSprites() {
+	width := SCREEN_WIDTH - 2*LYRICS_MARGIN
+	measure := gg.NewContext(1, 1)
+	measure.SetFontFace(app.FontMenu)
+
+	app.LyricsSprites = make([]*image.RGBA, len(app.Lyrics.Lines))
+	for i, line := range app.Lyrics.Lines {
+		var rows []string
+		for _, part := range strings.Split(line.Text, "\n") {
+			rows = append(rows, measure.WordWrap(part, float64(width))...)
+		}
+		if len(rows) == 0 {
+			rows = []string{""}
+		}
+
+		dc := gg.NewContext(width, len(rows)*LYRICS_ROW_H)
+		dc.SetFontFace(app.FontMenu)
+		dc.SetRGBA(1, 1, 1, 1) // White - tinted when blitting
+		for r, row := range rows {
+			dc.DrawStringAnchored(row, float64(width)/2, float64(r*LYRICS_ROW_H+LYRICS_ROW_H/2), 0.5, 0.5)
+		}
+		app.LyricsSprites[i] = dc.Image().(*image.RGBA)
+	}
+}
+
+// drawLyricsLines blits the lyric lines around the current one, which is
+// centred in the panel and highlighted. Only touches the panel's rows.
+func (app *MiyooPod) drawLyricsLines() {
+	if app.Lyrics == nil {
+		return
+	}
+	if app.LyricsSprites == nil {
+		app.buildLyricsSprites()
+	}
+
+	current := app.Lyrics.lineAt(app.Playing.Position, app.Playing.Duration)
+	app.LyricsLine = current
+	centre := current
+	if centre < 0 {
+		centre = 0
+	}
+
+	// Top of each line relative to the first
+	tops := make([]int, len(app.LyricsSprites)+1)
+	for i, sprite := range app.LyricsSprites {
+		tops[i+1] = tops[i] + sprite.Rect.Dy()
+	}
+	mid := (LYRICS_PANEL_Y0 + LYRICS_PANEL_Y1) / 2
+	shift := mid - (tops[centre]+tops[centre+1])/2
+
+	dimR, dimG, dimB, _ := parseHexColor(app.CurrentTheme.Dim)
+	curR, curG, curB, _ := parseHexColor(app.CurrentTheme.ItemTxt)
+	for i, sprite := range app.LyricsSprites {
+		y := shift + tops[i]
+		if y < LYRICS_PANEL_Y0 || y+sprite.Rect.Dy() > LYRICS_PANEL_Y1 {
+			continue
+		}
+		if i == current && app.Lyrics.Synced {
+			app.fastBlitTinted(sprite, LYRICS_MARGIN, y, curR, curG, curB)
+		} else {
+			app.fastBlitTinted(sprite, LYRICS_MARGIN, y, dimR, dimG, dimB)
+		}
+	}
+}
+
+// updateLyricsOnly is the poller's fast path for the lyrics view: when the
+// current line has moved, it restores the panel from NowPlayingBG and blits
+// the lines at the new position
+func (app *MiyooPod) updateLyricsOnly() {
+	if app.Playing == nil || app.NowPlayingBG == nil || !app.ShowLyrics || app.Lyrics == nil {
+		return
+	}
+	if app.Locked || app.OverlayVisible || app.CurrentScreen != ScreenNowPlaying {
+		return
+	}
+	if app.Lyrics.lineAt(app.Playing.Position, app.Playing.Duration) == app.LyricsLine {
+		return
+	}
+
+	fastCopyRegion(app.FB, app.NowPlayingBG, LYRICS_PANEL_Y0, LYRICS_PANEL_Y1)
+	app.drawLyricsLines()
+	app.triggerRefresh()
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseLRC(t *testing.T) {
+	lyrics := parseLRC("\uFEFF[ar:Artist]\r\n[offset:500]\r\n" +
+		"[00:10.50]First\r\n" +
+		"[00:30.00][01:00.00]Chorus <00:30.50>with <00:31.00>words\r\n" +
+		"and a second row\r\n" +
+		"[00:20:25]Middle\r\n" +
+		"[Chorus]\r\n")
+	if lyrics == nil || !lyrics.Synced {
+		t.Fatalf("lyrics = %+v, want synced", lyrics)
+	}
+
+	var got []string
+	for _, line := range lyrics.Lines {
+		got = append(got, fmt.Sprintf("%.2f %q", line.Time, line.Text))
+	}
+	want := `[10.00 "First" 19.75 "Middle\n[Chorus]" 29.50 "Chorus with words\nand a second row" 59.50 "Chorus with words\nand a second row"]`
+	if fmt.Sprint(got) != want {
+		t.Errorf("lines = %v\nwant    %s", got, want)
+	}
+
+	for position, want := range map[float64]int{0: -1, 10: 0, 25: 1, 30: 2, 90: 3} {
+		if got := lyrics.lineAt(position, 120); got != want {
+			t.Errorf("lineAt(%v) = %d, want %d", position, got, want)
+		}
+	}
+
+	plain := parseLRC("Just words\n\nNo times")
+	if plain == nil || plain.Synced || len(plain.Lines) != 2 {
+		t.Errorf("plain lyrics = %+v", plain)
+	}
+	if parseLRC("[ti:Title]\n") != nil {
+		t.Errorf("tags alone should not make lyrics")
+	}
+}
+
+func TestParseSYLT(t *testing.T) {
+	entry := func(text string, ms int) []byte {
+		return append([]byte(text+"\x00"), byte(ms>>24), byte(ms>>16), byte(ms>>8), byte(ms))
+	}
+	// UTF-8, "eng", milliseconds, lyrics, empty descriptor, one entry per syllable
+	body := []byte{3, 'e', 'n', 'g', 2, 1, 0}
+	body = append(body, entry("\nHel", 1000)...)
+	body = append(body, entry("lo", 1500)...)
+	body = append(body, entry("\nWorld", 4000)...)
+
+	lyrics := parseSYLT(body)
+	if lyrics == nil {
+		t.Fatal("no lyrics")
+	}
+	if got := fmt.Sprint(lyrics.Lines); got != "[{1 Hello} {4 World}]" {
+		t.Errorf("lines = %s", got)
+	}
+}
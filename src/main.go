@@ -1,21 +1,10 @@
 package main
 
-/*
-#cgo CFLAGS: -I/root/include/SDL2 -O2 -w -D_GNU_SOURCE=1 -D_REENTRANT
-#cgo LDFLAGS: -L/root/lib -Wl,-rpath-link,/root/lib -Wl,-rpath,'$ORIGIN' -Wl,--unresolved-symbols=ignore-in-shared-libs -lSDL2 -lSDL2_mixer -lpthread
-#include <stdlib.h>
-#include "main.c"
-#include "audio.c"
-*/
-import "C"
-
 import (
 	"fmt"
 	"image"
-	"os"
 	"runtime"
 	"time"
-	"unsafe"
 
 	"github.com/fogleman/gg"
 )
@@ -32,7 +21,7 @@ func (app *MiyooPod) Init() {
 	globalApp = app
 
 	// Default: local logs disabled
-	app.LocalLogsEnabled = true
+	app.LocalLogsEnabled.Store(true)
 	app.SentryEnabled = true // Default: developer logs enabled
 
 	// Initialize PostHog client early so C logs during SDL init are captured
@@ -40,19 +29,15 @@ func (app *MiyooPod) Init() {
 
 	logMsg("INFO: Initializing MiyooPod...")
 	logMsg("INFO: SDL init...")
-	if C.init() != 0 {
-		logMsg("FATAL: SDL init failed!")
+	if err := sdlInit(); err != nil {
+		logMsg(fmt.Sprintf("FATAL: %v", err))
 		return
 	}
 	logMsg("INFO: SDL init ok!")
 
 	// Init audio
 	logMsg("INFO: Audio init...")
-	if C.audio_init() != 0 {
-		logMsg("WARNING: Failed to init SDL2_mixer audio")
-	} else {
-		logMsg("INFO: Audio init ok!")
-	}
+	app.Audio = newAudioBackend()
 
 	// Create render context at native resolution (640x480)
 	app.DC = gg.NewContext(SCREEN_WIDTH, SCREEN_HEIGHT)
@@ -62,7 +47,7 @@ func (app *MiyooPod) Init() {
 	app.loadFonts()
 
 	// Init state
-	app.Running = true
+	app.Running.Store(true)
 	app.CurrentScreen = ScreenMenu
 	app.CurrentTheme = ThemeClassic // Set default theme
 	app.RepeatDelay = 300 * time.Millisecond
@@ -75,12 +60,15 @@ func (app *MiyooPod) Init() {
 	app.TextMeasureCache = make(map[string]float64)
 	app.RefreshChan = make(chan struct{}, 1)
 	app.RedrawChan = make(chan struct{}, 1)
+	app.Posted = make(chan func(), POSTED_QUEUE_SIZE)
 	app.LockKey = Y // Default lock key
 
 	// Power management defaults
 	app.AutoLockMinutes = 3 // Auto-lock after 3 minutes of inactivity
 	app.ScreenPeekEnabled = true
 	app.UpdateNotifications = true // Default: show update prompts
+	app.IgnoreArticles = true      // Default: "The Beatles" sorts under B
+	app.AudiobookSpeed = 1.0       // Default: audiobooks at normal speed
 	app.LastActivityTime = time.Now()
 
 	// Pre-render digit sprites for fast time display (bypass gg in hot path)
@@ -91,70 +79,123 @@ func (app *MiyooPod) Init() {
 	app.SystemBrightness = 50
 
 	// Always max SDL2_mixer volume — MI_AO controls actual hardware volume
-	audioSetVolume(100)
+	app.Audio.SetVolume(100)
+
+	// Handle track ends as soon as they happen, so the next track starts
+	// without waiting for the playback poller
+	app.Audio.Notify(func() { app.post(app.handleAudioEvent) })
 
 	// Load settings (theme and lock key) before showing splash - fast parse
 	if err := app.loadSettings(); err != nil {
 		logMsg(fmt.Sprintf("WARNING: Could not load settings: %v (using defaults)", err))
 	}
+	app.applySortArticles()
 
 	// Draw splash screen with logo (now using restored theme if available)
 	app.drawLogoSplash()
 
-	// Check for updates asynchronously (don't block startup)
-	app.VersionCheckDone = make(chan struct{})
+	// Check for updates asynchronously (don't block startup); the prompt is
+	// shown once the library is up and the main loop is running
 	go func() {
-		app.checkVersion()
-		close(app.VersionCheckDone)
+		info, _ := fetchLatestVersion()
+		app.post(func() {
+			app.setUpdateInfo(info)
+			// Before the menu exists, main shows the prompt once it's built
+			if app.RootMenu != nil {
+				app.promptForUpdate()
+			}
+		})
 	}()
 
-	// Start power button monitor (reads directly from /dev/input/event0)
-	app.startPowerButtonMonitor()
+	// Submit scrobbles logged while offline (no-op without a token)
+	app.submitScrobblesInBackground(nil)
 
-	// Generate initial icon PNG with current theme
-	if err := app.generateIconPNG(); err != nil {
-		logMsg(fmt.Sprintf("ERROR: Failed to generate icon: %v", err))
+	if DESKTOP_BUILD {
+		// Power and volume keys come through SDL (see desktop.go)
+		app.DeviceModel = "desktop"
+	} else {
+		// Start power button monitor (reads directly from /dev/input/event0)
+		app.startPowerButtonMonitor()
+
+		// Generate initial icon PNG with current theme
+		if err := app.generateIconPNG(); err != nil {
+			logMsg(fmt.Sprintf("ERROR: Failed to generate icon: %v", err))
+		}
 	}
 
 	logMsg("INFO: MiyooPod init OK!")
 }
 
-func (app *MiyooPod) loadFonts() {
-	fontPath := "./assets/ui_font.ttf"
+// FONT_PATH is the UI font, inside ASSETS_DIR
+var FONT_PATH = "./assets/ui_font.ttf"
 
+func (app *MiyooPod) loadFonts() {
 	var err error
-	app.FontHeader, err = gg.LoadFontFace(fontPath, FONT_SIZE_HEADER)
+	app.FontHeader, err = gg.LoadFontFace(FONT_PATH, FONT_SIZE_HEADER)
 	if err != nil {
 		panic(fmt.Sprintf("Failed to load font: %v", err))
 	}
 
-	app.FontMenu, _ = gg.LoadFontFace(fontPath, FONT_SIZE_MENU)
-	app.FontTitle, _ = gg.LoadFontFace(fontPath, FONT_SIZE_TITLE)
-	app.FontArtist, _ = gg.LoadFontFace(fontPath, FONT_SIZE_ARTIST)
-	app.FontAlbum, _ = gg.LoadFontFace(fontPath, FONT_SIZE_ALBUM)
-	app.FontTime, _ = gg.LoadFontFace(fontPath, FONT_SIZE_TIME)
-	app.FontSmall, _ = gg.LoadFontFace(fontPath, FONT_SIZE_SMALL)
+	app.FontMenu, _ = gg.LoadFontFace(FONT_PATH, FONT_SIZE_MENU)
+	app.FontTitle, _ = gg.LoadFontFace(FONT_PATH, FONT_SIZE_TITLE)
+	app.FontArtist, _ = gg.LoadFontFace(FONT_PATH, FONT_SIZE_ARTIST)
+	app.FontAlbum, _ = gg.LoadFontFace(FONT_PATH, FONT_SIZE_ALBUM)
+	app.FontTime, _ = gg.LoadFontFace(FONT_PATH, FONT_SIZE_TIME)
+	app.FontSmall, _ = gg.LoadFontFace(FONT_PATH, FONT_SIZE_SMALL)
 }
 
 func (app *MiyooPod) RunUI() {
 	for range app.RefreshChan {
-		if !app.Running {
+		if !app.Running.Load() {
 			break
 		}
-		pixels := (*C.uchar)(unsafe.Pointer(&app.FB.Pix[0]))
-		C.refreshScreenPtr(pixels)
+		app.frameMu.Lock()
+		presentFrame(app.frame)
+		app.frameMu.Unlock()
 	}
 }
 
-// triggerRefresh signals the UI goroutine to present the framebuffer.
-// Non-blocking: if a refresh is already pending, this is a no-op.
+// triggerRefresh hands the framebuffer to the UI goroutine for presenting.
+// The frame is copied, so drawing can continue while it's on its way to the
+// screen. Non-blocking: if a refresh is already pending, it shows this frame.
 func (app *MiyooPod) triggerRefresh() {
+	app.frameMu.Lock()
+	if app.frame == nil {
+		app.frame = image.NewRGBA(app.FB.Rect)
+	}
+	copy(app.frame.Pix, app.FB.Pix)
+	app.frameMu.Unlock()
+
 	select {
 	case app.RefreshChan <- struct{}{}:
 	default:
 	}
 }
 
+// POSTED_QUEUE_SIZE bounds the work background goroutines can queue before
+// post blocks waiting for the main loop
+const POSTED_QUEUE_SIZE = 256
+
+// post queues fn to run on the main loop. Background goroutines use it to
+// apply their results, since only the main goroutine may touch app state.
+// Must not be called from the main goroutine (use the state directly there).
+func (app *MiyooPod) post(fn func()) {
+	app.Posted <- fn
+}
+
+// runPosted runs the work queued by post. Called by every loop on the main
+// goroutine that waits for input.
+func (app *MiyooPod) runPosted() {
+	for {
+		select {
+		case fn := <-app.Posted:
+			fn()
+		default:
+			return
+		}
+	}
+}
+
 // requestRedraw signals the main loop to call drawCurrentScreen on the next iteration.
 // Safe to call from any goroutine. Non-blocking.
 func (app *MiyooPod) requestRedraw() {
@@ -165,10 +206,11 @@ func (app *MiyooPod) requestRedraw() {
 }
 
 func createApp() *MiyooPod {
-	return &MiyooPod{
-		Running: true,
-		FB:      image.NewRGBA(image.Rect(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT)),
+	app := &MiyooPod{
+		FB: image.NewRGBA(image.Rect(0, 0, SCREEN_WIDTH, SCREEN_HEIGHT)),
 	}
+	app.Running.Store(true)
+	return app
 }
 
 func main() {
@@ -197,6 +239,9 @@ func main() {
 	// Install signal handlers for C-level crashes (SIGSEGV, SIGABRT, SIGBUS)
 	installCrashHandler()
 
+	// Music, data and asset folders from flags or MIYOOPOD_* env vars
+	parsePathFlags()
+
 	logMsg("\n\n\n-----------")
 	logMsg("INFO: MiyooPod started!")
 
@@ -227,6 +272,7 @@ func main() {
 				app.drawCurrentScreen()
 			default:
 			}
+			app.runPosted()
 			// Poll SDL events so the scan screen renders
 			keyEvent := C_GetKeyPress()
 			if keyEvent != -1 {
@@ -242,6 +288,10 @@ func main() {
 	scanFinished:
 	}
 
+	// Listening history for play counts and smart playlists
+	app.loadPlayStats()
+	app.loadAudiobookProgress()
+
 	// Restore saved playback state (queue, position) before building menu
 	app.restorePlaybackState()
 
@@ -252,8 +302,8 @@ func main() {
 	// Start playback poller
 	go app.startPlaybackPoller()
 
-	// Extract album art from MP3s for albums without cached art (background)
-	go app.deferredArtExtraction()
+	// Extract embedded album art for albums without cached art (background)
+	app.deferredArtExtraction()
 
 	// Start inactivity monitor for auto-lock
 	go app.startInactivityMonitor()
@@ -267,18 +317,13 @@ func main() {
 	// Check for update status from a previous OTA update
 	app.handleUpdateStatus()
 
-	// Wait for async version check to complete, then show update prompt if needed
-	go func() {
-		<-app.VersionCheckDone
-		if app.UpdateAvailable && app.UpdateNotifications && app.Running {
-			app.showUpdatePrompt()
-		}
-	}()
+	// Show the update prompt if the version check finished during startup
+	app.promptForUpdate()
 
 	// Main loop: poll SDL events on main thread (required by SDL2)
 	// SDL_PollEvent MUST run on the thread that called SDL_Init (LockOSThread in init)
 	// Sleep between polls to keep CPU usage low (replaces old runtime.Gosched spin loop)
-	for app.Running {
+	for app.Running.Load() {
 		keyEvent := C_GetKeyPress()
 		if keyEvent != -1 {
 			// Log the keycode for debugging
@@ -292,6 +337,8 @@ func main() {
 				app.handleKey(Key(keyEvent))
 			}
 		}
+		app.runPosted()
+		app.updateLogContext()
 		app.pollSeek()
 		app.pollMarquee()
 		// Check if a background goroutine requested a redraw (non-blocking)
@@ -305,6 +352,7 @@ func main() {
 
 	// Save playback state before exit
 	app.savePlaybackState()
+	app.recordAudiobookProgress(false)
 
 	// Track app closed
 	TrackAppLifecycle("app_closed", nil)
@@ -312,6 +360,7 @@ func main() {
 	// Cleanup: close refresh channel to unblock RunUI goroutine
 	close(app.RefreshChan)
 
+	app.Audio.Quit()
 	sdlCleanup()
 }
 
@@ -334,10 +383,16 @@ func (app *MiyooPod) handleKeyRelease(key Key) {
 	if (key == L || key == R) && app.SeekHeld {
 		direction := app.seekKeyReleased()
 		if direction != 0 {
-			// Was a short tap — do prev/next track
-			if direction < 0 {
+			// Was a short tap — do prev/next chapter, or track if it has none
+			hasChapters := app.playingChapter() >= 0
+			switch {
+			case direction < 0 && hasChapters:
+				app.prevChapter()
+			case direction < 0:
 				app.prevTrack()
-			} else {
+			case hasChapters:
+				app.nextChapter()
+			default:
 				app.nextTrack()
 			}
 			app.drawCurrentScreen()
@@ -392,6 +447,12 @@ func (app *MiyooPod) handleKey(key Key) {
 		return
 	}
 
+	// The on-screen keyboard uses every button, so skip the global keys
+	if app.CurrentScreen == ScreenTextEntry {
+		app.handleTextEntryKey(key)
+		return
+	}
+
 	// Global keys (work from any screen)
 	switch key {
 	case START:
@@ -460,6 +521,10 @@ func (app *MiyooPod) handleKey(key Key) {
 		app.handleAlbumArtKey(key)
 	case ScreenLibraryScan:
 		app.handleLibraryScanKey(key)
+	case ScreenPlaylistEdit:
+		app.handlePlaylistEditKey(key)
+	case ScreenEqualizer:
+		app.handleEqualizerKey(key)
 	}
 }
 
@@ -482,6 +547,18 @@ func (app *MiyooPod) handleNowPlayingKey(key Key) {
 		// Cycle repeat mode
 		app.cycleRepeat()
 		app.drawCurrentScreen()
+	case UP:
+		// Switch between cover and lyrics
+		app.toggleLyrics()
+		app.drawCurrentScreen()
+	case DOWN:
+		// Cycle sleep timer
+		app.cycleSleepTimer()
+		app.drawCurrentScreen()
+	case L2:
+		app.skipSeconds(-AUDIOBOOK_SKIP_SECONDS)
+	case R2:
+		app.skipSeconds(AUDIOBOOK_SKIP_SECONDS)
 	}
 }
 
@@ -502,6 +579,15 @@ func (app *MiyooPod) drawCurrentScreen() {
 	case ScreenLibraryScan:
 		app.drawLibraryScanScreen()
 		app.drawLibraryScanStatusBar()
+	case ScreenPlaylistEdit:
+		app.drawPlaylistEditScreen()
+		app.drawStatusBar()
+	case ScreenTextEntry:
+		app.drawTextEntryScreen()
+		app.drawStatusBar()
+	case ScreenEqualizer:
+		app.drawEqualizerScreen()
+		app.drawStatusBar()
 	}
 
 	// Draw lock overlay if locked
@@ -526,130 +612,3 @@ func (app *MiyooPod) drawCurrentScreen() {
 
 	app.triggerRefresh()
 }
-
-func C_GetKeyPress() int {
-	return int(C.pollEvents())
-}
-
-// Audio C wrappers
-
-func audioLoadFile(path string) error {
-	cpath := C.CString(path)
-	defer C.free(unsafe.Pointer(cpath))
-	if C.audio_load(cpath) != 0 {
-		return fmt.Errorf("failed to load audio: %s", path)
-	}
-	return nil
-}
-
-// audioLoadFileToMemory loads entire audio file into RAM to avoid SD card I/O during playback
-func audioLoadFileToMemory(path string) error {
-	// Read entire file into Go memory
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return fmt.Errorf("failed to read audio file: %v", err)
-	}
-
-	// Allocate C memory and copy data
-	cdata := C.malloc(C.size_t(len(data)))
-	if cdata == nil {
-		return fmt.Errorf("failed to allocate memory for audio")
-	}
-
-	// Copy Go bytes to C memory
-	C.memcpy(cdata, unsafe.Pointer(&data[0]), C.size_t(len(data)))
-
-	// audio_load_mem takes ownership of cdata, will free it on next load or quit
-	if C.audio_load_mem(cdata, C.int(len(data))) != 0 {
-		// audio_load_mem already freed cdata on error
-		return fmt.Errorf("failed to load audio from memory")
-	}
-
-	return nil
-}
-
-func audioPlay() error {
-	if C.audio_play() != 0 {
-		return fmt.Errorf("failed to play audio")
-	}
-	return nil
-}
-
-func audioStop() {
-	C.audio_stop()
-}
-
-func sdlCleanup() {
-	C.audio_quit()
-	C.quit()
-}
-
-func audioTogglePause() {
-	C.audio_toggle_pause()
-}
-
-func audioPause() {
-	C.audio_pause()
-}
-
-func audioResume() {
-	C.audio_resume()
-}
-
-func audioIsPlaying() bool {
-	return C.audio_is_playing() != 0
-}
-
-func audioIsPaused() bool {
-	return C.audio_is_paused() != 0
-}
-
-func audioGetPosition() float64 {
-	return float64(C.audio_get_position())
-}
-
-func audioGetDuration() float64 {
-	return float64(C.audio_get_duration())
-}
-
-func audioGetDurationForFile(path string) float64 {
-	cpath := C.CString(path)
-	defer C.free(unsafe.Pointer(cpath))
-	return float64(C.audio_get_file_duration(cpath))
-}
-
-func audioSeek(position float64) {
-	C.audio_seek(C.double(position))
-}
-
-func audioSetVolume(volume int) {
-	C.audio_set_volume(C.int(volume * 128 / 100))
-}
-
-func audioCheckFinished() bool {
-	return C.audio_check_finished() != 0
-}
-
-type AudioStateSnapshot struct {
-	Position  float64
-	Duration  float64
-	IsPlaying bool
-	IsPaused  bool
-	Finished  bool
-}
-
-func audioFlushBuffers() {
-	C.audio_flush_buffers()
-}
-
-func audioGetState() AudioStateSnapshot {
-	var state C.AudioState
-	C.audio_get_state(&state)
-	return AudioStateSnapshot{
-		Position:  float64(state.position),
-		Duration:  float64(state.duration),
-		IsPlaying: state.is_playing != 0,
-		IsPaused:  state.is_paused != 0,
-		Finished:  state.finished != 0,
-	}
-}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fogleman/gg"
+)
+
+// Run with: go test -tags headless ./src/
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "miyoopod-test")
+	if err != nil {
+		panic(err)
+	}
+
+	// Keep every file the app writes off the SD card paths
+	FONT_PATH = "../App/MiyooPod/assets/ui_font.ttf"
+	ASSETS_DIR = filepath.Join(dir, "assets")
+	DATA_DIR = dir + string(filepath.Separator)
+	SETTINGS_PATH = filepath.Join(dir, "settings.json")
+	LIBRARY_JSON_PATH = filepath.Join(dir, "library.json")
+	ARTWORK_DIR = filepath.Join(dir, "artwork") + string(filepath.Separator)
+	SMART_PLAYLISTS_PATH = filepath.Join(dir, "smart_playlists.json")
+	PLAYLIST_DIR = filepath.Join(dir, "Playlists") + string(filepath.Separator)
+	PLAYBACK_STATE_PATH = filepath.Join(dir, "playback.json")
+	PLAY_STATS_PATH = filepath.Join(dir, "stats.json")
+	AUDIOBOOK_PROGRESS_PATH = filepath.Join(dir, "audiobooks.json")
+	SCROBBLER_LOG_PATH = filepath.Join(dir, "scrobbler.log")
+	LISTENBRAINZ_EXPORT_PATH = filepath.Join(dir, "listenbrainz_listens.json")
+	UPDATE_INFO_PATH = filepath.Join(dir, "update.json")
+	UPDATE_STATUS_PATH = filepath.Join(dir, "update_status")
+
+	// Fixed battery reading so screenshots don't depend on the host
+	battery := filepath.Join(dir, "battery")
+	os.WriteFile(battery, []byte("80\n"), 0644)
+	batteryPaths = []string{battery}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestApp returns an app with a library of n tracks (03:00 each) and the
+// simulated audio backend, showing the queue screen
+func newTestApp(t *testing.T, n int) (*MiyooPod, *simAudio) {
+	t.Helper()
+
+	sim := newSimAudio()
+	app := &MiyooPod{
+		Audio:            sim,
+		CurrentScreen:    ScreenQueue,
+		CurrentTheme:     ThemeClassic,
+		Playing:          &NowPlaying{State: StateStopped, Volume: 100},
+		Queue:            &PlaybackQueue{Repeat: RepeatOff},
+		Coverflow:        &CoverflowState{CoverCache: make(map[string]image.Image)},
+		TextMeasureCache: make(map[string]float64),
+		RefreshChan:      make(chan struct{}, 1),
+		RedrawChan:       make(chan struct{}, 1),
+		Posted:           make(chan func(), POSTED_QUEUE_SIZE),
+		PlayStats:        make(map[string]*TrackStats),
+		Library: &Library{
+			TracksByPath:  make(map[string]*Track),
+			AlbumsByKey:   make(map[string]*Album),
+			ArtistsByName: make(map[string]*Artist),
+		},
+	}
+	app.DC = gg.NewContext(SCREEN_WIDTH, SCREEN_HEIGHT)
+	app.FB = app.DC.Image().(*image.RGBA)
+	app.loadFonts()
+	app.initDigitSprites(app.FontTime)
+	app.LockKey = Y
+	app.Running.Store(true)
+
+	for i := 1; i <= n; i++ {
+		app.Library.addTrack(&Track{
+			Path:     trackPath(i),
+			Title:    fmt.Sprintf("Track %d", i),
+			Artist:   "Artist",
+			Album:    "Album",
+			TrackNum: i,
+			Duration: SIM_DEFAULT_DURATION,
+		}, nil)
+	}
+
+	return app, sim
+}
+
+// playQueue queues the whole library and starts playing at idx
+func playQueue(t *testing.T, app *MiyooPod, idx int) {
+	t.Helper()
+	app.playTrackFromList(app.Library.Tracks, idx)
+	app.setScreen(ScreenQueue)
+	if app.Playing.State != StatePlaying {
+		t.Fatalf("playback did not start")
+	}
+}
+
+// waitForPreload waits for the background gapless preload to reach the backend
+func waitForPreload(t *testing.T, sim *simAudio, path string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for sim.Preloaded() != path {
+		if time.Now().After(deadline) {
+			t.Fatalf("preloaded %q, want %q", sim.Preloaded(), path)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// trackPath returns the path of library track n (1-based)
+func trackPath(n int) string {
+	return fmt.Sprintf("/music/Artist/Album/%02d.mp3", n)
+}
+
+func currentPath(app *MiyooPod) string {
+	if t := app.getCurrentTrack(); t != nil {
+		return t.Path
+	}
+	return ""
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"sort"
+	"strings"
 
 	"github.com/fogleman/gg"
 )
@@ -18,14 +19,15 @@ func (app *MiyooPod) buildRootMenu() *MenuScreen {
 	// Now Playing (only shown when something is playing)
 	// This will be dynamically added/removed in refreshRootMenu
 
-	// Playlists
-	if len(app.Library.Playlists) > 0 {
+	// Playlists (shown whenever there's something to put in one)
+	if len(app.Library.Playlists) > 0 || len(app.Library.Tracks) > 0 {
 		playlistMenu := &MenuScreen{
 			Title:  "Playlists",
 			Parent: root,
 			Builder: func() []*MenuItem {
 				return app.buildPlaylistMenuItems(root)
 			},
+			Rebuild: true, // Smart playlists depend on play counts
 		}
 		items = append(items, &MenuItem{
 			Label:      "Playlists",
@@ -81,6 +83,95 @@ func (app *MiyooPod) buildRootMenu() *MenuScreen {
 			Submenu:    songMenu,
 		})
 
+		// Compilations -> Tracks
+		if len(app.compilationAlbums()) > 0 {
+			items = append(items, &MenuItem{
+				Label:      "Compilations",
+				HasSubmenu: true,
+				Submenu: &MenuScreen{
+					Title:  "Compilations",
+					Parent: root,
+					Builder: func() []*MenuItem {
+						return app.buildCompilationMenuItems(root)
+					},
+				},
+			})
+		}
+
+		// Genres -> Artists -> Albums -> Tracks
+		if app.hasTrackField(trackGenre) {
+			items = append(items, &MenuItem{
+				Label:      "Genres",
+				HasSubmenu: true,
+				Submenu: &MenuScreen{
+					Title:  "Genres",
+					Parent: root,
+					Builder: func() []*MenuItem {
+						return app.buildGenreMenuItems(root)
+					},
+				},
+			})
+		}
+
+		// Composers -> Tracks
+		if app.hasTrackField(trackComposer) {
+			items = append(items, &MenuItem{
+				Label:      "Composers",
+				HasSubmenu: true,
+				Submenu: &MenuScreen{
+					Title:  "Composers",
+					Parent: root,
+					Builder: func() []*MenuItem {
+						return app.buildComposerMenuItems(root)
+					},
+				},
+			})
+		}
+
+		// Years (by decade) -> Albums -> Tracks
+		if app.hasYears() {
+			items = append(items, &MenuItem{
+				Label:      "Years",
+				HasSubmenu: true,
+				Submenu: &MenuScreen{
+					Title:  "Years",
+					Parent: root,
+					Builder: func() []*MenuItem {
+						return app.buildDecadeMenuItems(root)
+					},
+				},
+			})
+		}
+
+		// Folders -> Subfolders -> Tracks, straight from the file layout
+		items = append(items, &MenuItem{
+			Label:      "Folders",
+			HasSubmenu: true,
+			Submenu: &MenuScreen{
+				Title:  "Folders",
+				Parent: root,
+				Builder: func() []*MenuItem {
+					return app.buildFolderRootMenuItems(root)
+				},
+			},
+		})
+
+		// Unfinished audiobook and podcast files, most recent first
+		if len(app.AudiobookFolders) > 0 {
+			items = append(items, &MenuItem{
+				Label:      "Continue Listening",
+				HasSubmenu: true,
+				Submenu: &MenuScreen{
+					Title:  "Continue Listening",
+					Parent: root,
+					Builder: func() []*MenuItem {
+						return app.buildContinueListeningMenuItems()
+					},
+					Rebuild: true,
+				},
+			})
+		}
+
 		// Shuffle All
 		items = append(items, &MenuItem{
 			Label: "Shuffle All",
@@ -88,6 +179,44 @@ func (app *MiyooPod) buildRootMenu() *MenuScreen {
 				app.shuffleAllAndPlay()
 			},
 		})
+
+		// Listening history (rebuilt on entry so it reflects recent plays)
+		items = append(items, &MenuItem{
+			Label:      "Most Played",
+			HasSubmenu: true,
+			Submenu: &MenuScreen{
+				Title:  "Most Played",
+				Parent: root,
+				Builder: func() []*MenuItem {
+					return app.buildTrackMenuItems(app.mostPlayedTracks(STATS_MENU_LIMIT))
+				},
+				Rebuild: true,
+			},
+		})
+		items = append(items, &MenuItem{
+			Label:      "Recently Played",
+			HasSubmenu: true,
+			Submenu: &MenuScreen{
+				Title:  "Recently Played",
+				Parent: root,
+				Builder: func() []*MenuItem {
+					return app.buildTrackMenuItems(app.recentlyPlayedTracks(STATS_MENU_LIMIT))
+				},
+				Rebuild: true,
+			},
+		})
+		items = append(items, &MenuItem{
+			Label:      "Top Artists",
+			HasSubmenu: true,
+			Submenu: &MenuScreen{
+				Title:  "Top Artists",
+				Parent: root,
+				Builder: func() []*MenuItem {
+					return app.buildTopArtistMenuItems(root)
+				},
+				Rebuild: true,
+			},
+		})
 	}
 
 	// About
@@ -132,8 +261,8 @@ func (app *MiyooPod) buildRootMenu() *MenuScreen {
 	items = append(items, &MenuItem{
 		Label: "Exit",
 		Action: func() {
-			audioStop()
-			app.Running = false
+			app.Audio.Stop()
+			app.Running.Store(false)
 		},
 	})
 
@@ -143,8 +272,31 @@ func (app *MiyooPod) buildRootMenu() *MenuScreen {
 }
 
 func (app *MiyooPod) buildPlaylistMenuItems(root *MenuScreen) []*MenuItem {
-	items := make([]*MenuItem, 0, len(app.Library.Playlists))
-	for _, pl := range app.Library.Playlists {
+	items := make([]*MenuItem, 0, len(app.Library.Playlists)+2)
+
+	items = append(items, &MenuItem{
+		Label: "New Playlist",
+		Action: func() {
+			app.promptText("New Playlist", "", func(name string) {
+				if _, err := app.createPlaylist(name, nil); err != nil {
+					logMsg(fmt.Sprintf("ERROR: Failed to create playlist: %v", err))
+					app.showError("Failed to create playlist")
+					return
+				}
+				app.refreshPlaylistMenus()
+			})
+		},
+	})
+	items = append(items, &MenuItem{
+		Label: "Save Queue as Playlist",
+		Action: func() {
+			app.saveQueueAsPlaylist()
+		},
+	})
+
+	// Smart playlists are recomputed from the library on every build
+	playlists := append(app.buildSmartPlaylists(), app.Library.Playlists...)
+	for _, pl := range playlists {
 		playlist := pl // capture
 		trackMenu := &MenuScreen{
 			Title:  pl.Name,
@@ -157,14 +309,52 @@ func (app *MiyooPod) buildPlaylistMenuItems(root *MenuScreen) []*MenuItem {
 			Label:      pl.Name,
 			HasSubmenu: true,
 			Submenu:    trackMenu,
+			Playlist:   playlist, // Store playlist reference for editing
+		})
+	}
+	return items
+}
+
+// STATS_MENU_LIMIT caps the Most Played, Recently Played and Top Artists lists
+const STATS_MENU_LIMIT = 100
+
+func (app *MiyooPod) buildTopArtistMenuItems(root *MenuScreen) []*MenuItem {
+	artists := app.topArtists(STATS_MENU_LIMIT)
+	items := make([]*MenuItem, 0, len(artists))
+	for _, artist := range artists {
+		a := artist // capture
+		trackMenu := &MenuScreen{
+			Title:  a.Name,
+			Parent: root,
+			Builder: func() []*MenuItem {
+				return app.buildTrackMenuItems(a.Tracks)
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      fmt.Sprintf("%s (%d plays)", a.Name, a.Plays),
+			HasSubmenu: true,
+			Submenu:    trackMenu,
+			Tracks:     a.Tracks, // For Y-key queuing
 		})
 	}
 	return items
 }
 
 func (app *MiyooPod) buildArtistMenuItems(root *MenuScreen) []*MenuItem {
+	// Tracks credited to artists other than the one they're filed under
+	guests := app.artistAppearances()
+	appearances := make(map[string]*trackGroup)
+	for _, g := range guests {
+		appearances[strings.ToLower(g.Name)] = g
+	}
+
 	items := make([]*MenuItem, 0, len(app.Library.Artists))
 	for _, artist := range app.Library.Artists {
+		guest := appearances[strings.ToLower(artist.Name)] // capture
+		delete(appearances, strings.ToLower(artist.Name))
+		if app.HideCompilationArtists && strings.EqualFold(artist.Name, VARIOUS_ARTISTS) {
+			continue
+		}
 		a := artist // capture
 		albumMenu := &MenuScreen{
 			Title:  a.Name,
@@ -187,6 +377,10 @@ func (app *MiyooPod) buildArtistMenuItems(root *MenuScreen) []*MenuItem {
 						Album:      alb, // Store album reference for preview
 					})
 				}
+				// Then albums they only feature on, opening just those tracks
+				if guest != nil {
+					albumItems = append(albumItems, app.buildTrackAlbumMenuItems(root, guest.Tracks)...)
+				}
 				return albumItems
 			},
 		}
@@ -197,6 +391,33 @@ func (app *MiyooPod) buildArtistMenuItems(root *MenuScreen) []*MenuItem {
 			Artist:     a, // Store artist reference for Y-key action
 		})
 	}
+
+	// Artists without albums of their own (on compilations, or featured) open
+	// the tracks they appear on
+	if len(appearances) > 0 {
+		for _, artist := range guests {
+			a := artist // capture
+			if appearances[strings.ToLower(a.Name)] == nil {
+				continue // Has albums, listed above
+			}
+			if app.HideCompilationArtists && onlyCompilations(a.Tracks) {
+				continue
+			}
+			items = append(items, &MenuItem{
+				Label:      a.Name,
+				HasSubmenu: true,
+				Submenu: &MenuScreen{
+					Title:  a.Name,
+					Parent: root,
+					Builder: func() []*MenuItem {
+						return app.buildTrackAlbumMenuItems(root, a.Tracks)
+					},
+				},
+				Tracks: a.Tracks, // For Y-key queuing
+			})
+		}
+		sortArtistItems(items)
+	}
 	return items
 }
 
@@ -224,9 +445,7 @@ func (app *MiyooPod) buildAlbumMenuItems(root *MenuScreen) []*MenuItem {
 func (app *MiyooPod) buildSongMenuItems() []*MenuItem {
 	tracks := make([]*Track, len(app.Library.Tracks))
 	copy(tracks, app.Library.Tracks)
-	sort.Slice(tracks, func(i, j int) bool {
-		return tracks[i].Title < tracks[j].Title
-	})
+	sortByKey(tracks, trackSortKey)
 
 	return app.buildTrackMenuItems(tracks)
 }
@@ -265,9 +484,33 @@ func (app *MiyooPod) buildSettingsMenuItems(root *MenuScreen) []*MenuItem {
 		Submenu:    themesMenu,
 	})
 
+	// Compilation-only artists in Artists
+	compArtistStatus := "Shown"
+	if app.HideCompilationArtists {
+		compArtistStatus = "Hidden"
+	}
+	items = append(items, &MenuItem{
+		Label: "Compilation Artists: " + compArtistStatus,
+		Action: func() {
+			app.toggleCompilationArtists()
+		},
+	})
+
+	// Leading articles when sorting
+	articleStatus := "Off"
+	if app.IgnoreArticles {
+		articleStatus = "On"
+	}
+	items = append(items, &MenuItem{
+		Label: "Ignore Articles: " + articleStatus,
+		Action: func() {
+			app.toggleIgnoreArticles()
+		},
+	})
+
 	// Local Logs option
 	localLogStatus := "Off"
-	if app.LocalLogsEnabled {
+	if app.LocalLogsEnabled.Load() {
 		localLogStatus = "On"
 	}
 	items = append(items, &MenuItem{
@@ -301,6 +544,21 @@ func (app *MiyooPod) buildSettingsMenuItems(root *MenuScreen) []*MenuItem {
 		},
 	})
 
+	// Sleep timer and what happens when it runs out
+	items = append(items, &MenuItem{
+		Label: "Sleep Timer: " + app.sleepTimerLabel(),
+		Action: func() {
+			app.cycleSleepTimer()
+			app.refreshSettingsMenu()
+		},
+	})
+	items = append(items, &MenuItem{
+		Label: "When Sleep Timer Ends: " + app.sleepActionLabel(),
+		Action: func() {
+			app.cycleSleepAction()
+		},
+	})
+
 	// Screen Peek option
 	peekStatus := "Off"
 	if app.ScreenPeekEnabled {
@@ -313,6 +571,69 @@ func (app *MiyooPod) buildSettingsMenuItems(root *MenuScreen) []*MenuItem {
 		},
 	})
 
+	// Playback speed of files in the audiobook folders
+	items = append(items, &MenuItem{
+		Label: "Audiobook Speed: " + formatSpeed(app.AudiobookSpeed),
+		Action: func() {
+			app.cycleAudiobookSpeed()
+		},
+	})
+
+	// Equalizer preset and curves
+	items = append(items, &MenuItem{
+		Label: app.equalizerLabel(),
+		Action: func() {
+			app.openEqualizer()
+		},
+	})
+
+	// ReplayGain mode
+	items = append(items, &MenuItem{
+		Label: app.replayGainLabel(),
+		Action: func() {
+			app.cycleReplayGainMode()
+		},
+	})
+
+	// ReplayGain preamp
+	items = append(items, &MenuItem{
+		Label: fmt.Sprintf("Preamp: %+.0f dB", app.ReplayGainPreamp),
+		Action: func() {
+			app.cycleReplayGainPreamp()
+		},
+	})
+
+	// Scrobbling
+	items = append(items, &MenuItem{
+		Label: "Export Listens (ListenBrainz)",
+		Action: func() {
+			app.manualExportScrobbles()
+		},
+	})
+	if app.ListenBrainzToken != "" {
+		items = append(items, &MenuItem{
+			Label: "Submit Listens Now",
+			Action: func() {
+				app.manualSubmitScrobbles()
+			},
+		})
+	}
+
+	// Music and data folders
+	storageMenu := &MenuScreen{
+		Title:   "Storage",
+		Parent:  root,
+		Rebuild: true,
+	}
+	storageMenu.Builder = func() []*MenuItem {
+		return app.buildStorageMenuItems(storageMenu)
+	}
+	items = append(items, &MenuItem{
+		Label:      "Storage",
+		HasSubmenu: true,
+		Submenu:    storageMenu,
+	})
+
 	// Check for Updates
 	items = append(items, &MenuItem{
 		Label: "Check for Updates",
@@ -425,9 +746,13 @@ func (app *MiyooPod) handleMenuKey(key Key) {
 			app.cancelSearch()
 		}
 		if item.Submenu != nil {
-			if !item.Submenu.Built && item.Submenu.Builder != nil {
+			if (!item.Submenu.Built || item.Submenu.Rebuild) && item.Submenu.Builder != nil {
 				item.Submenu.Items = item.Submenu.Builder()
 				item.Submenu.Built = true
+				if item.Submenu.SelIndex >= len(item.Submenu.Items) {
+					item.Submenu.SelIndex = 0
+					item.Submenu.ScrollOff = 0
+				}
 			}
 			item.Submenu.Parent = current
 			app.MenuStack = append(app.MenuStack, item.Submenu)
@@ -450,6 +775,9 @@ func (app *MiyooPod) handleMenuKey(key Key) {
 			app.cancelSearch()
 		} else if len(app.MenuStack) > 1 {
 			app.MenuStack = app.MenuStack[:len(app.MenuStack)-1]
+			if current.OnBack != nil {
+				current.OnBack()
+			}
 			// Track navigation back
 			if len(app.MenuStack) > 0 {
 				current := app.MenuStack[len(app.MenuStack)-1]
@@ -469,29 +797,31 @@ func (app *MiyooPod) handleMenuKey(key Key) {
 		} else if len(app.MenuStack) > 1 {
 			app.MenuStack = app.MenuStack[:1]
 		} else {
-			app.Running = false
+			app.Running.Store(false)
 			return
 		}
 	case Y:
-		// Add to queue: track, album, or all artist tracks
+		// Add to queue: track, album, all artist tracks, or playlist
 		if len(current.Items) == 0 {
 			return
 		}
 		item := current.Items[current.SelIndex]
-		if item.Track != nil {
-			app.addToQueue(item.Track)
-		} else if item.Album != nil {
-			// Add all album tracks
-			for _, track := range item.Album.Tracks {
-				app.addToQueue(track)
-			}
-		} else if item.Artist != nil {
-			// Add all tracks from all albums by this artist
-			for _, album := range item.Artist.Albums {
-				for _, track := range album.Tracks {
-					app.addToQueue(track)
-				}
-			}
+		for _, track := range app.menuItemTracks(item) {
+			app.addToQueue(track)
+		}
+	case X:
+		// Edit a playlist, or add the selected item's tracks to one
+		if len(current.Items) == 0 {
+			return
+		}
+		item := current.Items[current.SelIndex]
+		if item.Playlist != nil && !item.Playlist.Smart {
+			app.openPlaylistEditor(item.Playlist)
+			return
+		}
+		if tracks := app.menuItemTracks(item); len(tracks) > 0 {
+			app.openPlaylistChooser(tracks, ScreenMenu)
+			return
 		}
 	}
 
@@ -640,6 +970,9 @@ func (app *MiyooPod) drawStandardMenu(current *MenuScreen) {
 		isInQueue := item.Track != nil && app.isTrackInQueue(item.Track)
 
 		app.drawMenuItem(y, item.Label, selected, item.HasSubmenu, isPlaying, isInQueue)
+		if item.Track != nil {
+			app.drawAudiobookProgress(y, item.Track, selected)
+		}
 	}
 
 	// Scroll bar
@@ -849,11 +1182,11 @@ func (app *MiyooPod) refreshRootMenu() {
 	}
 }
 
-// rescanLibrary performs a full library scan and rebuilds the menu
+// rescanLibrary rescans the library (re-tagging only new or changed files) and rebuilds the menu
 func (app *MiyooPod) rescanLibrary() {
 	// Stop any current playback
 	if app.Playing.State == StatePlaying {
-		audioStop()
+		app.mpvStop()
 		app.Playing.State = StateStopped
 	}
 
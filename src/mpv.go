@@ -1,85 +1,139 @@
 package main
 
 import (
+	"fmt"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // startPlaybackPoller checks audio state and updates progress display.
-// Runs in its own goroutine. Minimal work per tick to avoid starving audio.
+// Runs in its own goroutine as a clock: each tick is posted to the main loop,
+// which owns the playback state. Minimal work per tick to avoid starving audio.
 func (app *MiyooPod) startPlaybackPoller() {
-	lastDrawnSecond := -1
-	tickCount := 0
-	saveTickCount := 0
-
-	for app.Running {
-		if app.Playing != nil && app.Playing.State != StateStopped {
-			state := audioGetState()
-
-			if state.Position >= 0 {
-				app.Playing.Position = state.Position
-			}
-			if state.Duration > 0 && app.Playing.Track != nil && app.Playing.Track.Duration == 0 {
-				app.Playing.Track.Duration = state.Duration
-			}
-
-			if state.IsPaused && app.Playing.State != StatePaused {
-				app.Playing.State = StatePaused
-				app.NPCacheDirty = true
-				app.requestRedraw()
-			} else if state.IsPlaying && app.Playing.State != StatePlaying {
-				app.Playing.State = StatePlaying
-				app.NPCacheDirty = true
-				app.requestRedraw()
-			}
-
-			if state.Finished {
-				app.handleTrackEnd()
-			}
-
-			// Update progress bar when on Now Playing screen and second changes
-			if app.CurrentScreen == ScreenNowPlaying {
-				currentSecond := int(app.Playing.Position)
-				if currentSecond != lastDrawnSecond {
-					lastDrawnSecond = currentSecond
-					app.updateProgressBarOnly()
-				}
-			}
-
-			// Flush audio buffers every 5 seconds to prevent choppy playback
-			// Mimics the fix that happens when user manually pauses/resumes
-			tickCount++
-			if tickCount >= 5 {
-				audioFlushBuffers()
-				tickCount = 0
-			}
-
-			// Save playback state every 3 seconds during active playback
-			saveTickCount++
-			if saveTickCount >= 3 {
-				app.savePlaybackState()
-				saveTickCount = 0
-			}
-		}
+	poller := &playbackPoller{lastDrawnSecond: -1}
+
+	for app.Running.Load() {
+		app.post(func() { app.pollPlayback(poller) })
 		// Increased sleep to reduce CPU usage and SD card contention
 		time.Sleep(1000 * time.Millisecond)
 	}
 }
 
-func (app *MiyooPod) mpvLoadFile(path string) error {
+// playbackPoller holds the poller's counters between ticks
+type playbackPoller struct {
+	lastDrawnSecond int
+	tickCount       int
+	saveTickCount   int
+}
+
+// pollPlayback is one poller tick, run on the main loop
+func (app *MiyooPod) pollPlayback(p *playbackPoller) {
+	if app.Playing == nil || app.Playing.State == StateStopped {
+		return
+	}
+	app.syncAudioState()
+
+	// Update progress bar when on Now Playing screen and second changes
+	if app.CurrentScreen == ScreenNowPlaying {
+		currentSecond := int(app.Playing.Position)
+		if currentSecond != p.lastDrawnSecond {
+			p.lastDrawnSecond = currentSecond
+			app.updateProgressBarOnly()
+		}
+		app.updateLyricsOnly()
+	}
+
+	app.checkChapterChange()
+	app.checkSleepTimer()
+
+	// Flush audio buffers every 5 seconds to prevent choppy playback
+	// Mimics the fix that happens when user manually pauses/resumes
+	p.tickCount++
+	if p.tickCount >= 5 {
+		app.Audio.FlushBuffers()
+		p.tickCount = 0
+	}
+
+	// Save playback state every 3 seconds during active playback
+	p.saveTickCount++
+	if p.saveTickCount >= 3 {
+		app.savePlaybackState()
+		app.recordAudiobookProgress(false)
+		p.saveTickCount = 0
+	}
+}
+
+// handleAudioEvent is run on the main loop when the audio backend reports a
+// track end (see AudioBackend.Notify)
+func (app *MiyooPod) handleAudioEvent() {
+	if app.Playing == nil || app.Playing.State == StateStopped {
+		return
+	}
+	app.syncAudioState()
+}
+
+// syncAudioState copies position and pause state from the audio backend and
+// handles its end-of-track events. Called once per poller tick.
+func (app *MiyooPod) syncAudioState() {
+	state := app.audioState()
+
+	if state.Position >= 0 {
+		app.Playing.Position = state.Position
+	}
+	if state.Duration > 0 && app.Playing.Track != nil && app.Playing.Track.Duration == 0 {
+		app.Playing.Track.Duration = state.Duration
+	}
+
+	if state.IsPaused && app.Playing.State != StatePaused {
+		app.Playing.State = StatePaused
+		app.NPCacheDirty = true
+		app.requestRedraw()
+	} else if state.IsPlaying && app.Playing.State != StatePlaying {
+		app.Playing.State = StatePlaying
+		app.NPCacheDirty = true
+		app.requestRedraw()
+	}
+
+	if state.Advanced {
+		app.handleGaplessAdvance()
+	}
+	if state.Finished {
+		app.handleTrackEnd()
+	}
+
+	// Count (and scrobble) the play as soon as it qualifies, so it
+	// isn't lost if the device is switched off mid-track
+	if state.IsPlaying {
+		app.recordListen(false)
+	}
+}
+
+func (app *MiyooPod) mpvLoadFile(track *Track) error {
 	// Stream from SD card with larger buffer (128KB) to reduce underruns
-	err := audioLoadFile(path)
+	err := app.Audio.Load(trackFile(track))
 	if err != nil {
 		return err
 	}
-	return audioPlay()
+	// Between load and play, so a sped-up audiobook starts at its speed
+	app.applyPlaybackSpeed(track)
+	if err := app.Audio.Play(); err != nil {
+		return err
+	}
+	if track.Start > 0 {
+		// A cue track: the backend only seeks once the file is playing
+		app.Audio.Seek(track.Start)
+	}
+	return nil
 }
 
 func (app *MiyooPod) mpvTogglePause() {
-	audioTogglePause()
+	app.Audio.TogglePause()
 }
 
 func (app *MiyooPod) mpvStop() {
-	audioStop()
+	app.Audio.Stop()
+	app.forgetPreload()
 }
 
 func (app *MiyooPod) mpvSeek(seconds float64) {
@@ -93,5 +147,75 @@ func (app *MiyooPod) mpvSeek(seconds float64) {
 	if newPos > app.Playing.Duration && app.Playing.Duration > 0 {
 		newPos = app.Playing.Duration
 	}
-	audioSeek(newPos)
+	app.seekAudio(newPos)
+}
+
+// preloadMu serialises preload requests so a slow open can't install a track
+// that has since been superseded. preloadWanted holds the latest requested
+// path; unlike PreloadedPath it's safe to read from the preload goroutines.
+var (
+	preloadMu     sync.Mutex
+	preloadWanted atomic.Value
+)
+
+// forgetPreload drops the current preload request, including one still being
+// opened in the background
+func (app *MiyooPod) forgetPreload() {
+	app.PreloadedPath = ""
+	preloadWanted.Store("")
+}
+
+// preloadNextTrack opens the track that will follow the current one, so the
+// audio layer can switch to it without a gap. Call whenever the queue, shuffle
+// or repeat state changes during playback.
+//
+// Cue tracks (see splitByCueSheets) are special: the track after one that
+// ends mid-file is only gapless when it continues the same file, which plays
+// on without opening anything, and otherwise the backend stops it at its end;
+// and a track starting mid-file can't be preloaded, since the audio layer
+// switches to the start of a file.
+func (app *MiyooPod) preloadNextTrack() {
+	path, file := "", ""
+	gain := 1.0
+	if app.Playing != nil && app.Playing.State != StateStopped {
+		current := app.Playing.Track
+		next := app.peekNextTrack()
+		if app.sleepsAfterTrack() {
+			next = nil // The sleep timer stops at the track's end
+		}
+		app.Audio.SetEnd(trackEnd(current, next))
+		switch {
+		case next == nil:
+			// End of the queue
+		case continuesInFile(current, next):
+			path = next.Path // Nothing to open
+		case current != nil && current.End > 0, next.Start > 0:
+			// Not gapless; the track is loaded when it starts
+		default:
+			path, file = next.Path, trackFile(next)
+			gain = app.replayGainFactor(next)
+		}
+	}
+
+	if path != "" && path == app.PreloadedPath {
+		return
+	}
+	app.PreloadedPath = path
+	preloadWanted.Store(path)
+
+	go func() {
+		preloadMu.Lock()
+		defer preloadMu.Unlock()
+
+		if preloadWanted.Load() != path {
+			return // Superseded by a newer request
+		}
+		if file == "" {
+			app.Audio.ClearPreload()
+			return
+		}
+		if err := app.Audio.Preload(file, gain); err != nil {
+			logMsg(fmt.Sprintf("WARNING: Gapless preload failed: %v", err))
+		}
+	}()
 }
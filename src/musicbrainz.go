@@ -52,49 +52,48 @@ type MusicBrainzReleaseSearch struct {
 }
 
 // fetchAlbumArtFromMusicBrainz attempts to fetch album artwork from MusicBrainz/Cover Art Archive
-func (app *MiyooPod) fetchAlbumArtFromMusicBrainz(album *Album) bool {
-	if album == nil || album.Name == "" {
-		return false
+// and saves it under ARTWORK_DIR, returning the file's path. status (may be nil)
+// receives progress text. It doesn't touch the album, so it can run off the main loop.
+func fetchAlbumArtFromMusicBrainz(artist, name string, status func(string)) (string, bool) {
+	if name == "" {
+		return "", false
+	}
+	if status == nil {
+		status = func(string) {}
 	}
 
-	logMsg(fmt.Sprintf("[MUSICBRAINZ] Fetching art for: %s - %s", album.Artist, album.Name))
+	logMsg(fmt.Sprintf("[MUSICBRAINZ] Fetching art for: %s - %s", artist, name))
 
 	// Step 1: Search for releases
-	releaseIDs, err := searchMusicBrainzRelease(album.Artist, album.Name)
+	releaseIDs, err := searchMusicBrainzRelease(artist, name)
 	if err != nil {
 		logMsg(fmt.Sprintf("WARNING: [MUSICBRAINZ] Search failed: %v", err))
-		return false
+		return "", false
 	}
 
 	if len(releaseIDs) == 0 {
-		logMsg(fmt.Sprintf("[MUSICBRAINZ] No release found for: %s - %s", album.Artist, album.Name))
-		return false
+		logMsg(fmt.Sprintf("[MUSICBRAINZ] No release found for: %s - %s", artist, name))
+		return "", false
 	}
 
 	logMsg(fmt.Sprintf("[MUSICBRAINZ] Found %d release(s), trying each for cover art...", len(releaseIDs)))
 
 	// Update UI status
-	if app.albumArtStatusFunc != nil {
-		app.albumArtStatusFunc(fmt.Sprintf("Searching MusicBrainz...\nFound %d release(s)", len(releaseIDs)))
-	}
+	status(fmt.Sprintf("Searching MusicBrainz...\nFound %d release(s)", len(releaseIDs)))
 
 	// Step 2: Try each release until we find cover art
 	for i, releaseID := range releaseIDs {
 		logMsg(fmt.Sprintf("[MUSICBRAINZ] Trying release %d/%d: %s", i+1, len(releaseIDs), releaseID))
 
 		// Update UI status
-		if app.albumArtStatusFunc != nil {
-			app.albumArtStatusFunc(fmt.Sprintf("Trying release %d/%d...", i+1, len(releaseIDs)))
-		}
+		status(fmt.Sprintf("Trying release %d/%d...", i+1, len(releaseIDs)))
 
 		artData, mimeType, err := fetchCoverArt(releaseID)
 		if err != nil {
 			logMsg(fmt.Sprintf("[MUSICBRAINZ] Release %d failed: %v", i+1, err))
 
 			// Update UI status
-			if app.albumArtStatusFunc != nil {
-				app.albumArtStatusFunc(fmt.Sprintf("Release %d/%d failed\nTrying next...", i+1, len(releaseIDs)))
-			}
+			status(fmt.Sprintf("Release %d/%d failed\nTrying next...", i+1, len(releaseIDs)))
 
 			continue // Try next release
 		}
@@ -103,18 +102,14 @@ func (app *MiyooPod) fetchAlbumArtFromMusicBrainz(album *Album) bool {
 			logMsg(fmt.Sprintf("[MUSICBRAINZ] Release %d has no cover art", i+1))
 
 			// Update UI status
-			if app.albumArtStatusFunc != nil {
-				app.albumArtStatusFunc(fmt.Sprintf("Release %d/%d no art\nTrying next...", i+1, len(releaseIDs)))
-			}
+			status(fmt.Sprintf("Release %d/%d no art\nTrying next...", i+1, len(releaseIDs)))
 
 			continue // Try next release
 		}
 
 		// Found art! Downscale and save
 		// Update UI status
-		if app.albumArtStatusFunc != nil {
-			app.albumArtStatusFunc(fmt.Sprintf("Downloading from release %d/%d...", i+1, len(releaseIDs)))
-		}
+		status(fmt.Sprintf("Downloading from release %d/%d...", i+1, len(releaseIDs)))
 
 		artData, mimeType, err = downscaleImage(artData, mimeType, 200)
 		if err != nil {
@@ -131,34 +126,28 @@ func (app *MiyooPod) fetchAlbumArtFromMusicBrainz(album *Album) bool {
 			ext = "png"
 		}
 
-		album.ArtData = artData
-		album.ArtExt = ext
-
 		// Save artwork to disk to persist across restarts and save RAM
-		if err := app.saveAlbumArtwork(album); err != nil {
+		path, err := writeAlbumArtwork(artist, name, ext, artData)
+		if err != nil {
 			logMsg(fmt.Sprintf("WARNING: [MUSICBRAINZ] Failed to save artwork to disk: %v", err))
-			// Continue anyway, art is still in memory
+			return "", false
 		}
 
 		logMsg(fmt.Sprintf("[MUSICBRAINZ] ✓ Successfully fetched art from release %d: %d bytes, type: %s", i+1, len(artData), mimeType))
 
 		// Update UI status
-		if app.albumArtStatusFunc != nil {
-			app.albumArtStatusFunc(fmt.Sprintf("✓ Success!\nFetched from release %d/%d", i+1, len(releaseIDs)))
-		}
+		status(fmt.Sprintf("✓ Success!\nFetched from release %d/%d", i+1, len(releaseIDs)))
 
-		return true
+		return path, true
 	}
 
 	// No releases had cover art
 	logMsg(fmt.Sprintf("[MUSICBRAINZ] No cover art found in any of the %d releases", len(releaseIDs)))
 
 	// Update UI status
-	if app.albumArtStatusFunc != nil {
-		app.albumArtStatusFunc(fmt.Sprintf("✗ Failed\nNo art found in %d releases", len(releaseIDs)))
-	}
+	status(fmt.Sprintf("✗ Failed\nNo art found in %d releases", len(releaseIDs)))
 
-	return false
+	return "", false
 }
 
 // downscaleImage downscales an image if it's larger than maxSize
@@ -337,32 +326,41 @@ func generateAlbumCacheKey(artist, album string) string {
 }
 
 // saveAlbumArtwork saves album artwork to disk and clears ArtData from memory
-func (app *MiyooPod) saveAlbumArtwork(album *Album) error {
+func saveAlbumArtwork(album *Album) error {
 	if album == nil || album.ArtData == nil || len(album.ArtData) == 0 {
 		return fmt.Errorf("no artwork data to save")
 	}
 
+	path, err := writeAlbumArtwork(album.Artist, album.Name, album.ArtExt, album.ArtData)
+	if err != nil {
+		return err
+	}
+
+	// Store path and clear memory
+	album.ArtPath = path
+	album.ArtData = nil // Free memory!
+	return nil
+}
+
+// writeAlbumArtwork writes artwork bytes to ARTWORK_DIR and returns the file path
+func writeAlbumArtwork(artist, name, ext string, data []byte) (string, error) {
 	// Create artwork directory if it doesn't exist
 	if err := os.MkdirAll(ARTWORK_DIR, 0755); err != nil {
-		return fmt.Errorf("failed to create artwork directory: %v", err)
+		return "", fmt.Errorf("failed to create artwork directory: %v", err)
 	}
 
 	// Generate filename using hash of artist+album
-	hash := generateAlbumCacheKey(album.Artist, album.Name)
-	filename := fmt.Sprintf("%s.%s", hash, album.ArtExt)
+	hash := generateAlbumCacheKey(artist, name)
+	filename := fmt.Sprintf("%s.%s", hash, ext)
 	filepath := ARTWORK_DIR + filename
 
 	// Write artwork to disk
-	if err := os.WriteFile(filepath, album.ArtData, 0644); err != nil {
-		return fmt.Errorf("failed to write artwork file: %v", err)
+	if err := os.WriteFile(filepath, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write artwork file: %v", err)
 	}
 
-	// Store path and clear memory
-	album.ArtPath = filepath
-	album.ArtData = nil // Free memory!
-
 	logMsg(fmt.Sprintf("[ARTWORK] Saved to disk: %s", filepath))
-	return nil
+	return filepath, nil
 }
 
 // loadAlbumArtwork loads album artwork from disk if available
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ASSETS_DIR holds the UI font and the generated launcher icon
+var ASSETS_DIR = "./assets"
+
+// MUSIC_ROOTS are the folders scanned for music. MUSIC_ROOT is the first of
+// them; playlists made on the device are saved there.
+var MUSIC_ROOTS = []string{MUSIC_ROOT}
+
+// DATA_DIR holds MiyooPod's own files: library cache, artwork, stats and the
+// OTA handoff files. The settings file stays where it was found at startup,
+// since it is what points here.
+var DATA_DIR = MUSIC_ROOT
+
+// Locations given on the command line or in the environment win over the
+// storage settings
+var musicFromFlags, dataFromFlags bool
+
+// parsePathFlags reads the storage locations from the command line, falling
+// back to environment variables and then to the platform defaults (the SD card
+// layout on the device):
+//
+//	-music  / MIYOOPOD_MUSIC   folders scanned for audio files, separated by ':'
+//	-data   / MIYOOPOD_DATA    library cache, settings, stats and artwork
+//	-assets / MIYOOPOD_ASSETS  folder containing ui_font.ttf
+func parsePathFlags() {
+	defaultMusic, defaultData := defaultPaths()
+	music := flag.String("music", envOr("MIYOOPOD_MUSIC", defaultMusic), "music folders to scan, separated by ':'")
+	data := flag.String("data", envOr("MIYOOPOD_DATA", defaultData), "folder for the library cache, settings and stats (default: the first music folder)")
+	assets := flag.String("assets", envOr("MIYOOPOD_ASSETS", ASSETS_DIR), "folder containing ui_font.ttf")
+	flag.Parse()
+
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	musicFromFlags = set["music"] || os.Getenv("MIYOOPOD_MUSIC") != ""
+	dataFromFlags = set["data"] || os.Getenv("MIYOOPOD_DATA") != ""
+
+	if *music != "" {
+		setMusicRoots(filepath.SplitList(*music))
+	}
+	if *music != "" || *data != "" {
+		dataDir := *data
+		if dataDir == "" {
+			dataDir = MUSIC_ROOT
+		}
+		SETTINGS_PATH = filepath.Join(dataDir, ".miyoopod_settings.json")
+		setDataDir(dataDir)
+	}
+	ASSETS_DIR = *assets
+	FONT_PATH = filepath.Join(ASSETS_DIR, "ui_font.ttf")
+}
+
+func envOr(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// normalizeMusicRoots cleans the folders, giving each a trailing separator,
+// and drops blanks, duplicates and folders inside another root (which would
+// be scanned twice)
+func normalizeMusicRoots(roots []string) []string {
+	var out []string
+	for _, root := range roots {
+		root = strings.TrimSpace(root)
+		if root == "" {
+			continue
+		}
+		root = filepath.Clean(root) + string(filepath.Separator)
+		if root == "//" {
+			root = "/"
+		}
+
+		nested := false
+		for i := 0; i < len(out); i++ {
+			switch {
+			case strings.HasPrefix(root, out[i]):
+				nested = true
+			case strings.HasPrefix(out[i], root):
+				// The new root contains an earlier one; it replaces it
+				out = append(out[:i], out[i+1:]...)
+				i--
+			}
+		}
+		if !nested {
+			out = append(out, root)
+		}
+	}
+	return out
+}
+
+// setMusicRoots changes the folders the library scan walks. An empty list
+// keeps the current ones.
+func setMusicRoots(roots []string) {
+	roots = normalizeMusicRoots(roots)
+	if len(roots) == 0 {
+		return
+	}
+	MUSIC_ROOTS = roots
+	MUSIC_ROOT = roots[0]
+	PLAYLIST_DIR = MUSIC_ROOT + "Playlists/"
+	logMsg(fmt.Sprintf("INFO: Music folders: %s", strings.Join(MUSIC_ROOTS, ", ")))
+}
+
+// setDataDir moves MiyooPod's own files to dir. The settings file stays put,
+// and so does the scrobbler log, which scrobble tools look for at the card
+// root.
+func setDataDir(dir string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		logMsg(fmt.Sprintf("WARNING: Could not create data folder %s: %v", dir, err))
+	}
+	dataFile := func(name string) string {
+		return filepath.Join(dir, name)
+	}
+
+	DATA_DIR = filepath.Clean(dir) + string(filepath.Separator)
+	LIBRARY_JSON_PATH = dataFile(".miyoopod_library.json")
+	ARTWORK_DIR = dataFile(".miyoopod_artwork") + string(filepath.Separator)
+	SMART_PLAYLISTS_PATH = dataFile(".miyoopod_smart_playlists.json")
+	PLAYBACK_STATE_PATH = dataFile(".miyoopod_playback.json")
+	PLAY_STATS_PATH = dataFile(".miyoopod_stats.json")
+	AUDIOBOOK_PROGRESS_PATH = dataFile(".miyoopod_audiobooks.json")
+	LISTENBRAINZ_EXPORT_PATH = dataFile("listenbrainz_listens.json")
+	UPDATE_INFO_PATH = dataFile(".miyoopod_update.json")
+	UPDATE_STATUS_PATH = dataFile(".miyoopod_update_status")
+
+	logMsg(fmt.Sprintf("INFO: Data folder %s", DATA_DIR))
+}
+
+// applyStorageSettings switches to the music folders and data folder saved in
+// settings, unless they were given on the command line
+func (app *MiyooPod) applyStorageSettings(roots []string, dataDir string) {
+	app.MusicRoots = normalizeMusicRoots(roots)
+	app.DataDir = dataDir
+
+	if len(app.MusicRoots) > 0 && !musicFromFlags {
+		setMusicRoots(app.MusicRoots)
+	}
+	if app.DataDir != "" && !dataFromFlags {
+		setDataDir(app.DataDir)
+	}
+}
+
+// musicRelPath returns path relative to the music root containing it, or
+// path unchanged if it is outside them all
+func musicRelPath(path string) string {
+	for _, root := range MUSIC_ROOTS {
+		if strings.HasPrefix(path, root) {
+			return strings.TrimPrefix(path, root)
+		}
+	}
+	return path
+}
@@ -0,0 +1,34 @@
+//go:build !desktop
+
+package main
+
+// DESKTOP_BUILD is false on the device: keys arrive already mapped by the
+// Miyoo SDL driver and volume goes through MI_AO
+const DESKTOP_BUILD = false
+
+// defaultPaths keeps the built-in SD card layout
+func defaultPaths() (music, data string) {
+	return "", ""
+}
+
+// folderBrowseRoot is where the folder browser starts: the SD card and any
+// other mounted partitions
+func folderBrowseRoot() string {
+	return "/mnt/"
+}
+
+func translateKeyEvent(event int) int {
+	return event
+}
+
+// applySystemVolume sets the hardware volume, keeping SDL2_mixer at full scale
+func (app *MiyooPod) applySystemVolume(percent int) {
+	app.Audio.SetVolume(100)
+	setMiAOVolume(percent)
+}
+
+// mixerVolume is the SDL2_mixer volume in percent while not fading: the
+// hardware carries the system volume, so the mixer runs at full scale
+func (app *MiyooPod) mixerVolume() int {
+	return 100
+}
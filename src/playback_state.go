@@ -7,7 +7,7 @@ import (
 	"time"
 )
 
-const PLAYBACK_STATE_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_playback.json"
+var PLAYBACK_STATE_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_playback.json"
 
 // PlaybackState stores queue and playback position for session persistence
 type PlaybackState struct {
@@ -39,7 +39,7 @@ func (app *MiyooPod) savePlaybackState() {
 	position := 0.0
 	if app.Playing != nil {
 		if app.Playing.State != StateStopped {
-			state := audioGetState()
+			state := app.audioState()
 			if state.Position > 0 {
 				position = state.Position
 			} else if app.Playing.Position > 0 {
@@ -153,7 +153,8 @@ func (app *MiyooPod) restorePlaybackState() {
 		app.Playing.Duration = track.Duration
 	}
 
-	err = app.mpvLoadFile(track.Path)
+	app.Audio.SetGain(app.replayGainFactor(track))
+	err = app.mpvLoadFile(track)
 	if err != nil {
 		logMsg(fmt.Sprintf("WARNING: Failed to load saved track: %v", err))
 		app.Playing.State = StateStopped
@@ -162,16 +163,18 @@ func (app *MiyooPod) restorePlaybackState() {
 	}
 
 	// Pause immediately - user expects to resume manually
-	audioPause()
+	app.Audio.Pause()
 	app.Playing.State = StatePaused
 
 	// Set position immediately so UI shows saved position
 	if ps.Position > 0 {
 		app.Playing.Position = ps.Position
 	}
+	app.startListen(ps.Position)
 
 	app.updateCoverflowForCurrentTrack()
 	app.NPCacheDirty = true
+	app.preloadNextTrack()
 
 	logMsg(fmt.Sprintf("INFO: Restored playback state - %s at %.1fs (paused)", track.Title, ps.Position))
 
@@ -181,15 +184,17 @@ func (app *MiyooPod) restorePlaybackState() {
 		go func() {
 			for attempt := 0; attempt < 10; attempt++ {
 				time.Sleep(100 * time.Millisecond)
-				audioSeek(seekTarget)
+				app.Audio.Seek(track.Start + seekTarget)
 
 				time.Sleep(50 * time.Millisecond)
-				state := audioGetState()
+				state := trackState(track, app.Audio.State())
 				if state.Position > 0 && state.Duration > 0 {
-					app.Playing.Position = state.Position
-					app.Playing.Duration = state.Duration
-					app.NPCacheDirty = true
-					app.requestRedraw()
+					app.post(func() {
+						app.Playing.Position = state.Position
+						app.Playing.Duration = state.Duration
+						app.NPCacheDirty = true
+						app.requestRedraw()
+					})
 					return
 				}
 			}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// restartApp simulates quitting and relaunching: a fresh app with the same
+// library restores the saved session
+func restartApp(t *testing.T, app *MiyooPod) (*MiyooPod, *simAudio) {
+	t.Helper()
+	app.savePlaybackState()
+
+	restored, sim := newTestApp(t, 0)
+	for _, track := range app.Library.Tracks {
+		copied := *track
+		restored.Library.addTrack(&copied, nil)
+	}
+	return restored, sim
+}
+
+// waitForPosition waits for the background seek issued by restorePlaybackState
+func waitForPosition(t *testing.T, sim *simAudio, want float64) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for {
+		sim.mu.Lock()
+		pos := sim.position
+		sim.mu.Unlock()
+		if pos == want {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("backend position %.1f, want %.1f", pos, want)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRestoreSession(t *testing.T) {
+	app, sim := newTestApp(t, 6)
+	app.Queue.Shuffle = true
+	app.Queue.Repeat = RepeatAll
+	playQueue(t, app, 3)
+	app.nextTrack()
+	sim.Advance(42 * time.Second)
+	app.syncAudioState()
+
+	wantPath := currentPath(app)
+	wantOrder := append([]int(nil), app.Queue.ShuffleOrder...)
+
+	restored, restoredSim := restartApp(t, app)
+	restored.restorePlaybackState()
+
+	if got := currentPath(restored); got != wantPath {
+		t.Errorf("current track = %q, want %q", got, wantPath)
+	}
+	if restored.Queue.CurrentIndex != 1 || !restored.Queue.Shuffle || restored.Queue.Repeat != RepeatAll {
+		t.Errorf("queue index %d shuffle %v repeat %v, want 1, true, all",
+			restored.Queue.CurrentIndex, restored.Queue.Shuffle, restored.Queue.Repeat)
+	}
+	for i, idx := range wantOrder {
+		if i >= len(restored.Queue.ShuffleOrder) || restored.Queue.ShuffleOrder[i] != idx {
+			t.Fatalf("shuffle order %v, want %v", restored.Queue.ShuffleOrder, wantOrder)
+		}
+	}
+
+	if restored.Playing.State != StatePaused || restored.Playing.Position != 42 {
+		t.Errorf("state %v at %.1fs, want paused at 42s", restored.Playing.State, restored.Playing.Position)
+	}
+	if state := restoredSim.State(); !state.IsPaused {
+		t.Errorf("backend not paused after restore")
+	}
+	if restoredSim.Current() != wantPath {
+		t.Errorf("backend loaded %q, want %q", restoredSim.Current(), wantPath)
+	}
+	waitForPosition(t, restoredSim, 42)
+}
+
+func TestRestoreSkipsMissingTracks(t *testing.T) {
+	app, _ := newTestApp(t, 5)
+	playQueue(t, app, 3)
+	app.savePlaybackState()
+
+	// Tracks 1 and 3 were deleted before the next launch
+	restored, _ := newTestApp(t, 0)
+	for _, track := range app.Library.Tracks {
+		if track.Path == trackPath(1) || track.Path == trackPath(3) {
+			continue
+		}
+		copied := *track
+		restored.Library.addTrack(&copied, nil)
+	}
+	restored.restorePlaybackState()
+
+	if len(restored.Queue.Tracks) != 3 {
+		t.Fatalf("restored %d tracks, want 3", len(restored.Queue.Tracks))
+	}
+	if got := currentPath(restored); got != trackPath(4) || restored.Queue.CurrentIndex != 1 {
+		t.Errorf("current %q at %d, want %q at 1", got, restored.Queue.CurrentIndex, trackPath(4))
+	}
+}
+
+func TestEmptyQueueRemovesSavedState(t *testing.T) {
+	app, _ := newTestApp(t, 2)
+	playQueue(t, app, 0)
+	app.savePlaybackState()
+
+	app.clearQueue()
+	app.savePlaybackState()
+
+	restored, sim := newTestApp(t, 2)
+	restored.restorePlaybackState()
+	if len(restored.Queue.Tracks) != 0 || len(sim.Loads()) != 0 {
+		t.Errorf("restored a cleared queue: %d tracks", len(restored.Queue.Tracks))
+	}
+}
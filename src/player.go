@@ -18,7 +18,9 @@ func (app *MiyooPod) playTrackFromList(tracks []*Track, startIdx int) {
 	app.Queue.CurrentIndex = startIdx
 
 	if app.Queue.Shuffle {
+		// The chosen track leads the shuffle order
 		app.buildShuffleOrder(startIdx)
+		app.Queue.CurrentIndex = 0
 	} else {
 		// Clear old shuffle order when playing without shuffle
 		app.Queue.ShuffleOrder = nil
@@ -57,12 +59,19 @@ func (app *MiyooPod) playCurrentQueueTrack() {
 		return
 	}
 
+	// Count the outgoing track before it's replaced
+	app.recordListen(false)
+	app.recordAudiobookProgress(false)
+
 	// Track song play for analytics
 	TrackSongPlayed(track)
 
 	app.Playing.Track = track
 	app.Playing.State = StatePlaying
 	app.Playing.Position = 0
+	app.ListenRecorded = false
+	app.PlayStartedAt = time.Now()
+	app.startListen(0)
 	// Use track duration if available, otherwise will be updated by poller
 	if track.Duration > 0 {
 		app.Playing.Duration = track.Duration
@@ -70,7 +79,11 @@ func (app *MiyooPod) playCurrentQueueTrack() {
 		app.Playing.Duration = 0
 	}
 
-	err := app.mpvLoadFile(track.Path)
+	// Loading replaces any preloaded track in the audio layer
+	app.forgetPreload()
+	app.Audio.SetGain(app.replayGainFactor(track))
+
+	err := app.mpvLoadFile(track)
 	if err != nil {
 		logMsg(fmt.Sprintf("ERROR: Failed to load: %v", err))
 		app.Playing.State = StateStopped
@@ -81,7 +94,7 @@ func (app *MiyooPod) playCurrentQueueTrack() {
 
 	// Verify playback actually started
 	time.Sleep(100 * time.Millisecond)
-	state := audioGetState()
+	state := app.Audio.State()
 	if !state.IsPlaying && !state.IsPaused {
 		logMsg("ERROR: Audio failed to start playing")
 		app.Playing.State = StateStopped
@@ -89,21 +102,112 @@ func (app *MiyooPod) playCurrentQueueTrack() {
 		app.showError("Playback failed to start")
 		return
 	}
+	app.resumeAudiobook(track)
 
 	app.updateCoverflowForCurrentTrack()
 	app.NPCacheDirty = true
+	app.preloadNextTrack()
 
 	// Persist queue state when track changes
 	app.savePlaybackState()
 }
 
+// handleGaplessAdvance is called when the audio layer has already switched to
+// the preloaded track. It advances the queue to match without reloading audio.
+func (app *MiyooPod) handleGaplessAdvance() {
+	if app.Queue == nil || len(app.Queue.Tracks) == 0 {
+		return
+	}
+
+	preloaded := app.PreloadedPath
+	previous := app.Playing.Track
+	app.forgetPreload()
+
+	// The previous track played to its end
+	app.recordListen(true)
+	app.recordAudiobookProgress(true)
+
+	if app.Queue.Repeat != RepeatOne {
+		maxIdx := len(app.Queue.Tracks) - 1
+		if app.Queue.Shuffle && len(app.Queue.ShuffleOrder) > 0 {
+			maxIdx = len(app.Queue.ShuffleOrder) - 1
+		}
+		app.Queue.CurrentIndex++
+		if app.Queue.CurrentIndex > maxIdx {
+			app.Queue.CurrentIndex = 0
+		}
+	}
+
+	track := app.getCurrentTrack()
+	if track == nil || track.Path != preloaded {
+		// Queue changed after the preload was issued — play the right track
+		logMsg("WARNING: Gapless track mismatch, reloading")
+		app.playCurrentQueueTrack()
+		app.requestRedraw()
+		return
+	}
+
+	TrackSongPlayed(track)
+
+	if continuesInFile(previous, track) {
+		// Same file playing on: only the gain is the next track's
+		app.Audio.SetGain(app.replayGainFactor(track))
+	}
+
+	app.Playing.Track = track
+	app.Playing.State = StatePlaying
+	app.Playing.Position = 0
+	app.Playing.Duration = track.Duration
+	app.ListenRecorded = false
+	app.PlayStartedAt = time.Now()
+	app.startListen(0)
+	app.applyPlaybackSpeed(track)
+	app.resumeAudiobook(track)
+
+	app.updateCoverflowForCurrentTrack()
+	app.NPCacheDirty = true
+	app.preloadNextTrack()
+	app.savePlaybackState()
+	app.requestRedraw()
+}
+
+// peekNextTrack returns the track handleTrackEnd would play after the current
+// one, following shuffle order and repeat mode, or nil if playback would stop.
+func (app *MiyooPod) peekNextTrack() *Track {
+	if app.Queue == nil || len(app.Queue.Tracks) == 0 {
+		return nil
+	}
+
+	if app.Queue.Repeat == RepeatOne {
+		return app.getCurrentTrack()
+	}
+
+	maxIdx := len(app.Queue.Tracks) - 1
+	if app.Queue.Shuffle && len(app.Queue.ShuffleOrder) > 0 {
+		maxIdx = len(app.Queue.ShuffleOrder) - 1
+	}
+
+	idx := app.Queue.CurrentIndex + 1
+	if idx > maxIdx {
+		if app.Queue.Repeat != RepeatAll {
+			return nil
+		}
+		idx = 0
+	}
+	return app.queueTrackAt(idx)
+}
+
 // getCurrentTrack returns the current track based on queue and shuffle state
 func (app *MiyooPod) getCurrentTrack() *Track {
 	if app.Queue == nil || len(app.Queue.Tracks) == 0 {
 		return nil
 	}
+	return app.queueTrackAt(app.Queue.CurrentIndex)
+}
 
-	idx := app.Queue.CurrentIndex
+// queueTrackAt returns the track at a playback position, mapping through the
+// shuffle order when shuffle is on
+func (app *MiyooPod) queueTrackAt(idx int) *Track {
 	if app.Queue.Shuffle && len(app.Queue.ShuffleOrder) > 0 {
 		if idx >= 0 && idx < len(app.Queue.ShuffleOrder) {
 			idx = app.Queue.ShuffleOrder[idx]
@@ -130,6 +234,9 @@ func (app *MiyooPod) nextTrack() {
 		return
 	}
 
+	// Leaving the track early counts as a skip
+	app.recordSkip()
+
 	// Determine the max index based on shuffle state
 	maxIdx := len(app.Queue.Tracks) - 1
 	if app.Queue.Shuffle && len(app.Queue.ShuffleOrder) > 0 {
@@ -162,6 +269,8 @@ func (app *MiyooPod) prevTrack() {
 		return
 	}
 
+	app.recordSkip()
+
 	// Determine the max index based on shuffle state
 	maxIdx := len(app.Queue.Tracks) - 1
 	if app.Queue.Shuffle && len(app.Queue.ShuffleOrder) > 0 {
@@ -181,6 +290,15 @@ func (app *MiyooPod) prevTrack() {
 }
 
 func (app *MiyooPod) handleTrackEnd() {
+	app.recordListen(true)
+	app.recordAudiobookProgress(true)
+
+	if app.sleepsAfterTrack() {
+		// The sleep timer pauses the next track as soon as it's loaded
+		app.Audio.SetVolume(0)
+		defer app.finishSleepTimerAtTrackEnd()
+	}
+
 	if app.Queue == nil || len(app.Queue.Tracks) == 0 {
 		app.Playing.State = StateStopped
 		return
@@ -204,6 +322,8 @@ func (app *MiyooPod) handleTrackEnd() {
 			app.playCurrentQueueTrack()
 		} else {
 			app.Queue.CurrentIndex = maxIdx
+			// A cue track ends mid-file, which would play on
+			app.mpvStop()
 			app.Playing.State = StateStopped
 			app.NPCacheDirty = true
 			app.refreshRootMenu()
@@ -255,6 +375,7 @@ func (app *MiyooPod) toggleShuffle() {
 		TrackAction("shuffle_disabled", nil)
 	}
 	app.NPCacheDirty = true
+	app.preloadNextTrack()
 }
 
 func (app *MiyooPod) cycleRepeat() {
@@ -273,6 +394,7 @@ func (app *MiyooPod) cycleRepeat() {
 		TrackAction("repeat_mode_changed", map[string]interface{}{"repeat_mode": "off"})
 	}
 	app.NPCacheDirty = true
+	app.preloadNextTrack()
 }
 
 func (app *MiyooPod) buildShuffleOrder(startIdx int) {
@@ -336,6 +458,10 @@ func (app *MiyooPod) drawNowPlayingScreen() {
 		return
 	}
 
+	if app.ShowLyrics {
+		app.ensureLyrics()
+	}
+
 	if app.NowPlayingBG == nil || app.NPCacheDirty {
 		app.renderNowPlayingFull()
 		app.drawStatusBar()
@@ -351,6 +477,10 @@ func (app *MiyooPod) drawNowPlayingScreen() {
 
 	// Draw progress bar using direct pixel operations (bypass gg)
 	app.fastDrawProgressBar(40, PROGRESS_BAR_Y, SCREEN_WIDTH-80, app.Playing.Position, app.Playing.Duration)
+
+	if app.ShowLyrics {
+		app.drawLyricsLines()
+	}
 }
 
 // updateProgressBarOnly is the fast path called by the playback poller.
@@ -382,6 +512,11 @@ func (app *MiyooPod) updateProgressBarOnly() {
 
 // renderNowPlayingFull draws all static now-playing elements via gg
 func (app *MiyooPod) renderNowPlayingFull() {
+	if app.ShowLyrics {
+		app.renderLyricsFull()
+		return
+	}
+
 	dc := app.DC
 	track := app.Playing.Track
 
@@ -414,8 +549,12 @@ func (app *MiyooPod) renderNowPlayingFull() {
 		dc.DrawString(albumText, float64(infoX), float64(infoStartY+65))
 	}
 
-	// Track/Disc numbers if available
-	if track.TrackNum > 0 {
+	// Chapter playing, or the track number if available
+	if idx := chapterIndex(track, app.Playing.Position); idx >= 0 {
+		dc.SetFontFace(app.FontSmall)
+		chapterText := fmt.Sprintf("%s (%d/%d)", track.Chapters[idx].Title, idx+1, len(track.Chapters))
+		dc.DrawString(app.truncateText(chapterText, maxWidth, app.FontSmall), float64(infoX), float64(infoStartY+95))
+	} else if track.TrackNum > 0 {
 		dc.SetFontFace(app.FontSmall)
 		trackInfo := fmt.Sprintf("Track %d", track.TrackNum)
 		if track.TrackTotal > 0 {
@@ -427,8 +566,16 @@ func (app *MiyooPod) renderNowPlayingFull() {
 	// Control hints
 	dc.SetFontFace(app.FontSmall)
 	dc.SetHexColor(app.CurrentTheme.Dim)
-	dc.DrawString("Hold L/R to seek", float64(infoX), float64(infoStartY+125))
+	seekHint := "Hold L/R to seek"
+	switch {
+	case len(track.Chapters) > 0:
+		seekHint = "L/R Chapter · L2/R2 Skip 30s"
+	case app.isAudiobook(track):
+		seekHint = "L2/R2 Skip 30s · Hold L/R to seek"
+	}
+	dc.DrawString(seekHint, float64(infoX), float64(infoStartY+125))
 	dc.DrawString("X Repeat · SELECT Shuffle", float64(infoX), float64(infoStartY+150))
+	dc.DrawString("UP Lyrics · DOWN Sleep Timer", float64(infoX), float64(infoStartY+175))
 
 	app.drawStatusIndicators(infoStartY + 250)
 }
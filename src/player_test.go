@@ -0,0 +1,233 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGaplessAdvance(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 0)
+	waitForPreload(t, sim, trackPath(2))
+
+	sim.Finish()
+	app.syncAudioState()
+
+	if got := currentPath(app); got != trackPath(2) {
+		t.Fatalf("current track = %q, want %q", got, trackPath(2))
+	}
+	if app.Playing.Track.Path != sim.Current() {
+		t.Errorf("playing %q but backend has %q", app.Playing.Track.Path, sim.Current())
+	}
+	if loads := sim.Loads(); len(loads) != 1 {
+		t.Errorf("gapless advance reloaded audio: %v", loads)
+	}
+	if got := app.playCount(app.Library.Tracks[0]); got != 1 {
+		t.Errorf("finished track play count = %d, want 1", got)
+	}
+}
+
+func TestTrackEndWithoutPreload(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 0)
+	waitForPreload(t, sim, trackPath(2))
+	sim.ClearPreload()
+
+	sim.Finish()
+	app.syncAudioState()
+
+	if got := sim.Current(); got != trackPath(2) {
+		t.Fatalf("backend playing %q, want %q", got, trackPath(2))
+	}
+	if app.Queue.CurrentIndex != 1 {
+		t.Errorf("CurrentIndex = %d, want 1", app.Queue.CurrentIndex)
+	}
+}
+
+func TestRepeatOffStopsAtEnd(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 2)
+
+	sim.Finish()
+	app.syncAudioState()
+
+	if app.Playing.State != StateStopped {
+		t.Errorf("state = %v, want stopped", app.Playing.State)
+	}
+	if app.Queue.CurrentIndex != 2 {
+		t.Errorf("CurrentIndex = %d, want 2", app.Queue.CurrentIndex)
+	}
+	if sim.Current() != "" {
+		t.Errorf("backend still playing %q", sim.Current())
+	}
+}
+
+func TestRepeatAllWraps(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	app.Queue.Repeat = RepeatAll
+	playQueue(t, app, 2)
+	waitForPreload(t, sim, trackPath(1))
+
+	sim.Finish()
+	app.syncAudioState()
+
+	if app.Queue.CurrentIndex != 0 || sim.Current() != trackPath(1) {
+		t.Errorf("after wrap: index %d playing %q, want 0 and %q", app.Queue.CurrentIndex, sim.Current(), trackPath(1))
+	}
+}
+
+func TestRepeatOneReplays(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	app.Queue.Repeat = RepeatOne
+	playQueue(t, app, 1)
+	waitForPreload(t, sim, trackPath(2))
+
+	for i := 0; i < 2; i++ {
+		sim.Finish()
+		app.syncAudioState()
+		waitForPreload(t, sim, trackPath(2))
+	}
+
+	if app.Queue.CurrentIndex != 1 || sim.Current() != trackPath(2) {
+		t.Errorf("index %d playing %q, want 1 and %q", app.Queue.CurrentIndex, sim.Current(), trackPath(2))
+	}
+	if got := app.playCount(app.Library.Tracks[1]); got != 2 {
+		t.Errorf("play count = %d, want 2", got)
+	}
+}
+
+func TestCycleRepeat(t *testing.T) {
+	app, _ := newTestApp(t, 1)
+
+	want := []RepeatMode{RepeatAll, RepeatOne, RepeatOff}
+	for _, mode := range want {
+		app.cycleRepeat()
+		if app.Queue.Repeat != mode {
+			t.Fatalf("Repeat = %v, want %v", app.Queue.Repeat, mode)
+		}
+	}
+}
+
+func TestNextTrackAtEnd(t *testing.T) {
+	app, sim := newTestApp(t, 2)
+	playQueue(t, app, 1)
+
+	app.nextTrack()
+	if app.Playing.State != StateStopped || sim.Current() != "" {
+		t.Errorf("next on last track with repeat off should stop, state %v playing %q", app.Playing.State, sim.Current())
+	}
+
+	app.Queue.Repeat = RepeatAll
+	playQueue(t, app, 1)
+	app.nextTrack()
+	if app.Queue.CurrentIndex != 0 || sim.Current() != trackPath(1) {
+		t.Errorf("next on last track with repeat all: index %d playing %q", app.Queue.CurrentIndex, sim.Current())
+	}
+}
+
+func TestPrevTrackRestartsAfterThreeSeconds(t *testing.T) {
+	app, sim := newTestApp(t, 2)
+	playQueue(t, app, 1)
+
+	sim.Advance(10 * time.Second)
+	app.syncAudioState()
+	app.prevTrack()
+
+	if app.Queue.CurrentIndex != 1 {
+		t.Errorf("CurrentIndex = %d, want 1", app.Queue.CurrentIndex)
+	}
+	if pos := sim.State().Position; pos != 0 {
+		t.Errorf("position = %.1f, want 0", pos)
+	}
+}
+
+func TestSeekingDoesNotCountAsListening(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 0)
+
+	// 10 seconds heard, then a jump most of the way through
+	sim.Advance(10 * time.Second)
+	app.syncAudioState()
+	app.mpvSeek(150)
+	sim.Advance(5 * time.Second)
+	app.syncAudioState()
+	app.nextTrack()
+
+	first := app.trackStats(app.Library.Tracks[0])
+	if first.PlayCount != 0 || first.SkipCount != 1 {
+		t.Errorf("seeked track: %d plays, %d skips; want a skip", first.PlayCount, first.SkipCount)
+	}
+
+	// Seeking back and hearing part of the track again still adds up
+	sim.Advance(60 * time.Second)
+	app.syncAudioState()
+	app.mpvSeek(-50)
+	sim.Advance(40 * time.Second)
+	app.syncAudioState()
+	app.nextTrack()
+
+	if got := app.playCount(app.Library.Tracks[1]); got != 1 {
+		t.Errorf("track heard for 100s of 180s: play count = %d, want 1", got)
+	}
+}
+
+func TestBuildShuffleOrder(t *testing.T) {
+	app, _ := newTestApp(t, 10)
+	app.Queue.Tracks = app.Library.Tracks
+
+	for start := 0; start < 10; start++ {
+		app.buildShuffleOrder(start)
+		order := app.Queue.ShuffleOrder
+		if len(order) != 10 || order[0] != start {
+			t.Fatalf("order %v should start with %d", order, start)
+		}
+		seen := make(map[int]bool)
+		for _, idx := range order {
+			if idx < 0 || idx >= 10 || seen[idx] {
+				t.Fatalf("order %v is not a permutation", order)
+			}
+			seen[idx] = true
+		}
+	}
+}
+
+func TestToggleShuffleKeepsCurrentTrack(t *testing.T) {
+	app, _ := newTestApp(t, 8)
+	playQueue(t, app, 4)
+
+	app.toggleShuffle()
+	if !app.Queue.Shuffle || app.Queue.CurrentIndex != 0 {
+		t.Fatalf("shuffle %v index %d, want on at 0", app.Queue.Shuffle, app.Queue.CurrentIndex)
+	}
+	if got := currentPath(app); got != trackPath(5) {
+		t.Errorf("current track after shuffle on = %q, want %q", got, trackPath(5))
+	}
+
+	app.toggleShuffle()
+	if app.Queue.Shuffle || app.Queue.CurrentIndex != 4 || app.Queue.ShuffleOrder != nil {
+		t.Errorf("shuffle off: index %d order %v, want 4 and nil", app.Queue.CurrentIndex, app.Queue.ShuffleOrder)
+	}
+}
+
+func TestShufflePlaysEveryTrackOnce(t *testing.T) {
+	app, sim := newTestApp(t, 6)
+	app.Queue.Shuffle = true
+	playQueue(t, app, 2)
+
+	played := map[string]int{sim.Current(): 1}
+	for i := 1; i < 6; i++ {
+		app.nextTrack()
+		played[sim.Current()]++
+	}
+
+	for _, track := range app.Library.Tracks {
+		if played[track.Path] != 1 {
+			t.Errorf("%s played %d times, want once", track.Path, played[track.Path])
+		}
+	}
+
+	app.nextTrack()
+	if app.Playing.State != StateStopped {
+		t.Errorf("playback should stop after the shuffled queue ends")
+	}
+}
@@ -1,43 +1,497 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 )
 
-// parsePlaylist reads an M3U file and resolves track references
-func (app *MiyooPod) parsePlaylist(pl *Playlist) {
+// playlistFormats maps supported playlist extensions to their format name
+var playlistFormats = map[string]string{
+	".m3u":  "M3U",
+	".m3u8": "M3U",
+	".pls":  "PLS",
+	".xspf": "XSPF",
+}
+
+// isPlaylistFile reports whether path has a supported playlist extension
+func isPlaylistFile(path string) bool {
+	_, ok := playlistFormats[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// playlistEntry is one track reference read from a playlist file
+type playlistEntry struct {
+	Location string  // Path or URI as written in the file
+	URI      bool    // Location is a URI reference (XSPF) and must be unescaped
+	Title    string  // Display title from #EXTINF, TitleN= or <title>
+	Artist   string  // Artist from "Artist - Title" or <creator>
+	Duration float64 // Seconds, 0 when unknown
+}
+
+// parsePlaylists reads every playlist in the library and resolves its
+// entries against the scanned tracks. Returns the number of entries that
+// could not be matched to a file.
+func (lib *Library) parsePlaylists() int {
+	res := newPlaylistResolver(lib)
+	unresolved := 0
+	for _, pl := range lib.Playlists {
+		parsePlaylist(pl, res)
+		unresolved += len(pl.Missing)
+	}
+	return unresolved
+}
+
+// parsePlaylist reads an M3U, PLS or XSPF file and resolves track references
+func parsePlaylist(pl *Playlist, res *playlistResolver) {
 	data, err := os.ReadFile(pl.Path)
 	if err != nil {
 		logMsg("ERROR: Failed to read playlist " + pl.Path + ": " + err.Error())
 		return
 	}
 
-	lines := strings.Split(string(data), "\n")
+	// Strip a UTF-8 BOM left by Windows editors
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var name string
+	var entries []playlistEntry
+	switch strings.ToLower(filepath.Ext(pl.Path)) {
+	case ".pls":
+		entries = parsePLS(data)
+	case ".xspf":
+		name, entries, err = parseXSPF(data)
+		if err != nil {
+			logMsg("ERROR: Failed to parse playlist " + pl.Path + ": " + err.Error())
+			return
+		}
+	default:
+		name, entries = parseM3U(data)
+	}
+	if name != "" {
+		pl.Name = name
+	}
+
 	baseDir := filepath.Dir(pl.Path)
+	pl.Tracks = nil
+	pl.Missing = nil
+	for _, entry := range entries {
+		path := normalizePlaylistPath(entry.Location, entry.URI, baseDir)
+		if track := res.resolve(path); track != nil {
+			pl.Tracks = append(pl.Tracks, track)
+			continue
+		}
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+		// Playable files outside the library (e.g. not under MUSIC_ROOT) still
+		// play, using the metadata the playlist carries
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && isAudioFile(path) {
+			pl.Tracks = append(pl.Tracks, entry.track(path))
+			continue
+		}
+
+		pl.Missing = append(pl.Missing, entry.Location)
+		logMsg(fmt.Sprintf("WARNING: Playlist %s: entry not found: %s", pl.Name, entry.Location))
+	}
+
+	logMsg(fmt.Sprintf("Parsed playlist %s: %d tracks, %d unresolved", pl.Name, len(pl.Tracks), len(pl.Missing)))
+}
+
+// track builds a Track for a file that isn't in the library
+func (e playlistEntry) track(path string) *Track {
+	title := e.Title
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	return &Track{
+		Path:     path,
+		Title:    title,
+		Artist:   e.Artist,
+		Duration: e.Duration,
+	}
+}
+
+// parseM3U reads plain and extended M3U. #EXTINF supplies the duration and
+// "Artist - Title" for the entry that follows; #PLAYLIST names the playlist.
+func parseM3U(data []byte) (string, []playlistEntry) {
+	var name string
+	var entries []playlistEntry
+	var pending playlistEntry
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
 		// Handle Windows line endings
-		line = strings.TrimRight(line, "\r")
+		line := strings.TrimSpace(strings.TrimRight(scanner.Text(), "\r"))
+		if line == "" {
+			continue
+		}
 
-		if line == "" || strings.HasPrefix(line, "#") {
+		if strings.HasPrefix(line, "#") {
+			switch {
+			case strings.HasPrefix(line, "#EXTINF:"):
+				pending = parseExtInf(strings.TrimPrefix(line, "#EXTINF:"))
+			case strings.HasPrefix(line, "#PLAYLIST:"):
+				name = strings.TrimSpace(strings.TrimPrefix(line, "#PLAYLIST:"))
+			}
 			continue
 		}
 
-		// Resolve relative paths against the .m3u file's directory
-		trackPath := line
-		if !filepath.IsAbs(trackPath) {
-			trackPath = filepath.Join(baseDir, trackPath)
+		pending.Location = line
+		entries = append(entries, pending)
+		pending = playlistEntry{}
+	}
+
+	return name, entries
+}
+
+// parseExtInf parses the part after "#EXTINF:", e.g. `215,Artist - Title` or
+// `215 tvg-name="x",Title`. A negative duration means unknown. The title runs
+// from the first comma outside a quoted attribute, so it may contain commas.
+func parseExtInf(s string) playlistEntry {
+	var entry playlistEntry
+
+	info, title := s, ""
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' {
+			quoted = !quoted
+		} else if s[i] == ',' && !quoted {
+			info, title = s[:i], s[i+1:]
+			break
+		}
+	}
+	if fields := strings.Fields(info); len(fields) > 0 {
+		if secs, err := strconv.ParseFloat(fields[0], 64); err == nil && secs > 0 {
+			entry.Duration = secs
 		}
-		trackPath = filepath.Clean(trackPath)
+	}
 
-		if track, ok := app.Library.TracksByPath[trackPath]; ok {
-			pl.Tracks = append(pl.Tracks, track)
+	title = strings.TrimSpace(title)
+	if artist, t, ok := strings.Cut(title, " - "); ok {
+		entry.Artist = strings.TrimSpace(artist)
+		title = strings.TrimSpace(t)
+	}
+	entry.Title = title
+	return entry
+}
+
+// parsePLS reads a [playlist] INI file with FileN/TitleN/LengthN keys
+func parsePLS(data []byte) []playlistEntry {
+	byIndex := make(map[int]*playlistEntry)
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(strings.TrimRight(line, "\r"))
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		var field string
+		for _, prefix := range []string{"file", "title", "length"} {
+			if strings.HasPrefix(key, prefix) {
+				field = prefix
+				break
+			}
+		}
+		if field == "" {
+			continue
+		}
+		n, err := strconv.Atoi(key[len(field):])
+		if err != nil {
+			continue
+		}
+
+		entry := byIndex[n]
+		if entry == nil {
+			entry = &playlistEntry{}
+			byIndex[n] = entry
+		}
+		switch field {
+		case "file":
+			entry.Location = value
+		case "title":
+			entry.Title = value
+		case "length":
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				entry.Duration = secs
+			}
+		}
+	}
+
+	indices := make([]int, 0, len(byIndex))
+	for n, entry := range byIndex {
+		if entry.Location != "" {
+			indices = append(indices, n)
 		}
 	}
+	sort.Ints(indices)
 
-	logMsg(fmt.Sprintf("Parsed playlist %s: %d tracks", pl.Name, len(pl.Tracks)))
+	entries := make([]playlistEntry, 0, len(indices))
+	for _, n := range indices {
+		entries = append(entries, *byIndex[n])
+	}
+	return entries
+}
+
+// xspfPlaylist is the subset of XSPF (http://xspf.org/ns/0/) we read and write
+type xspfPlaylist struct {
+	XMLName xml.Name    `xml:"playlist"`
+	Version string      `xml:"version,attr"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	Title   string      `xml:"title,omitempty"`
+	Tracks  []xspfTrack `xml:"trackList>track"`
+}
+
+type xspfTrack struct {
+	Location string `xml:"location"`
+	Title    string `xml:"title,omitempty"`
+	Creator  string `xml:"creator,omitempty"`
+	Duration int64  `xml:"duration,omitempty"` // Milliseconds
+}
+
+// parseXSPF reads an XSPF playlist. Locations are URI references.
+func parseXSPF(data []byte) (string, []playlistEntry, error) {
+	var doc xspfPlaylist
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return "", nil, err
+	}
+
+	entries := make([]playlistEntry, 0, len(doc.Tracks))
+	for _, t := range doc.Tracks {
+		location := strings.TrimSpace(t.Location)
+		if location == "" {
+			continue
+		}
+		entries = append(entries, playlistEntry{
+			Location: location,
+			URI:      true,
+			Title:    strings.TrimSpace(t.Title),
+			Artist:   strings.TrimSpace(t.Creator),
+			Duration: float64(t.Duration) / 1000,
+		})
+	}
+	return strings.TrimSpace(doc.Title), entries, nil
+}
+
+// normalizePlaylistPath turns a playlist entry into a clean slash-separated
+// path: file:// URIs are unwrapped, Windows separators converted, and relative
+// paths resolved against the playlist's directory
+func normalizePlaylistPath(location string, uri bool, baseDir string) string {
+	p := strings.TrimSpace(location)
+
+	if len(p) >= 7 && strings.EqualFold(p[:7], "file://") {
+		p = strings.TrimPrefix(p[7:], "localhost")
+		uri = true
+	}
+	if uri {
+		if unescaped, err := url.PathUnescape(p); err == nil {
+			p = unescaped
+		}
+	}
+
+	p = strings.ReplaceAll(p, "\\", "/")
+
+	// "/C:/Music/..." from file:///C:/Music/...
+	if len(p) >= 3 && p[0] == '/' && p[2] == ':' {
+		p = p[1:]
+	}
+
+	// Drive-letter paths can't exist here; keep them for suffix matching
+	if len(p) >= 2 && p[1] == ':' {
+		return filepath.ToSlash(filepath.Clean(p))
+	}
+
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(baseDir, p)
+	}
+	return filepath.ToSlash(filepath.Clean(p))
+}
+
+// playlistResolver matches playlist paths to library tracks. Exact matches are
+// tried first, then case-insensitive, then the longest unique trailing part of
+// the path (for playlists exported from another machine's library).
+type playlistResolver struct {
+	byPath   map[string]*Track // Exact path
+	byFold   map[string]*Track // Lowercased path
+	bySuffix map[string]*Track // Lowercased trailing components; nil when ambiguous
+}
+
+func newPlaylistResolver(lib *Library) *playlistResolver {
+	res := &playlistResolver{
+		byPath:   lib.TracksByPath,
+		byFold:   make(map[string]*Track, len(lib.Tracks)),
+		bySuffix: make(map[string]*Track, len(lib.Tracks)*3),
+	}
+
+	for _, track := range lib.Tracks {
+		lower := strings.ToLower(filepath.ToSlash(track.Path))
+		res.byFold[lower] = track
+
+		rel := strings.ToLower(filepath.ToSlash(musicRelPath(track.Path)))
+		parts := strings.Split(rel, "/")
+		for i := range parts {
+			suffix := strings.Join(parts[i:], "/")
+			if existing, seen := res.bySuffix[suffix]; seen && existing != track {
+				res.bySuffix[suffix] = nil
+			} else {
+				res.bySuffix[suffix] = track
+			}
+		}
+	}
+
+	return res
+}
+
+// resolve returns the library track for a normalized path, or nil
+func (res *playlistResolver) resolve(path string) *Track {
+	if track, ok := res.byPath[path]; ok {
+		return track
+	}
+
+	lower := strings.ToLower(path)
+	if track, ok := res.byFold[lower]; ok {
+		return track
+	}
+
+	parts := strings.Split(strings.TrimPrefix(lower, "/"), "/")
+	for i := range parts {
+		track, seen := res.bySuffix[strings.Join(parts[i:], "/")]
+		if !seen {
+			continue
+		}
+		// A shorter suffix can only be more ambiguous
+		return track
+	}
+	return nil
+}
+
+// savePlaylist writes a playlist back to its file in the file's own format,
+// with paths relative to the playlist's directory so it survives rescans and
+// works on a PC. Entries that couldn't be resolved are kept at the end.
+//
+// A cue track has no file of its own, so it's saved as the file it was split
+// from, once per run of tracks from that file. That's what other players
+// expect; read back, the entry plays the whole file.
+func (app *MiyooPod) savePlaylist(pl *Playlist) error {
+	baseDir := filepath.Dir(pl.Path)
+
+	var entries []*Track
+	cueCount := 0
+	for _, track := range pl.Tracks {
+		if track.File == "" {
+			entries = append(entries, track)
+			continue
+		}
+		cueCount++
+		if n := len(entries); n > 0 && entries[n-1].Path == track.File {
+			continue
+		}
+		entries = append(entries, app.Library.splitFile(track))
+	}
+	if cueCount > 0 {
+		logMsg(fmt.Sprintf("WARNING: Playlist %s: %d cue sheet tracks saved as the files they're split from", pl.Name, cueCount))
+	}
+
+	relPath := func(track *Track) string {
+		entry := track.Path
+		if rel, err := filepath.Rel(baseDir, track.Path); err == nil {
+			entry = rel
+		}
+		return filepath.ToSlash(entry)
+	}
+
+	var data []byte
+	switch strings.ToLower(filepath.Ext(pl.Path)) {
+	case ".pls":
+		var b strings.Builder
+		b.WriteString("[playlist]\n")
+		n := 0
+		for _, track := range entries {
+			n++
+			fmt.Fprintf(&b, "File%d=%s\n", n, relPath(track))
+			fmt.Fprintf(&b, "Title%d=%s\n", n, extInfTitle(track))
+			fmt.Fprintf(&b, "Length%d=%d\n", n, extInfDuration(track))
+		}
+		for _, missing := range pl.Missing {
+			n++
+			fmt.Fprintf(&b, "File%d=%s\n", n, missing)
+		}
+		fmt.Fprintf(&b, "NumberOfEntries=%d\nVersion=2\n", n)
+		data = []byte(b.String())
+
+	case ".xspf":
+		doc := xspfPlaylist{Version: "1", Xmlns: "http://xspf.org/ns/0/", Title: pl.Name}
+		for _, track := range entries {
+			doc.Tracks = append(doc.Tracks, xspfTrack{
+				Location: (&url.URL{Path: relPath(track)}).String(),
+				Title:    track.Title,
+				Creator:  track.Artist,
+				Duration: int64(track.Duration * 1000),
+			})
+		}
+		for _, missing := range pl.Missing {
+			doc.Tracks = append(doc.Tracks, xspfTrack{Location: missing})
+		}
+		out, err := xml.MarshalIndent(doc, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode playlist: %v", err)
+		}
+		data = append([]byte(xml.Header), out...)
+		data = append(data, '\n')
+
+	default:
+		var b strings.Builder
+		b.WriteString("#EXTM3U\n")
+		fmt.Fprintf(&b, "#PLAYLIST:%s\n", pl.Name)
+		for _, track := range entries {
+			fmt.Fprintf(&b, "#EXTINF:%d,%s\n", extInfDuration(track), extInfTitle(track))
+			b.WriteString(relPath(track))
+			b.WriteString("\n")
+		}
+		for _, missing := range pl.Missing {
+			b.WriteString(missing)
+			b.WriteString("\n")
+		}
+		data = []byte(b.String())
+	}
+
+	if err := os.MkdirAll(baseDir, 0755); err != nil {
+		return fmt.Errorf("failed to create playlist directory: %v", err)
+	}
+
+	tmpPath := pl.Path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write playlist: %v", err)
+	}
+	if err := os.Rename(tmpPath, pl.Path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace playlist: %v", err)
+	}
+
+	logMsg(fmt.Sprintf("INFO: Saved playlist %s (%d tracks)", pl.Name, len(pl.Tracks)))
+	return nil
+}
+
+// extInfTitle formats "Artist - Title" for #EXTINF and PLS TitleN
+func extInfTitle(track *Track) string {
+	if track.Artist == "" {
+		return track.Title
+	}
+	return track.Artist + " - " + track.Title
+}
+
+// extInfDuration returns whole seconds, or -1 when unknown
+func extInfDuration(track *Track) int {
+	if track.Duration <= 0 {
+		return -1
+	}
+	return int(track.Duration + 0.5)
 }
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PLAYLIST_DIR is where playlists created on the device are written
+var PLAYLIST_DIR = MUSIC_ROOT + "Playlists/"
+
+// createPlaylist creates a new M3U8 playlist under PLAYLIST_DIR with the given tracks
+func (app *MiyooPod) createPlaylist(name string, tracks []*Track) (*Playlist, error) {
+	name = sanitizePlaylistName(name)
+	if name == "" {
+		return nil, fmt.Errorf("invalid playlist name")
+	}
+
+	// Don't overwrite an existing file: "Name", "Name 2", "Name 3"...
+	path := filepath.Join(PLAYLIST_DIR, name+".m3u8")
+	displayName := name
+	for i := 2; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			break
+		}
+		displayName = fmt.Sprintf("%s %d", name, i)
+		path = filepath.Join(PLAYLIST_DIR, displayName+".m3u8")
+	}
+
+	pl := &Playlist{
+		Name:   displayName,
+		Path:   path,
+		Tracks: append([]*Track(nil), tracks...),
+	}
+	if err := app.savePlaylist(pl); err != nil {
+		return nil, err
+	}
+
+	app.Library.Playlists = append(app.Library.Playlists, pl)
+	sortByKey(app.Library.Playlists, func(p *Playlist) string { return sortKey(p.Name, "") })
+
+	// Persist so the playlist shows up on next launch without a rescan
+	if err := app.saveLibraryJSON(); err != nil {
+		logMsg(fmt.Sprintf("WARNING: Failed to save library: %v", err))
+	}
+
+	TrackAction("playlist_created", map[string]interface{}{"tracks": len(tracks)})
+	return pl, nil
+}
+
+// sanitizePlaylistName strips characters that aren't valid in FAT32 file names
+func sanitizePlaylistName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`<>:"/\|?*`, r) || r < 0x20 {
+			return -1
+		}
+		return r
+	}, name)
+	return strings.Trim(strings.TrimSpace(name), ".")
+}
+
+// addTracksToPlaylist appends tracks not already in the playlist and saves it
+func (app *MiyooPod) addTracksToPlaylist(pl *Playlist, tracks []*Track) error {
+	existing := make(map[string]bool, len(pl.Tracks))
+	for _, t := range pl.Tracks {
+		existing[t.Path] = true
+	}
+
+	added := 0
+	for _, t := range tracks {
+		if !existing[t.Path] {
+			pl.Tracks = append(pl.Tracks, t)
+			existing[t.Path] = true
+			added++
+		}
+	}
+	if added == 0 {
+		return nil
+	}
+
+	logMsg(fmt.Sprintf("INFO: Added %d tracks to playlist %s", added, pl.Name))
+	return app.savePlaylist(pl)
+}
+
+// menuItemTracks returns the tracks a menu item stands for
+func (app *MiyooPod) menuItemTracks(item *MenuItem) []*Track {
+	switch {
+	case item.Track != nil:
+		return []*Track{item.Track}
+	case item.Album != nil:
+		return item.Album.Tracks
+	case item.Artist != nil:
+		var tracks []*Track
+		for _, album := range item.Artist.Albums {
+			tracks = append(tracks, album.Tracks...)
+		}
+		return tracks
+	case item.Playlist != nil:
+		return item.Playlist.Tracks
+	case item.Tracks != nil:
+		return item.Tracks
+	}
+	return nil
+}
+
+// openPlaylistChooser shows a menu of playlists to add tracks to, with an
+// option to create a new one. The app returns to returnScreen afterwards.
+func (app *MiyooPod) openPlaylistChooser(tracks []*Track, returnScreen ScreenType) {
+	if len(tracks) == 0 || app.Library == nil {
+		return
+	}
+
+	var parent *MenuScreen
+	if len(app.MenuStack) > 0 {
+		parent = app.MenuStack[len(app.MenuStack)-1]
+	}
+
+	chooser := &MenuScreen{
+		Title:  "Add to Playlist",
+		Parent: parent,
+		OnBack: func() {
+			app.setScreen(returnScreen)
+		},
+	}
+
+	finish := func() {
+		if len(app.MenuStack) > 1 && app.MenuStack[len(app.MenuStack)-1] == chooser {
+			app.MenuStack = app.MenuStack[:len(app.MenuStack)-1]
+		}
+		app.refreshPlaylistMenus()
+		app.setScreen(returnScreen)
+		app.drawCurrentScreen()
+	}
+
+	items := []*MenuItem{{
+		Label: "New Playlist...",
+		Action: func() {
+			app.promptText("New Playlist", "", func(name string) {
+				if _, err := app.createPlaylist(name, tracks); err != nil {
+					logMsg(fmt.Sprintf("ERROR: Failed to create playlist: %v", err))
+					app.showError("Failed to create playlist")
+					return
+				}
+				finish()
+			})
+		},
+	}}
+	for _, pl := range app.Library.Playlists {
+		playlist := pl // capture
+		items = append(items, &MenuItem{
+			Label: fmt.Sprintf("%s (%d)", pl.Name, len(pl.Tracks)),
+			Action: func() {
+				if err := app.addTracksToPlaylist(playlist, tracks); err != nil {
+					logMsg(fmt.Sprintf("ERROR: Failed to update playlist: %v", err))
+					app.showError("Failed to save playlist")
+					return
+				}
+				finish()
+			},
+		})
+	}
+	chooser.Items = items
+	chooser.Built = true
+
+	app.MenuStack = append(app.MenuStack, chooser)
+	app.setScreen(ScreenMenu)
+	app.drawCurrentScreen()
+}
+
+// saveQueueAsPlaylist prompts for a name and writes the queue in playback order
+func (app *MiyooPod) saveQueueAsPlaylist() {
+	if app.Queue == nil || len(app.Queue.Tracks) == 0 {
+		app.showError("Queue is empty")
+		return
+	}
+
+	tracks := make([]*Track, 0, len(app.Queue.Tracks))
+	for i := range app.Queue.Tracks {
+		if t := app.queueTrackAt(i); t != nil {
+			tracks = append(tracks, t)
+		}
+	}
+
+	app.promptText("Save Queue as Playlist", "", func(name string) {
+		if _, err := app.createPlaylist(name, tracks); err != nil {
+			logMsg(fmt.Sprintf("ERROR: Failed to save queue as playlist: %v", err))
+			app.showError("Failed to create playlist")
+			return
+		}
+		app.refreshPlaylistMenus()
+	})
+}
+
+// refreshPlaylistMenus rebuilds the Playlists menu so new playlists and
+// track counts show up, keeping the current selection where possible
+func (app *MiyooPod) refreshPlaylistMenus() {
+	if app.RootMenu == nil {
+		return
+	}
+	for _, item := range app.RootMenu.Items {
+		if item.Label != "Playlists" || item.Submenu == nil {
+			continue
+		}
+		menu := item.Submenu
+		if menu.Built && menu.Builder != nil {
+			menu.Items = menu.Builder()
+			if menu.SelIndex >= len(menu.Items) {
+				menu.SelIndex = len(menu.Items) - 1
+			}
+			if menu.SelIndex < 0 {
+				menu.SelIndex = 0
+			}
+			menu.adjustScroll()
+		}
+		return
+	}
+}
+
+// openPlaylistEditor shows the reorder/remove screen for a playlist
+func (app *MiyooPod) openPlaylistEditor(pl *Playlist) {
+	app.EditPlaylist = pl
+	app.EditTracks = append([]*Track(nil), pl.Tracks...)
+	app.EditSelIndex = 0
+	app.EditScrollOff = 0
+	app.EditGrabbed = false
+	app.EditDirty = false
+
+	app.setScreen(ScreenPlaylistEdit)
+	app.drawCurrentScreen()
+}
+
+// closePlaylistEditor saves any changes and returns to the menu
+func (app *MiyooPod) closePlaylistEditor() {
+	if app.EditDirty && app.EditPlaylist != nil {
+		app.EditPlaylist.Tracks = app.EditTracks
+		if err := app.savePlaylist(app.EditPlaylist); err != nil {
+			logMsg(fmt.Sprintf("ERROR: Failed to save playlist: %v", err))
+			app.showError("Failed to save playlist")
+		}
+		app.refreshPlaylistMenus()
+	}
+
+	app.EditPlaylist = nil
+	app.EditTracks = nil
+	app.EditGrabbed = false
+	app.EditDirty = false
+
+	app.setScreen(ScreenMenu)
+	app.drawCurrentScreen()
+}
+
+// handlePlaylistEditKey processes key input on the playlist editor.
+// A grabs/drops the selected track, UP/DOWN move it while grabbed, X removes it.
+func (app *MiyooPod) handlePlaylistEditKey(key Key) {
+	n := len(app.EditTracks)
+
+	switch key {
+	case UP:
+		if n == 0 {
+			return
+		}
+		if app.EditGrabbed {
+			if app.EditSelIndex > 0 {
+				i := app.EditSelIndex
+				app.EditTracks[i-1], app.EditTracks[i] = app.EditTracks[i], app.EditTracks[i-1]
+				app.EditSelIndex--
+				app.EditDirty = true
+			}
+		} else if app.EditSelIndex > 0 {
+			app.EditSelIndex--
+		} else {
+			app.EditSelIndex = n - 1
+		}
+	case DOWN:
+		if n == 0 {
+			return
+		}
+		if app.EditGrabbed {
+			if app.EditSelIndex < n-1 {
+				i := app.EditSelIndex
+				app.EditTracks[i+1], app.EditTracks[i] = app.EditTracks[i], app.EditTracks[i+1]
+				app.EditSelIndex++
+				app.EditDirty = true
+			}
+		} else if app.EditSelIndex < n-1 {
+			app.EditSelIndex++
+		} else {
+			app.EditSelIndex = 0
+		}
+	case A:
+		if n > 0 {
+			app.EditGrabbed = !app.EditGrabbed
+		}
+	case X:
+		if n == 0 {
+			return
+		}
+		i := app.EditSelIndex
+		app.EditTracks = append(app.EditTracks[:i], app.EditTracks[i+1:]...)
+		if app.EditSelIndex >= len(app.EditTracks) && app.EditSelIndex > 0 {
+			app.EditSelIndex--
+		}
+		app.EditGrabbed = false
+		app.EditDirty = true
+	case B, LEFT, MENU:
+		app.closePlaylistEditor()
+		return
+	default:
+		return
+	}
+
+	app.drawCurrentScreen()
+}
+
+// drawPlaylistEditScreen renders the playlist editor list
+func (app *MiyooPod) drawPlaylistEditScreen() {
+	dc := app.DC
+	dc.SetHexColor(app.CurrentTheme.BG)
+	dc.Clear()
+
+	title := "Edit Playlist"
+	if app.EditPlaylist != nil {
+		title = app.EditPlaylist.Name
+	}
+	if app.EditDirty {
+		title += " *"
+	}
+	app.drawHeader(title)
+
+	total := len(app.EditTracks)
+	if total == 0 {
+		dc.SetFontFace(app.FontMenu)
+		dc.SetHexColor(app.CurrentTheme.Dim)
+		dc.DrawStringAnchored("Playlist is empty", SCREEN_WIDTH/2, SCREEN_HEIGHT/2, 0.5, 0.5)
+		return
+	}
+
+	// Keep selection visible
+	if app.EditSelIndex < app.EditScrollOff {
+		app.EditScrollOff = app.EditSelIndex
+	}
+	if app.EditSelIndex >= app.EditScrollOff+VISIBLE_ITEMS {
+		app.EditScrollOff = app.EditSelIndex - VISIBLE_ITEMS + 1
+	}
+
+	y := MENU_TOP_Y
+	for i := app.EditScrollOff; i < total && i < app.EditScrollOff+VISIBLE_ITEMS; i++ {
+		track := app.EditTracks[i]
+		selected := i == app.EditSelIndex
+
+		if selected {
+			if app.EditGrabbed {
+				dc.SetHexColor(app.CurrentTheme.Accent)
+			} else {
+				dc.SetHexColor(app.CurrentTheme.SelBG)
+			}
+			dc.DrawRectangle(0, float64(y), SCREEN_WIDTH, MENU_ITEM_HEIGHT)
+			dc.Fill()
+		}
+
+		textY := float64(y) + float64(MENU_ITEM_HEIGHT)/2
+
+		dc.SetFontFace(app.FontMenu)
+		if selected {
+			dc.SetHexColor(app.CurrentTheme.SelTxt)
+		} else {
+			dc.SetHexColor(app.CurrentTheme.ItemTxt)
+		}
+		label := fmt.Sprintf("%d. %s", i+1, track.Title)
+		maxWidth := float64(SCREEN_WIDTH - MENU_LEFT_PAD - MENU_RIGHT_PAD - 160)
+		dc.DrawStringAnchored(app.truncateText(label, maxWidth, app.FontMenu), float64(MENU_LEFT_PAD), textY, 0, 0.5)
+
+		dc.SetFontFace(app.FontSmall)
+		if selected {
+			dc.SetHexColor(app.CurrentTheme.SelTxt)
+		} else {
+			dc.SetHexColor(app.CurrentTheme.Dim)
+		}
+		artistText := app.truncateText(track.Artist, 150, app.FontSmall)
+		dc.DrawStringAnchored(artistText, float64(SCREEN_WIDTH-MENU_RIGHT_PAD), textY, 1, 0.5)
+
+		y += MENU_ITEM_HEIGHT
+	}
+
+	app.drawScrollBar(total, app.EditScrollOff, VISIBLE_ITEMS)
+}
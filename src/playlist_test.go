@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParseExtInf(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want playlistEntry
+	}{
+		{"215,Artist - Title", playlistEntry{Duration: 215, Artist: "Artist", Title: "Title"}},
+		{"215,Title Only", playlistEntry{Duration: 215, Title: "Title Only"}},
+		{"-1,Artist - Title", playlistEntry{Artist: "Artist", Title: "Title"}},
+		{"301,Crosby, Stills & Nash - Helplessly Hoping, Live", playlistEntry{Duration: 301, Title: "Helplessly Hoping, Live", Artist: "Crosby, Stills & Nash"}},
+		{"180,Suite No. 1, Prelude", playlistEntry{Duration: 180, Title: "Suite No. 1, Prelude"}},
+		{`180 tvg-name="Live, 1972" tvg-id="x",Band - Song, Part 2`, playlistEntry{Duration: 180, Artist: "Band", Title: "Song, Part 2"}},
+		{"not a number,Title", playlistEntry{Title: "Title"}},
+		{"120", playlistEntry{Duration: 120}},
+	} {
+		if got := parseExtInf(tc.in); got != tc.want {
+			t.Errorf("parseExtInf(%q) = %+v, want %+v", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseM3U(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		in       string
+		wantName string
+		want     string
+	}{
+		{"plain", "Artist/Album/01.mp3\nArtist/Album/02.mp3\n", "",
+			"[{Artist/Album/01.mp3 false   0} {Artist/Album/02.mp3 false   0}]"},
+		{"extended with crlf", "#EXTM3U\r\n#PLAYLIST:Road Trip\r\n#EXTINF:215,Artist - Title, Reprise\r\n01.mp3\r\n\r\n#EXTINF:-1,Second\r\n02.mp3\r\n", "Road Trip",
+			"[{01.mp3 false Title, Reprise Artist 215} {02.mp3 false Second  0}]"},
+		{"extinf applies to the next entry only", "#EXTINF:100,First\n# comment\n01.mp3\n02.mp3\n", "",
+			"[{01.mp3 false First  100} {02.mp3 false   0}]"},
+		{"windows drive letters", "C:\\Music\\Artist\\01.mp3\n", "",
+			`[{C:\Music\Artist\01.mp3 false   0}]`},
+	} {
+		name, entries := parseM3U([]byte(tc.in))
+		if name != tc.wantName || fmt.Sprint(entries) != tc.want {
+			t.Errorf("%s: name %q, entries %v\nwant name %q, entries %s", tc.name, name, entries, tc.wantName, tc.want)
+		}
+	}
+}
+
+func TestParsePLS(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"complete", "[playlist]\nFile1=01.mp3\nTitle1=One\nLength1=60\nFile2=02.mp3\nNumberOfEntries=2\nVersion=2\n",
+			"[{01.mp3 false One  60} {02.mp3 false   0}]"},
+		{"missing NumberOfEntries", "[playlist]\r\nFile1=01.mp3\r\nFile2=02.mp3\r\nFile3=03.mp3\r\n",
+			"[{01.mp3 false   0} {02.mp3 false   0} {03.mp3 false   0}]"},
+		{"out of order and gaps", "[playlist]\nfile10=10.mp3\nFile2=02.mp3\nTitle5=No file\nLength2=-1\n",
+			"[{02.mp3 false   0} {10.mp3 false   0}]"},
+		{"windows drive letters", "[playlist]\nFile1=D:\\Music\\Song.mp3\n",
+			`[{D:\Music\Song.mp3 false   0}]`},
+	} {
+		if got := fmt.Sprint(parsePLS([]byte(tc.in))); got != tc.want {
+			t.Errorf("%s: entries %s\nwant %s", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestParseXSPF(t *testing.T) {
+	name, entries, err := parseXSPF([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<playlist version="1" xmlns="http://xspf.org/ns/0/">
+  <title>Mix</title>
+  <trackList>
+    <track>
+      <location>file:///mnt/SDCARD/Media/Music/Caf%C3%A9%20Tacvba/01%20Song%2B1.mp3</location>
+      <title>Song+1</title>
+      <creator>Café Tacvba</creator>
+      <duration>215500</duration>
+    </track>
+    <track><location> </location></track>
+    <track><location>Artist/02.mp3</location></track>
+  </trackList>
+</playlist>`))
+	if err != nil {
+		t.Fatalf("parseXSPF: %v", err)
+	}
+	want := "[{file:///mnt/SDCARD/Media/Music/Caf%C3%A9%20Tacvba/01%20Song%2B1.mp3 true Song+1 Café Tacvba 215.5} {Artist/02.mp3 true   0}]"
+	if name != "Mix" || fmt.Sprint(entries) != want {
+		t.Errorf("name %q, entries %v\nwant %s", name, entries, want)
+	}
+
+	if _, _, err := parseXSPF([]byte("<playlist><trackList>")); err == nil {
+		t.Errorf("truncated XSPF parsed without error")
+	}
+}
+
+func TestNormalizePlaylistPath(t *testing.T) {
+	base := "/mnt/SDCARD/Media/Music/Playlists"
+	for _, tc := range []struct {
+		location string
+		uri      bool
+		want     string
+	}{
+		{"../Artist/01.mp3", false, "/mnt/SDCARD/Media/Music/Artist/01.mp3"},
+		{"/mnt/SDCARD/Media/Music/Artist/01.mp3", false, "/mnt/SDCARD/Media/Music/Artist/01.mp3"},
+		{"..\\Artist\\01.mp3", false, "/mnt/SDCARD/Media/Music/Artist/01.mp3"},
+		// A literal % in a plain path isn't an escape
+		{"100% Hits/01.mp3", false, "/mnt/SDCARD/Media/Music/Playlists/100% Hits/01.mp3"},
+		{"file:///mnt/SDCARD/Media/Music/Caf%C3%A9/01%20Song.mp3", true, "/mnt/SDCARD/Media/Music/Café/01 Song.mp3"},
+		{"FILE://localhost/mnt/SDCARD/Media/Music/A%26B/01.mp3", false, "/mnt/SDCARD/Media/Music/A&B/01.mp3"},
+		{"Caf%C3%A9/01.mp3", true, "/mnt/SDCARD/Media/Music/Playlists/Café/01.mp3"},
+		{"C:\\Users\\me\\Music\\Artist\\01.mp3", false, "C:/Users/me/Music/Artist/01.mp3"},
+		{"file:///C:/Users/me/Music/Sigur%20R%C3%B3s/01.mp3", true, "C:/Users/me/Music/Sigur Rós/01.mp3"},
+		{"d:/Music/../Music/01.mp3", false, "d:/Music/01.mp3"},
+	} {
+		if got := normalizePlaylistPath(tc.location, tc.uri, base); got != tc.want {
+			t.Errorf("normalizePlaylistPath(%q, %v) = %q, want %q", tc.location, tc.uri, got, tc.want)
+		}
+	}
+}
+
+func TestPlaylistResolver(t *testing.T) {
+	lib := &Library{TracksByPath: make(map[string]*Track)}
+	for _, path := range []string{
+		"/music/Artist A/Album/01 Song.mp3",
+		"/music/Artist B/Album/01 Song.mp3",
+		"/music/Artist B/Other/02 Tune.mp3",
+	} {
+		track := &Track{Path: path}
+		lib.Tracks = append(lib.Tracks, track)
+		lib.TracksByPath[path] = track
+	}
+	res := newPlaylistResolver(lib)
+
+	for _, tc := range []struct {
+		path string
+		want string // Resolved track path, "" for none
+	}{
+		{"/music/Artist A/Album/01 Song.mp3", "/music/Artist A/Album/01 Song.mp3"},
+		{"/MUSIC/artist b/album/01 song.mp3", "/music/Artist B/Album/01 Song.mp3"},
+		{"C:/Users/me/Music/Artist B/Other/02 Tune.mp3", "/music/Artist B/Other/02 Tune.mp3"},
+		{"C:/Users/me/Music/Artist A/Album/01 Song.mp3", "/music/Artist A/Album/01 Song.mp3"},
+		// Both artists have an Album/01 Song.mp3, so this matches neither
+		{"C:/Music/Album/01 Song.mp3", ""},
+		{"/elsewhere/01 Song.mp3", ""},
+		{"/music/Artist B/Other/03 Missing.mp3", ""},
+	} {
+		got := ""
+		if track := res.resolve(tc.path); track != nil {
+			got = track.Path
+		}
+		if got != tc.want {
+			t.Errorf("resolve(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -73,7 +74,7 @@ type OTLPLogsPayload struct {
 
 type PostHogClient struct {
 	Token   string
-	Enabled bool
+	Enabled atomic.Bool // Toggled from settings, read by every logMsg caller
 }
 
 var posthogClient *PostHogClient
@@ -84,12 +85,10 @@ func (app *MiyooPod) initSentry() {
 		return // Silently skip if no token
 	}
 
-	posthogClient = &PostHogClient{
-		Token:   POSTHOG_TOKEN,
-		Enabled: app.SentryEnabled, // Reusing existing setting
-	}
+	posthogClient = &PostHogClient{Token: POSTHOG_TOKEN}
+	posthogClient.Enabled.Store(app.SentryEnabled) // Reusing existing setting
 
-	if posthogClient.Enabled {
+	if posthogClient.Enabled.Load() {
 		logMsg("INFO: PostHog client initialized and enabled")
 	} else {
 		logMsg("INFO: PostHog client initialized but disabled")
@@ -99,7 +98,7 @@ func (app *MiyooPod) initSentry() {
 // captureEvent sends logs/events to PostHog (fire and forget)
 // INFO → OTLP logs, ERROR/WARNING → Error tracking API
 func captureEvent(level, message string, extra map[string]interface{}) {
-	if posthogClient == nil || !posthogClient.Enabled || POSTHOG_TOKEN == "" || globalApp == nil {
+	if posthogClient == nil || !posthogClient.Enabled.Load() || POSTHOG_TOKEN == "" || globalApp == nil {
 		return
 	}
 
@@ -115,14 +114,14 @@ func captureEvent(level, message string, extra map[string]interface{}) {
 		if level == "info" {
 			// Send INFO logs to OTLP for analytics
 			if err := sendOTLPLog(level, message, extra); err != nil {
-				if globalApp.LocalLogsEnabled {
+				if globalApp.LocalLogsEnabled.Load() {
 					logFile.WriteString(fmt.Sprintf("[POSTHOG] OTLP failed: %v\n", err))
 				}
 			}
 		} else if level == "error" || level == "warning" {
 			// Send ERROR/WARNING to error tracking API
 			if err := sendErrorToPostHog(level, message, extra); err != nil {
-				if globalApp.LocalLogsEnabled {
+				if globalApp.LocalLogsEnabled.Load() {
 					logFile.WriteString(fmt.Sprintf("[POSTHOG] Error tracking failed: %v\n", err))
 				}
 			}
@@ -333,7 +332,7 @@ func sendErrorToPostHog(level, message string, extra map[string]interface{}) err
 // Used during panic recovery and signal handling — must complete before process exits.
 // Returns error for logging but the caller should not depend on success.
 func sendCrashReport(crashType, message, stackTrace string) {
-	if posthogClient == nil || !posthogClient.Enabled || POSTHOG_TOKEN == "" {
+	if posthogClient == nil || !posthogClient.Enabled.Load() || POSTHOG_TOKEN == "" {
 		return
 	}
 
@@ -499,7 +498,7 @@ func CaptureInfo(message string, extra map[string]interface{}) {
 
 // trackEvent sends a custom event to PostHog for product analytics
 func trackEvent(eventName string, properties map[string]interface{}) error {
-	if posthogClient == nil || !posthogClient.Enabled || POSTHOG_TOKEN == "" || globalApp == nil {
+	if posthogClient == nil || !posthogClient.Enabled.Load() || POSTHOG_TOKEN == "" || globalApp == nil {
 		return nil
 	}
 
@@ -565,7 +564,7 @@ func trackEvent(eventName string, properties map[string]interface{}) error {
 // Screens: menu, now_playing, queue
 // For menu screen, pass the menu title (e.g., "Artists", "Albums", "Playlists")
 func TrackPageView(screenName string, properties map[string]interface{}) {
-	if posthogClient == nil || !posthogClient.Enabled {
+	if posthogClient == nil || !posthogClient.Enabled.Load() {
 		return
 	}
 
@@ -584,7 +583,7 @@ func TrackPageView(screenName string, properties map[string]interface{}) {
 		properties["screen_name"] = screenName
 
 		if err := trackEvent("$pageview", properties); err != nil {
-			if globalApp.LocalLogsEnabled {
+			if globalApp.LocalLogsEnabled.Load() {
 				logFile.WriteString(fmt.Sprintf("[POSTHOG] TrackPageView failed: %v\n", err))
 			}
 		}
@@ -593,10 +592,25 @@ func TrackPageView(screenName string, properties map[string]interface{}) {
 
 // TrackSongPlayed tracks music playback with full metadata
 func TrackSongPlayed(track *Track) {
-	if posthogClient == nil || !posthogClient.Enabled || track == nil {
+	if posthogClient == nil || !posthogClient.Enabled.Load() || track == nil {
 		return
 	}
 
+	properties := map[string]interface{}{
+		"artist":    track.Artist,
+		"title":     track.Title,
+		"album":     track.Album,
+		"duration":  track.Duration,
+		"file_path": track.Path,
+	}
+
+	// Add playback context if available (read here, on the main loop)
+	if globalApp != nil {
+		properties["shuffle_enabled"] = globalApp.Queue.Shuffle
+		properties["repeat_mode"] = globalApp.Queue.Repeat.String()
+		properties["queue_size"] = len(globalApp.Queue.Tracks)
+	}
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -604,23 +618,8 @@ func TrackSongPlayed(track *Track) {
 			}
 		}()
 
-		properties := map[string]interface{}{
-			"artist":    track.Artist,
-			"title":     track.Title,
-			"album":     track.Album,
-			"duration":  track.Duration,
-			"file_path": track.Path,
-		}
-
-		// Add playback context if available
-		if globalApp != nil {
-			properties["shuffle_enabled"] = globalApp.Queue.Shuffle
-			properties["repeat_mode"] = globalApp.Queue.Repeat.String()
-			properties["queue_size"] = len(globalApp.Queue.Tracks)
-		}
-
 		if err := trackEvent("song_played", properties); err != nil {
-			if globalApp.LocalLogsEnabled {
+			if globalApp.LocalLogsEnabled.Load() {
 				logFile.WriteString(fmt.Sprintf("[POSTHOG] TrackSongPlayed failed: %v\n", err))
 			}
 		}
@@ -630,7 +629,7 @@ func TrackSongPlayed(track *Track) {
 // TrackAction tracks user actions like theme changes, shuffle toggles, etc.
 // The action becomes the event name (e.g., "theme_changed", "screen_locked")
 func TrackAction(action string, properties map[string]interface{}) {
-	if posthogClient == nil || !posthogClient.Enabled {
+	if posthogClient == nil || !posthogClient.Enabled.Load() {
 		return
 	}
 
@@ -646,7 +645,7 @@ func TrackAction(action string, properties map[string]interface{}) {
 		}
 
 		if err := trackEvent(action, properties); err != nil {
-			if globalApp.LocalLogsEnabled {
+			if globalApp.LocalLogsEnabled.Load() {
 				logFile.WriteString(fmt.Sprintf("[POSTHOG] TrackAction failed: %v\n", err))
 			}
 		}
@@ -655,10 +654,21 @@ func TrackAction(action string, properties map[string]interface{}) {
 
 // TrackAppLifecycle tracks app opened and closed events
 func TrackAppLifecycle(event string, properties map[string]interface{}) {
-	if posthogClient == nil || !posthogClient.Enabled {
+	if posthogClient == nil || !posthogClient.Enabled.Load() {
 		return
 	}
 
+	if properties == nil {
+		properties = make(map[string]interface{})
+	}
+
+	// Add library stats if available (read here, on the main loop)
+	if globalApp != nil && globalApp.Library != nil {
+		properties["library_tracks"] = len(globalApp.Library.Tracks)
+		properties["library_artists"] = len(globalApp.Library.Artists)
+		properties["library_albums"] = len(globalApp.Library.Albums)
+	}
+
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -666,19 +676,8 @@ func TrackAppLifecycle(event string, properties map[string]interface{}) {
 			}
 		}()
 
-		if properties == nil {
-			properties = make(map[string]interface{})
-		}
-
-		// Add library stats if available
-		if globalApp != nil && globalApp.Library != nil {
-			properties["library_tracks"] = len(globalApp.Library.Tracks)
-			properties["library_artists"] = len(globalApp.Library.Artists)
-			properties["library_albums"] = len(globalApp.Library.Albums)
-		}
-
 		if err := trackEvent(event, properties); err != nil {
-			if globalApp.LocalLogsEnabled {
+			if globalApp.LocalLogsEnabled.Load() {
 				logFile.WriteString(fmt.Sprintf("[POSTHOG] TrackAppLifecycle failed: %v\n", err))
 			}
 		}
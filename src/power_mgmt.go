@@ -24,61 +24,58 @@ func (app *MiyooPod) startInactivityMonitor() {
 	ticker := time.NewTicker(30 * time.Second) // Check every 30 seconds
 	defer ticker.Stop()
 
-	for {
-		select {
-		case <-ticker.C:
-			// Skip if auto-lock is disabled or already locked
-			if app.AutoLockMinutes <= 0 || app.Locked {
-				continue
-			}
+	for range ticker.C {
+		if !app.Running.Load() {
+			return
+		}
+		app.post(app.checkInactivity)
+	}
+}
 
-			// Check if inactive for specified duration
-			inactiveDuration := time.Since(app.LastActivityTime)
-			autoLockDuration := time.Duration(app.AutoLockMinutes) * time.Minute
+// checkInactivity locks the screen once the auto-lock period has passed
+// without input. Runs on the main loop.
+func (app *MiyooPod) checkInactivity() {
+	// Skip if auto-lock is disabled or already locked
+	if app.AutoLockMinutes <= 0 || app.Locked {
+		return
+	}
 
-			if inactiveDuration >= autoLockDuration {
-				logMsg(fmt.Sprintf("INFO: Auto-lock triggered after %v of inactivity", inactiveDuration))
-				app.toggleLock()
-			}
-		}
+	// Check if inactive for specified duration
+	inactiveDuration := time.Since(app.LastActivityTime)
+	autoLockDuration := time.Duration(app.AutoLockMinutes) * time.Minute
+
+	if inactiveDuration >= autoLockDuration {
+		logMsg(fmt.Sprintf("INFO: Auto-lock triggered after %v of inactivity", inactiveDuration))
+		app.toggleLock()
 	}
 }
 
-// monitorPowerButtonHold monitors power button hold duration and forces quit if held 5+ seconds
+// monitorPowerButtonHold forces quit if the power button is held 5+ seconds.
+// Runs on the main loop: the hold is checked by a timer posting back to it.
 func (app *MiyooPod) monitorPowerButtonHold() {
 	startTime := app.PowerButtonPressTime
 
-	// Check every 100ms
-	ticker := time.NewTicker(100 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ticker.C:
-			// If button was released, stop monitoring
-			if !app.PowerButtonPressed {
+	time.AfterFunc(5*time.Second, func() {
+		app.post(func() {
+			// Released (or released and pressed again) in the meantime
+			if !app.PowerButtonPressed || app.PowerButtonPressTime != startTime {
 				return
 			}
 
 			holdDuration := time.Since(startTime)
+			logMsg("INFO: Power button held for 5+ seconds - forcing shutdown")
 
-			// Force shutdown after 5 seconds
-			if holdDuration >= 5*time.Second {
-				logMsg("INFO: Power button held for 5+ seconds - forcing shutdown")
-
-				// Restore brightness before exiting
-				restoreBrightness()
+			// Restore brightness before exiting
+			restoreBrightness()
 
-				TrackAction("force_shutdown", map[string]interface{}{
-					"hold_duration": holdDuration.Seconds(),
-				})
+			TrackAction("force_shutdown", map[string]interface{}{
+				"hold_duration": holdDuration.Seconds(),
+			})
 
-				// Set flag to exit cleanly
-				app.Running = false
-				return
-			}
-		}
-	}
+			// Set flag to exit cleanly
+			app.Running.Store(false)
+		})
+	})
 }
 
 // resetInactivityTimer resets the inactivity timer (called on user interaction)
@@ -105,10 +102,17 @@ func (app *MiyooPod) peekScreen() {
 	app.drawCurrentScreen()
 
 	// Set timer to dim screen after 3 seconds
-	app.ScreenPeekTimer = time.AfterFunc(3*time.Second, func() {
-		app.dimScreen()
-		app.ScreenPeekActive = false
+	var timer *time.Timer
+	timer = time.AfterFunc(3*time.Second, func() {
+		app.post(func() {
+			if app.ScreenPeekTimer != timer {
+				return // A newer peek restarted the timer
+			}
+			app.dimScreen()
+			app.ScreenPeekActive = false
+		})
 	})
+	app.ScreenPeekTimer = timer
 }
 
 // dimScreen reduces brightness to minimum (for locked state)
@@ -167,6 +167,11 @@ func (app *MiyooPod) handleQueueKey(key Key) {
 	case SELECT:
 		// Clear all except currently playing track
 		app.clearQueueExceptCurrent()
+	case Y:
+		// Add selected track to a playlist
+		if track := app.queueTrackAt(app.QueueSelectedIndex); track != nil {
+			app.openPlaylistChooser([]*Track{track}, ScreenQueue)
+		}
 	}
 }
 
@@ -209,6 +214,7 @@ func (app *MiyooPod) clearQueueExceptCurrent() {
 	app.Queue.Shuffle = false // Disable shuffle since only one track remains
 	app.QueueSelectedIndex = 0
 	app.QueueScrollOffset = 0
+	app.preloadNextTrack()
 	app.NPCacheDirty = true
 	app.drawCurrentScreen()
 }
@@ -219,7 +225,8 @@ func (app *MiyooPod) clearQueue() {
 		return
 	}
 
-	audioStop()
+	app.Audio.Stop()
+	app.forgetPreload()
 	app.Queue.Tracks = nil
 	app.Queue.CurrentIndex = 0
 	app.Queue.ShuffleOrder = nil
@@ -313,6 +320,7 @@ func (app *MiyooPod) removeFromQueueAtPlaybackPosition(playbackPos int) {
 		app.QueueSelectedIndex = 0
 	}
 
+	app.preloadNextTrack()
 	app.NPCacheDirty = true
 	app.drawCurrentScreen()
 }
@@ -372,6 +380,7 @@ func (app *MiyooPod) removeFromQueue(idx int) {
 		app.QueueSelectedIndex = 0
 	}
 
+	app.preloadNextTrack()
 	app.NPCacheDirty = true
 	app.drawCurrentScreen()
 }
@@ -423,6 +432,7 @@ func (app *MiyooPod) addToQueue(track *Track) {
 	}
 
 	logMsg(fmt.Sprintf("Added to queue: %s - %s", track.Artist, track.Title))
+	app.preloadNextTrack()
 }
 
 // addTracksToQueue appends multiple tracks to the queue
@@ -470,4 +480,5 @@ func (app *MiyooPod) addTracksToQueue(tracks []*Track) {
 	}
 
 	logMsg(fmt.Sprintf("Added %d tracks to queue", len(tracks)))
+	app.preloadNextTrack()
 }
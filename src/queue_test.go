@@ -0,0 +1,90 @@
+package main
+
+import "testing"
+
+func TestRemoveBeforeCurrent(t *testing.T) {
+	app, sim := newTestApp(t, 5)
+	playQueue(t, app, 2)
+
+	app.removeFromQueue(0)
+
+	if app.Queue.CurrentIndex != 1 || currentPath(app) != trackPath(3) {
+		t.Errorf("index %d track %q, want 1 and %q", app.Queue.CurrentIndex, currentPath(app), trackPath(3))
+	}
+	if loads := sim.Loads(); len(loads) != 1 {
+		t.Errorf("removing another track reloaded audio: %v", loads)
+	}
+	waitForPreload(t, sim, trackPath(4))
+}
+
+func TestRemoveCurrentPlaysNext(t *testing.T) {
+	app, sim := newTestApp(t, 5)
+	playQueue(t, app, 2)
+
+	app.removeFromQueue(2)
+
+	if len(app.Queue.Tracks) != 4 {
+		t.Fatalf("queue has %d tracks, want 4", len(app.Queue.Tracks))
+	}
+	if sim.Current() != trackPath(4) || app.Playing.Track.Path != trackPath(4) {
+		t.Errorf("playing %q, want %q", sim.Current(), trackPath(4))
+	}
+}
+
+func TestRemoveCurrentAtEnd(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 2)
+
+	app.removeFromQueue(2)
+
+	if app.Queue.CurrentIndex != 1 || sim.Current() != trackPath(2) {
+		t.Errorf("index %d playing %q, want 1 and %q", app.Queue.CurrentIndex, sim.Current(), trackPath(2))
+	}
+}
+
+func TestRemoveWithShuffle(t *testing.T) {
+	app, sim := newTestApp(t, 6)
+	app.Queue.Shuffle = true
+	playQueue(t, app, 3)
+	playing := currentPath(app)
+
+	// Remove the track two places ahead in shuffle order
+	removed := app.queueTrackAt(2)
+	app.removeFromQueueAtPlaybackPosition(2)
+
+	if got := currentPath(app); got != playing {
+		t.Errorf("current track changed from %q to %q", playing, got)
+	}
+	if len(sim.Loads()) != 1 {
+		t.Errorf("removing another track reloaded audio: %v", sim.Loads())
+	}
+
+	order := app.Queue.ShuffleOrder
+	if len(order) != 5 {
+		t.Fatalf("shuffle order %v, want 5 entries", order)
+	}
+	seen := make(map[int]bool)
+	for _, idx := range order {
+		if idx < 0 || idx >= 5 || seen[idx] {
+			t.Fatalf("shuffle order %v is not a permutation", order)
+		}
+		seen[idx] = true
+		if app.Queue.Tracks[idx] == removed {
+			t.Errorf("removed track still in shuffle order")
+		}
+	}
+}
+
+func TestRemoveOnlyTrackClearsQueue(t *testing.T) {
+	app, sim := newTestApp(t, 1)
+	playQueue(t, app, 0)
+
+	app.removeFromQueue(0)
+
+	if len(app.Queue.Tracks) != 0 || app.Playing.State != StateStopped {
+		t.Errorf("queue %d tracks, state %v; want empty and stopped", len(app.Queue.Tracks), app.Playing.State)
+	}
+	if sim.Current() != "" {
+		t.Errorf("backend still playing %q", sim.Current())
+	}
+}
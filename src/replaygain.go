@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/dhowden/tag"
+)
+
+// ReplayGainMode selects which loudness tag is applied during playback
+type ReplayGainMode int
+
+const (
+	ReplayGainOff ReplayGainMode = iota
+	ReplayGainTrack
+	ReplayGainAlbum
+)
+
+func (m ReplayGainMode) String() string {
+	switch m {
+	case ReplayGainTrack:
+		return "track"
+	case ReplayGainAlbum:
+		return "album"
+	default:
+		return "off"
+	}
+}
+
+// parseReplayGainMode is the inverse of ReplayGainMode.String
+func parseReplayGainMode(s string) ReplayGainMode {
+	switch s {
+	case "track":
+		return ReplayGainTrack
+	case "album":
+		return ReplayGainAlbum
+	default:
+		return ReplayGainOff
+	}
+}
+
+// replayGainPreamps are the preamp steps offered in Settings (dB)
+var replayGainPreamps = []float64{-6, -3, 0, 3, 6}
+
+// readReplayGain fills the track's gain/peak fields from ReplayGain tags
+// (ID3 TXXX, Vorbis comments, MP4 freeform atoms). iTunes SoundCheck
+// (iTunNORM) is used as the track gain when no ReplayGain tag is present.
+func readReplayGain(track *Track, m tag.Metadata) {
+	soundCheck := ""
+
+	for key, value := range m.Raw() {
+		name, text := key, ""
+		switch v := value.(type) {
+		case *tag.Comm:
+			// ID3 TXXX/COMM: the tag name is the frame description
+			name, text = v.Description, v.Text
+		case string:
+			text = v
+		case []string:
+			if len(v) > 0 {
+				text = v[0]
+			}
+		default:
+			continue
+		}
+
+		// MP4 freeform atoms may be keyed "----:com.apple.iTunes:NAME"
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			name = name[i+1:]
+		}
+
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "replaygain_track_gain":
+			track.TrackGain, _ = parseGainDB(text)
+		case "replaygain_track_peak":
+			track.TrackPeak, _ = strconv.ParseFloat(strings.TrimSpace(text), 64)
+		case "replaygain_album_gain":
+			track.AlbumGain, _ = parseGainDB(text)
+		case "replaygain_album_peak":
+			track.AlbumPeak, _ = strconv.ParseFloat(strings.TrimSpace(text), 64)
+		case "itunnorm":
+			soundCheck = text
+		}
+	}
+
+	if track.TrackGain == 0 && soundCheck != "" {
+		if gain, ok := parseSoundCheck(soundCheck); ok {
+			track.TrackGain = gain
+		}
+	}
+}
+
+// parseGainDB parses a ReplayGain value such as "-6.52 dB"
+func parseGainDB(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSpace(strings.TrimSuffix(strings.TrimSuffix(s, "dB"), "DB"))
+	gain, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return gain, true
+}
+
+// parseSoundCheck converts an iTunNORM value to a gain in dB. The first two
+// hex words are the left/right adjustment relative to a 1/1000 reference.
+func parseSoundCheck(s string) (float64, bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return 0, false
+	}
+
+	peak := uint64(0)
+	for _, f := range fields[:2] {
+		v, err := strconv.ParseUint(f, 16, 32)
+		if err != nil {
+			return 0, false
+		}
+		if v > peak {
+			peak = v
+		}
+	}
+	if peak == 0 {
+		return 0, false
+	}
+
+	return -10 * math.Log10(float64(peak)/1000), true
+}
+
+// replayGainFactor returns the linear gain to apply to a track for the current
+// mode and preamp. Untagged tracks play unchanged; the factor is capped so the
+// tagged peak never clips.
+func (app *MiyooPod) replayGainFactor(track *Track) float64 {
+	if track == nil || app.ReplayGainMode == ReplayGainOff {
+		return 1
+	}
+
+	gain, peak := track.TrackGain, track.TrackPeak
+	if app.ReplayGainMode == ReplayGainAlbum && track.AlbumGain != 0 {
+		gain, peak = track.AlbumGain, track.AlbumPeak
+	}
+	if gain == 0 && peak == 0 {
+		return 1
+	}
+
+	factor := math.Pow(10, (gain+app.ReplayGainPreamp)/20)
+	if peak > 0 && factor*peak > 1 {
+		factor = 1 / peak
+	}
+	return factor
+}
+
+// applyReplayGain pushes the current settings to the playing and preloaded tracks
+func (app *MiyooPod) applyReplayGain() {
+	if app.Playing != nil && app.Playing.Track != nil {
+		app.Audio.SetGain(app.replayGainFactor(app.Playing.Track))
+	}
+	// Re-issue the preload so the next track picks up the new factor
+	app.forgetPreload()
+	app.preloadNextTrack()
+}
+
+// cycleReplayGainMode cycles Off -> Track -> Album -> Off
+func (app *MiyooPod) cycleReplayGainMode() {
+	switch app.ReplayGainMode {
+	case ReplayGainOff:
+		app.ReplayGainMode = ReplayGainTrack
+	case ReplayGainTrack:
+		app.ReplayGainMode = ReplayGainAlbum
+	default:
+		app.ReplayGainMode = ReplayGainOff
+	}
+
+	app.applyReplayGain()
+	TrackAction("replaygain_mode_changed", map[string]interface{}{"mode": app.ReplayGainMode.String()})
+	app.refreshSettingsMenu()
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save ReplayGain preference: %v", err))
+	}
+}
+
+// cycleReplayGainPreamp steps through replayGainPreamps
+func (app *MiyooPod) cycleReplayGainPreamp() {
+	next := replayGainPreamps[0]
+	for i, p := range replayGainPreamps {
+		if p == app.ReplayGainPreamp && i+1 < len(replayGainPreamps) {
+			next = replayGainPreamps[i+1]
+			break
+		}
+	}
+	app.ReplayGainPreamp = next
+
+	app.applyReplayGain()
+	app.refreshSettingsMenu()
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save preamp preference: %v", err))
+	}
+}
+
+// replayGainLabel returns the Settings label for the current mode
+func (app *MiyooPod) replayGainLabel() string {
+	switch app.ReplayGainMode {
+	case ReplayGainTrack:
+		return "ReplayGain: Track"
+	case ReplayGainAlbum:
+		return "ReplayGain: Album"
+	default:
+		return "ReplayGain: Off"
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/dhowden/tag"
+)
+
+// rawTags is a tag.Metadata that only answers Raw
+type rawTags struct {
+	tag.Metadata
+	raw map[string]interface{}
+}
+
+func (m rawTags) Raw() map[string]interface{} { return m.raw }
+
+func TestParseGainDB(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		{"+3.5 dB", 3.5, true},
+		{"-7.12dB", -7.12, true},
+		{" -6.52 DB ", -6.52, true},
+		{"0.00 dB", 0, true},
+		{"loud", 0, false},
+		{"", 0, false},
+	} {
+		got, ok := parseGainDB(tc.in)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("parseGainDB(%q) = %v, %v; want %v, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestParseSoundCheck(t *testing.T) {
+	for _, tc := range []struct {
+		in   string
+		want float64
+		ok   bool
+	}{
+		// The louder channel wins: 0x7D0 is twice the 1/1000 reference
+		{" 000003E8 000007D0 00002A3C 00002A3C 00009C40 00009C40 00007FFF 00007FFF 00000000 00000000", -3.0103, true},
+		{"000003E8 000003E8", 0, true},
+		{"000001F4 000001F4", 3.0103, true},
+		{"000003E8", 0, false},
+		{"00000000 00000000", 0, false},
+		{"XYZ 000003E8", 0, false},
+	} {
+		got, ok := parseSoundCheck(tc.in)
+		if math.Abs(got-tc.want) > 1e-4 || ok != tc.ok {
+			t.Errorf("parseSoundCheck(%q) = %.4f, %v; want %.4f, %v", tc.in, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestReadReplayGain(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		raw  map[string]interface{}
+		want Track
+	}{
+		{"id3 txxx", map[string]interface{}{
+			"TXXX":   &tag.Comm{Description: "REPLAYGAIN_TRACK_GAIN", Text: "-6.50 dB"},
+			"TXXX_0": &tag.Comm{Description: "replaygain_track_peak", Text: "0.988"},
+			"TXXX_1": &tag.Comm{Description: "REPLAYGAIN_ALBUM_GAIN", Text: "+1.25 dB"},
+			"TXXX_2": &tag.Comm{Description: "REPLAYGAIN_ALBUM_PEAK", Text: " 1.0 "},
+		}, Track{TrackGain: -6.5, TrackPeak: 0.988, AlbumGain: 1.25, AlbumPeak: 1}},
+		{"vorbis comments", map[string]interface{}{
+			"replaygain_track_gain": "-3.00 dB",
+			"replaygain_album_gain": []string{"-4.00 dB"},
+		}, Track{TrackGain: -3, AlbumGain: -4}},
+		{"mp4 freeform", map[string]interface{}{
+			"----:com.apple.iTunes:replaygain_track_gain": "+2.00 dB",
+		}, Track{TrackGain: 2}},
+		{"soundcheck only", map[string]interface{}{
+			"COMM": &tag.Comm{Description: "iTunNORM", Text: " 000007D0 000007D0"},
+		}, Track{TrackGain: -3.0103}},
+		{"replaygain beats soundcheck", map[string]interface{}{
+			"replaygain_track_gain": "-1.00 dB",
+			"iTunNORM":              " 000007D0 000007D0",
+		}, Track{TrackGain: -1}},
+		{"untagged", map[string]interface{}{"title": "Song", "track": 3}, Track{}},
+	} {
+		var got Track
+		readReplayGain(&got, rawTags{raw: tc.raw})
+		if math.Abs(got.TrackGain-tc.want.TrackGain) > 1e-4 || got.TrackPeak != tc.want.TrackPeak ||
+			got.AlbumGain != tc.want.AlbumGain || got.AlbumPeak != tc.want.AlbumPeak {
+			t.Errorf("%s: gain %.4f/%v, album %v/%v; want %.4f/%v, %v/%v", tc.name,
+				got.TrackGain, got.TrackPeak, got.AlbumGain, got.AlbumPeak,
+				tc.want.TrackGain, tc.want.TrackPeak, tc.want.AlbumGain, tc.want.AlbumPeak)
+		}
+	}
+}
+
+func TestReplayGainFactor(t *testing.T) {
+	tagged := &Track{TrackGain: -6, TrackPeak: 0.5, AlbumGain: 6, AlbumPeak: 0.8}
+	for _, tc := range []struct {
+		name   string
+		mode   ReplayGainMode
+		preamp float64
+		track  *Track
+		want   float64
+	}{
+		{"off", ReplayGainOff, 0, tagged, 1},
+		{"untagged", ReplayGainTrack, 6, &Track{}, 1},
+		{"no track", ReplayGainTrack, 0, nil, 1},
+		{"track gain", ReplayGainTrack, 0, tagged, math.Pow(10, -6.0/20)},
+		{"preamp", ReplayGainTrack, 3, tagged, math.Pow(10, -3.0/20)},
+		// +6 dB would push the 0.8 peak past full scale
+		{"album capped at peak", ReplayGainAlbum, 0, tagged, 1 / 0.8},
+		{"track peak cap", ReplayGainTrack, 18, tagged, 1 / 0.5},
+		{"album falls back to track", ReplayGainAlbum, 0, &Track{TrackGain: -6}, math.Pow(10, -6.0/20)},
+	} {
+		app := &MiyooPod{ReplayGainMode: tc.mode, ReplayGainPreamp: tc.preamp}
+		if got := app.replayGainFactor(tc.track); math.Abs(got-tc.want) > 1e-9 {
+			t.Errorf("%s: factor = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SCROBBLER_LOG_PATH is where Rockbox-compatible scrobble tools look for plays
+var SCROBBLER_LOG_PATH = "/mnt/SDCARD/.scrobbler.log"
+
+// LISTENBRAINZ_EXPORT_PATH receives the ListenBrainz JSON export
+var LISTENBRAINZ_EXPORT_PATH = "/mnt/SDCARD/listenbrainz_listens.json"
+
+// listenBrainzAPIURL is the submission endpoint base; tests point it at a local server
+var listenBrainzAPIURL = "https://api.listenbrainz.org"
+
+const (
+	// listenBrainzBatchSize is the most listens ListenBrainz accepts per request
+	listenBrainzBatchSize = 1000
+	// listenBrainzMinTimestamp is the earliest listened_at ListenBrainz accepts.
+	// Plays logged while the device clock was unset fall before it.
+	listenBrainzMinTimestamp = 1033430400
+)
+
+// errListenBrainzToken is returned when ListenBrainz rejects the user token
+var errListenBrainzToken = errors.New("invalid ListenBrainz token")
+
+// Scrobble is one qualifying play, as stored in .scrobbler.log
+type Scrobble struct {
+	Artist    string
+	Album     string
+	Title     string
+	TrackNum  int
+	Length    int    // Seconds
+	Rating    string // "L" listened, "S" skipped
+	Timestamp int64  // Unix time the play started
+}
+
+// scrobble appends a qualifying play of track to the scrobble log
+func (app *MiyooPod) scrobble(track *Track, started time.Time) {
+	s := Scrobble{
+		Artist:    track.Artist,
+		Album:     track.Album,
+		Title:     track.Title,
+		TrackNum:  track.TrackNum,
+		Length:    int(track.Duration + 0.5),
+		Rating:    "L",
+		Timestamp: started.Unix(),
+	}
+	if s.Artist == "" || s.Title == "" {
+		// Scrobble services reject listens without artist and title
+		return
+	}
+
+	if err := appendScrobble(SCROBBLER_LOG_PATH, s); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to write scrobble: %v", err))
+	}
+}
+
+// appendScrobble adds one entry to an AUDIOSCROBBLER/1.1 log, writing the
+// header first if the file is new
+func appendScrobble(path string, s Scrobble) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var b strings.Builder
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		b.WriteString("#AUDIOSCROBBLER/1.1\n")
+		b.WriteString("#TZ/UTC\n")
+		fmt.Fprintf(&b, "#CLIENT/MiyooPod %s\n", APP_VERSION)
+	}
+
+	trackNum := ""
+	if s.TrackNum > 0 {
+		trackNum = strconv.Itoa(s.TrackNum)
+	}
+	// artist, album, title, track number, length, rating, timestamp, MusicBrainz track id
+	fields := []string{
+		scrobbleField(s.Artist),
+		scrobbleField(s.Album),
+		scrobbleField(s.Title),
+		trackNum,
+		strconv.Itoa(s.Length),
+		s.Rating,
+		strconv.FormatInt(s.Timestamp, 10),
+		"",
+	}
+	b.WriteString(strings.Join(fields, "\t"))
+	b.WriteString("\n")
+
+	_, err = f.WriteString(b.String())
+	return err
+}
+
+// scrobbleField strips characters that would break the tab-separated format
+func scrobbleField(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// readScrobbleLog parses an AUDIOSCROBBLER/1.1 log, skipping header and malformed lines
+func readScrobbleLog(path string) ([]Scrobble, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	scrobbles, _ := parseScrobbleLog(data)
+	return scrobbles, nil
+}
+
+// parseScrobbleLog parses the entries of a scrobble log, along with the byte
+// offset just past each one
+func parseScrobbleLog(data []byte) ([]Scrobble, []int64) {
+	var scrobbles []Scrobble
+	var ends []int64
+	for offset := 0; offset < len(data); {
+		next := len(data)
+		if i := bytes.IndexByte(data[offset:], '\n'); i >= 0 {
+			next = offset + i + 1
+		}
+		line := strings.TrimRight(string(data[offset:next]), "\r\n")
+		offset = next
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		ts, err := strconv.ParseInt(fields[6], 10, 64)
+		if err != nil {
+			continue
+		}
+		trackNum, _ := strconv.Atoi(fields[3])
+		length, _ := strconv.Atoi(fields[4])
+
+		scrobbles = append(scrobbles, Scrobble{
+			Artist:    fields[0],
+			Album:     fields[1],
+			Title:     fields[2],
+			TrackNum:  trackNum,
+			Length:    length,
+			Rating:    fields[5],
+			Timestamp: ts,
+		})
+		ends = append(ends, int64(offset))
+	}
+	return scrobbles, ends
+}
+
+// ListenBrainz submission format (https://listenbrainz.readthedocs.io)
+type listenBrainzSubmission struct {
+	ListenType string               `json:"listen_type"`
+	Payload    []listenBrainzListen `json:"payload"`
+}
+
+type listenBrainzListen struct {
+	ListenedAt    int64                 `json:"listened_at"`
+	TrackMetadata listenBrainzTrackMeta `json:"track_metadata"`
+}
+
+type listenBrainzTrackMeta struct {
+	ArtistName     string                 `json:"artist_name"`
+	TrackName      string                 `json:"track_name"`
+	ReleaseName    string                 `json:"release_name,omitempty"`
+	AdditionalInfo map[string]interface{} `json:"additional_info,omitempty"`
+}
+
+// listenBrainzListens converts listened (not skipped) scrobbles into
+// ListenBrainz listens
+func listenBrainzListens(scrobbles []Scrobble) []listenBrainzListen {
+	var listens []listenBrainzListen
+	for _, s := range scrobbles {
+		if l, ok := listenBrainzListenFor(s); ok {
+			listens = append(listens, l)
+		}
+	}
+	return listens
+}
+
+// listenBrainzListenFor converts one scrobble, or reports false for skipped
+// plays and plays logged while the clock was unset
+func listenBrainzListenFor(s Scrobble) (listenBrainzListen, bool) {
+	if s.Rating != "L" || s.Timestamp < listenBrainzMinTimestamp {
+		return listenBrainzListen{}, false
+	}
+
+	info := map[string]interface{}{
+		"media_player":              "MiyooPod",
+		"submission_client":         "MiyooPod",
+		"submission_client_version": APP_VERSION,
+	}
+	if s.Length > 0 {
+		info["duration_ms"] = s.Length * 1000
+	}
+	if s.TrackNum > 0 {
+		info["tracknumber"] = s.TrackNum
+	}
+
+	return listenBrainzListen{
+		ListenedAt: s.Timestamp,
+		TrackMetadata: listenBrainzTrackMeta{
+			ArtistName:     s.Artist,
+			TrackName:      s.Title,
+			ReleaseName:    s.Album,
+			AdditionalInfo: info,
+		},
+	}, true
+}
+
+// exportListenBrainz writes every logged listen as a ListenBrainz import file
+func exportListenBrainz(logPath, exportPath string) (int, error) {
+	scrobbles, err := readScrobbleLog(logPath)
+	if err != nil {
+		return 0, err
+	}
+
+	listens := listenBrainzListens(scrobbles)
+	data, err := json.MarshalIndent(listenBrainzSubmission{ListenType: "import", Payload: listens}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.WriteFile(exportPath, data, 0644); err != nil {
+		return 0, err
+	}
+	return len(listens), nil
+}
+
+// submitListenBrainz posts listens to baseURL in batches, in order. It returns
+// how many were accepted, so a failure part way through can resume after them.
+func submitListenBrainz(client *http.Client, baseURL, token string, listens []listenBrainzListen) (int, error) {
+	submitted := 0
+
+	for start := 0; start < len(listens); start += listenBrainzBatchSize {
+		end := start + listenBrainzBatchSize
+		if end > len(listens) {
+			end = len(listens)
+		}
+		batch := listens[start:end]
+
+		listenType := "import"
+		if len(batch) == 1 {
+			listenType = "single"
+		}
+		body, err := json.Marshal(listenBrainzSubmission{ListenType: listenType, Payload: batch})
+		if err != nil {
+			return submitted, err
+		}
+
+		req, err := http.NewRequest("POST", strings.TrimRight(baseURL, "/")+"/1/submit-listens", bytes.NewReader(body))
+		if err != nil {
+			return submitted, err
+		}
+		req.Header.Set("Authorization", "Token "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return submitted, err
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusUnauthorized {
+			return submitted, errListenBrainzToken
+		}
+		if resp.StatusCode != 200 {
+			return submitted, fmt.Errorf("ListenBrainz returned %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		}
+
+		submitted += len(batch)
+	}
+
+	return submitted, nil
+}
+
+// submitScrobblesInBackground submits listens logged since the last
+// submission to ListenBrainz off the main loop, then records the progress and
+// calls done (if set) with the result on the main loop. It's a no-op without a
+// token; offline failures are logged and retried next time.
+func (app *MiyooPod) submitScrobblesInBackground(done func(n int, err error)) {
+	token, offset := app.ListenBrainzToken, app.ListenBrainzOffset
+	if token == "" {
+		if done != nil {
+			done(0, fmt.Errorf("no ListenBrainz token configured"))
+		}
+		return
+	}
+	go func() {
+		n, offset, err := submitPendingListens(token, offset)
+		app.post(func() {
+			app.markListensSubmitted(offset)
+			if done != nil {
+				done(n, err)
+			}
+		})
+	}()
+}
+
+// markListensSubmitted saves how far through the scrobble log has been
+// submitted, so those listens aren't sent again
+func (app *MiyooPod) markListensSubmitted(offset int64) {
+	if offset == app.ListenBrainzOffset {
+		return
+	}
+	app.ListenBrainzOffset = offset
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save ListenBrainz progress: %v", err))
+	}
+}
+
+// submitPendingListens submits the listens logged after offset, a byte offset
+// into the scrobble log, and returns how many were sent and the offset just
+// past the last one. Going by position in the log rather than by timestamp
+// keeps listens logged after the clock reset on boot from being skipped.
+func submitPendingListens(token string, offset int64) (int, int64, error) {
+	data, err := os.ReadFile(SCROBBLER_LOG_PATH)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, 0, nil
+		}
+		return 0, offset, err
+	}
+	// Leave a line without its line break, still being written, for next time
+	data = data[:bytes.LastIndexByte(data, '\n')+1]
+	// A log that's shorter than before, or doesn't break at the offset, was
+	// cleared or replaced (by a scrobbling tool, say): start over
+	if offset > int64(len(data)) || (offset > 0 && data[offset-1] != '\n') {
+		offset = 0
+	}
+
+	scrobbles, ends := parseScrobbleLog(data[offset:])
+	var listens []listenBrainzListen
+	var listenEnds []int64
+	for i, s := range scrobbles {
+		if l, ok := listenBrainzListenFor(s); ok {
+			listens = append(listens, l)
+			listenEnds = append(listenEnds, offset+ends[i])
+		}
+	}
+	if len(listens) == 0 {
+		return 0, int64(len(data)), nil
+	}
+
+	client := getInsecureHTTPClient(15 * time.Second)
+	n, err := submitListenBrainz(client, listenBrainzAPIURL, token, listens)
+	if err != nil {
+		logMsg(fmt.Sprintf("WARNING: ListenBrainz submit stopped after %d listens: %v", n, err))
+		if n > 0 {
+			offset = listenEnds[n-1]
+		}
+		return n, offset, err
+	}
+
+	logMsg(fmt.Sprintf("INFO: Submitted %d listens to ListenBrainz", n))
+	return n, int64(len(data)), nil
+}
+
+// manualExportScrobbles writes the ListenBrainz export and shows the result
+func (app *MiyooPod) manualExportScrobbles() {
+	n, err := exportListenBrainz(SCROBBLER_LOG_PATH, LISTENBRAINZ_EXPORT_PATH)
+	switch {
+	case os.IsNotExist(err):
+		app.showMessage("No listens logged yet", false)
+	case err != nil:
+		logMsg(fmt.Sprintf("ERROR: ListenBrainz export failed: %v", err))
+		app.showMessage("Export failed", false)
+	default:
+		logMsg(fmt.Sprintf("INFO: Exported %d listens to %s", n, LISTENBRAINZ_EXPORT_PATH))
+		app.showMessage(fmt.Sprintf("Exported %d listens", n), true)
+	}
+}
+
+// manualSubmitScrobbles submits pending listens now and shows the result
+func (app *MiyooPod) manualSubmitScrobbles() {
+	if app.ListenBrainzBusy {
+		return
+	}
+	app.ListenBrainzBusy = true
+
+	dc := app.DC
+	dc.SetHexColor(app.CurrentTheme.BG)
+	dc.Clear()
+	dc.SetFontFace(app.FontMenu)
+	dc.SetHexColor(app.CurrentTheme.HeaderTxt)
+	dc.DrawStringAnchored("Submitting listens...", SCREEN_WIDTH/2, SCREEN_HEIGHT/2, 0.5, 0.5)
+	app.triggerRefresh()
+
+	app.submitScrobblesInBackground(func(n int, err error) {
+		app.ListenBrainzBusy = false
+		switch {
+		case errors.Is(err, errListenBrainzToken):
+			app.showMessage("Invalid ListenBrainz token", false)
+		case err != nil:
+			app.showMessage(fmt.Sprintf("Submit failed (%d sent)", n), false)
+		default:
+			app.showMessage(fmt.Sprintf("Submitted %d listens", n), true)
+		}
+	})
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeListenBrainz records submissions; fail decides the status for each
+// request (numbered from 1), 200 when nil
+type fakeListenBrainz struct {
+	mu       sync.Mutex
+	requests []listenBrainzSubmission
+	fail     func(n int) int
+}
+
+func (f *fakeListenBrainz) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path != "/1/submit-listens" || r.Header.Get("Authorization") != "Token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	var sub listenBrainzSubmission
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if f.fail != nil {
+		if status := f.fail(len(f.requests) + 1); status != 200 {
+			w.WriteHeader(status)
+			return
+		}
+	}
+	f.requests = append(f.requests, sub)
+	w.Write([]byte(`{"status": "ok"}`))
+}
+
+// batchSizes returns the number of listens in each accepted request
+func (f *fakeListenBrainz) batchSizes() []int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var sizes []int
+	for _, r := range f.requests {
+		sizes = append(sizes, len(r.Payload))
+	}
+	return sizes
+}
+
+// useFakeListenBrainz points submissions and the scrobble log at a local
+// server and a fresh log with n listens, one a minute
+func useFakeListenBrainz(t *testing.T, n int) *fakeListenBrainz {
+	fake := &fakeListenBrainz{}
+	server := httptest.NewServer(fake)
+	prevURL, prevLog := listenBrainzAPIURL, SCROBBLER_LOG_PATH
+	listenBrainzAPIURL = server.URL
+	SCROBBLER_LOG_PATH = filepath.Join(t.TempDir(), "scrobbler.log")
+	t.Cleanup(func() {
+		server.Close()
+		listenBrainzAPIURL, SCROBBLER_LOG_PATH = prevURL, prevLog
+	})
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < n; i++ {
+		s := Scrobble{Artist: "Artist", Title: "Title", Length: 180, Rating: "L", Timestamp: start.Unix() + int64(i)*60}
+		if err := appendScrobble(SCROBBLER_LOG_PATH, s); err != nil {
+			t.Fatalf("appendScrobble: %v", err)
+		}
+	}
+	return fake
+}
+
+func TestScrobbleLogRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scrobbler.log")
+	want := []Scrobble{
+		{Artist: "Artist", Album: "Album", Title: "Title", TrackNum: 3, Length: 215, Rating: "L", Timestamp: 1700000000},
+		{Artist: "Tab\tArtist", Title: "Line\nBreak", Length: 60, Rating: "S", Timestamp: 1700000300},
+	}
+	for _, s := range want {
+		if err := appendScrobble(path, s); err != nil {
+			t.Fatalf("appendScrobble: %v", err)
+		}
+	}
+
+	got, err := readScrobbleLog(path)
+	if err != nil {
+		t.Fatalf("readScrobbleLog: %v", err)
+	}
+	// Tabs and line breaks would split the entry, so they become spaces
+	want[1].Artist, want[1].Title = "Tab Artist", "Line Break"
+	if len(got) != len(want) {
+		t.Fatalf("read %d scrobbles, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("scrobble %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Skipped plays aren't listens
+	if listens := listenBrainzListens(got); len(listens) != 1 || listens[0].TrackMetadata.TrackName != "Title" {
+		t.Errorf("listens = %+v, want only Title", listens)
+	}
+}
+
+func TestListenBrainzBatches(t *testing.T) {
+	fake := useFakeListenBrainz(t, 2*listenBrainzBatchSize+5)
+
+	n, offset, err := submitPendingListens("secret", 0)
+	if err != nil || n != 2*listenBrainzBatchSize+5 {
+		t.Fatalf("submitted %d, %v; want all %d", n, err, 2*listenBrainzBatchSize+5)
+	}
+	sizes := fake.batchSizes()
+	if len(sizes) != 3 || sizes[0] != listenBrainzBatchSize || sizes[1] != listenBrainzBatchSize || sizes[2] != 5 {
+		t.Errorf("batches = %v, want %d, %d, 5", sizes, listenBrainzBatchSize, listenBrainzBatchSize)
+	}
+	if fake.requests[0].ListenType != "import" {
+		t.Errorf("listen type = %q, want import", fake.requests[0].ListenType)
+	}
+
+	// Nothing is left to send
+	if n, _, err := submitPendingListens("secret", offset); n != 0 || err != nil {
+		t.Errorf("second submit sent %d, %v; want nothing", n, err)
+	}
+	if len(fake.batchSizes()) != 3 {
+		t.Errorf("second submit made a request")
+	}
+
+	// A play logged after the clock reset on boot is still new
+	early := Scrobble{Artist: "Artist", Title: "Later", Rating: "L", Timestamp: 1500000000}
+	if err := appendScrobble(SCROBBLER_LOG_PATH, early); err != nil {
+		t.Fatalf("appendScrobble: %v", err)
+	}
+	if n, _, err := submitPendingListens("secret", offset); n != 1 || err != nil {
+		t.Errorf("submit after clock reset sent %d, %v; want 1", n, err)
+	}
+}
+
+func TestListenBrainzRestartsWhenLogIsCleared(t *testing.T) {
+	useFakeListenBrainz(t, 5)
+	_, offset, err := submitPendingListens("secret", 0)
+	if err != nil {
+		t.Fatalf("submit: %v", err)
+	}
+
+	// A scrobbling tool uploads the log and deletes it, then two more plays
+	os.Remove(SCROBBLER_LOG_PATH)
+	if n, got, err := submitPendingListens("secret", offset); n != 0 || got != 0 || err != nil {
+		t.Errorf("submit without a log: %d, offset %d, %v; want nothing from 0", n, got, err)
+	}
+	for i := 0; i < 2; i++ {
+		appendScrobble(SCROBBLER_LOG_PATH, Scrobble{Artist: "Artist", Title: "Title", Rating: "L", Timestamp: 1700000000 + int64(i)})
+	}
+	if n, _, err := submitPendingListens("secret", offset); n != 2 || err != nil {
+		t.Errorf("submit to a new log sent %d, %v; want 2", n, err)
+	}
+}
+
+func TestListenBrainzResumesAfterFailure(t *testing.T) {
+	fake := useFakeListenBrainz(t, listenBrainzBatchSize+10)
+	fake.fail = func(n int) int {
+		if n == 2 {
+			return http.StatusServiceUnavailable
+		}
+		return 200
+	}
+
+	// The first batch goes through before the server fails
+	n, offset, err := submitPendingListens("secret", 0)
+	if err == nil || n != listenBrainzBatchSize {
+		t.Fatalf("submitted %d, %v; want %d and an error", n, err, listenBrainzBatchSize)
+	}
+
+	// The next attempt sends only what's left
+	fake.fail = nil
+	n, _, err = submitPendingListens("secret", offset)
+	if err != nil || n != 10 {
+		t.Fatalf("resumed with %d, %v; want 10", n, err)
+	}
+	first, second := fake.requests[0].Payload, fake.requests[1].Payload
+	if last := first[len(first)-1].ListenedAt; second[0].ListenedAt != last+60 {
+		t.Errorf("resumed at %d, want %d", second[0].ListenedAt, last+60)
+	}
+}
+
+func TestListenBrainzInvalidToken(t *testing.T) {
+	prevSettings := SETTINGS_PATH
+	SETTINGS_PATH = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() { SETTINGS_PATH = prevSettings })
+
+	fake := useFakeListenBrainz(t, 3)
+	app, _ := newTestApp(t, 0)
+	app.ListenBrainzToken = "wrong"
+
+	// The submission runs off the main loop and reports back through post
+	app.manualSubmitScrobbles()
+	if !app.ListenBrainzBusy {
+		t.Fatalf("not marked busy while submitting")
+	}
+	app.manualSubmitScrobbles() // Ignored while the first one runs
+
+	select {
+	case fn := <-app.Posted:
+		fn()
+	case <-time.After(5 * time.Second):
+		t.Fatalf("submission never reported back")
+	}
+	if app.ListenBrainzBusy || app.ListenBrainzOffset != 0 || len(fake.batchSizes()) != 0 {
+		t.Errorf("after 401: busy %v, submitted up to byte %d, %d requests accepted",
+			app.ListenBrainzBusy, app.ListenBrainzOffset, len(fake.batchSizes()))
+	}
+	select {
+	case <-app.Posted:
+		t.Errorf("second press started another submission")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The error says what's wrong
+	_, err := submitListenBrainz(http.DefaultClient, listenBrainzAPIURL, "wrong", []listenBrainzListen{{ListenedAt: 1700000000}})
+	if err != errListenBrainzToken {
+		t.Errorf("err = %v, want %v", err, errListenBrainzToken)
+	}
+}
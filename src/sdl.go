@@ -0,0 +1,260 @@
+//go:build !headless
+
+package main
+
+/*
+#cgo !desktop CFLAGS: -I/root/include/SDL2 -O2 -w -D_GNU_SOURCE=1 -D_REENTRANT
+#cgo !desktop LDFLAGS: -L/root/lib -Wl,-rpath-link,/root/lib -Wl,-rpath,'$ORIGIN' -Wl,--unresolved-symbols=ignore-in-shared-libs -lSDL2 -lSDL2_mixer -lpthread -ldl
+#cgo desktop pkg-config: sdl2 SDL2_mixer
+#cgo desktop CFLAGS: -O2 -w -DMIYOOPOD_DESKTOP
+#cgo desktop LDFLAGS: -ldl
+#include <stdlib.h>
+#include "main.inc"
+#include "audio.inc"
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+)
+
+// sdlInit opens the SDL window and renderer
+func sdlInit() error {
+	if C.init() != 0 {
+		return fmt.Errorf("SDL init failed")
+	}
+	return nil
+}
+
+// newAudioBackend opens the SDL_mixer audio device
+func newAudioBackend() AudioBackend {
+	if C.audio_init() != 0 {
+		logMsg("WARNING: Failed to init SDL2_mixer audio")
+	} else {
+		logMsg("INFO: Audio init ok!")
+	}
+	return sdlAudio{}
+}
+
+// presentFrame copies a 640x480 RGBA framebuffer to the screen
+func presentFrame(fb *image.RGBA) {
+	C.refreshScreenPtr((*C.uchar)(unsafe.Pointer(&fb.Pix[0])))
+}
+
+func C_GetKeyPress() int {
+	event := int(C.pollEvents())
+	// Closing the window (desktop builds) exits like Quit in the menu
+	if C.quitRequested() != 0 && globalApp != nil {
+		globalApp.Running.Store(false)
+	}
+	return translateKeyEvent(event)
+}
+
+func sdlCleanup() {
+	C.quit()
+}
+
+// sdlAudio is the SDL_mixer AudioBackend implemented in audio.inc
+type sdlAudio struct{}
+
+// audioMusicType maps a file extension to the SDL_mixer decoder used to open it
+func audioMusicType(path string) C.int {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mp3":
+		return C.MUS_MP3
+	case ".flac":
+		return C.MUS_FLAC
+	case ".ogg":
+		return C.MUS_OGG
+	case ".opus":
+		return C.MUS_OPUS
+	}
+	return C.MUS_NONE
+}
+
+// audioCheckDecoder returns an error if the mixer failed to initialise a decoder
+// for the file's format (e.g. Opus without libopusfile)
+func audioCheckDecoder(path string) error {
+	flags := int(C.audio_init_flags())
+	ext := strings.ToLower(filepath.Ext(path))
+
+	var flag int
+	switch ext {
+	case ".mp3":
+		flag = C.MIX_INIT_MP3
+	case ".flac":
+		flag = C.MIX_INIT_FLAC
+	case ".ogg":
+		flag = C.MIX_INIT_OGG
+	case ".opus":
+		flag = C.MIX_INIT_OPUS
+	default:
+		return nil
+	}
+
+	if flags&flag == 0 {
+		return fmt.Errorf("%s playback is not supported on this device", audioFormatName(ext))
+	}
+	return nil
+}
+
+func (sdlAudio) Load(path string) error {
+	if err := audioCheckDecoder(path); err != nil {
+		return err
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if C.audio_load(cpath) != 0 {
+		return fmt.Errorf("failed to load audio: %s (%s)", filepath.Base(path), C.GoString(C.audio_last_error()))
+	}
+	return nil
+}
+
+// LoadToMemory loads entire audio file into RAM to avoid SD card I/O during playback
+func (sdlAudio) LoadToMemory(path string) error {
+	if err := audioCheckDecoder(path); err != nil {
+		return err
+	}
+
+	// Read entire file into Go memory
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read audio file: %v", err)
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("audio file is empty: %s", filepath.Base(path))
+	}
+
+	// Allocate C memory and copy data
+	cdata := C.malloc(C.size_t(len(data)))
+	if cdata == nil {
+		return fmt.Errorf("failed to allocate memory for audio")
+	}
+
+	// Copy Go bytes to C memory
+	C.memcpy(cdata, unsafe.Pointer(&data[0]), C.size_t(len(data)))
+
+	// audio_load_mem takes ownership of cdata, will free it on next load or quit
+	if C.audio_load_mem(cdata, C.int(len(data)), audioMusicType(path)) != 0 {
+		// audio_load_mem already freed cdata on error
+		return fmt.Errorf("failed to load audio from memory: %s (%s)", filepath.Base(path), C.GoString(C.audio_last_error()))
+	}
+
+	return nil
+}
+
+func (sdlAudio) Preload(path string, gain float64) error {
+	if err := audioCheckDecoder(path); err != nil {
+		return err
+	}
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	if C.audio_preload(cpath, C.double(gain)) != 0 {
+		return fmt.Errorf("failed to preload audio: %s (%s)", filepath.Base(path), C.GoString(C.audio_last_error()))
+	}
+	return nil
+}
+
+func (sdlAudio) ClearPreload() {
+	C.audio_clear_preload()
+}
+
+func (sdlAudio) Play() error {
+	if C.audio_play() != 0 {
+		return fmt.Errorf("failed to play audio")
+	}
+	return nil
+}
+
+func (sdlAudio) Stop() {
+	C.audio_stop()
+}
+
+func (sdlAudio) Quit() {
+	C.audio_quit()
+}
+
+func (sdlAudio) TogglePause() {
+	C.audio_toggle_pause()
+}
+
+func (sdlAudio) Pause() {
+	C.audio_pause()
+}
+
+func (sdlAudio) Resume() {
+	C.audio_resume()
+}
+
+func (sdlAudio) DurationForFile(path string) float64 {
+	cpath := C.CString(path)
+	defer C.free(unsafe.Pointer(cpath))
+	return float64(C.audio_get_file_duration(cpath))
+}
+
+func (sdlAudio) Seek(position float64) {
+	C.audio_seek(C.double(position))
+}
+
+func (sdlAudio) SetEnd(position float64) {
+	C.audio_set_end(C.double(position))
+}
+
+func (sdlAudio) SetGain(gain float64) {
+	C.audio_set_gain(C.double(gain))
+}
+
+func (sdlAudio) SetSpeed(speed float64) error {
+	if C.audio_set_speed(C.double(speed)) != 0 {
+		return fmt.Errorf("playback speed can't be changed for this file")
+	}
+	return nil
+}
+
+func (sdlAudio) SetEqualizer(gains []float64) {
+	rate := float64(C.audio_sample_rate())
+	sections, preamp := designEqualizer(gains, rate)
+	coeffs := make([]C.float, 0, len(sections)*5)
+	for _, s := range sections {
+		coeffs = append(coeffs, C.float(s.b0), C.float(s.b1), C.float(s.b2), C.float(s.a1), C.float(s.a2))
+	}
+	var ptr *C.float
+	if len(coeffs) > 0 {
+		ptr = &coeffs[0]
+	}
+	C.audio_set_eq(ptr, C.int(len(sections)), C.float(preamp))
+}
+
+func (sdlAudio) SetVolume(volume int) {
+	C.audio_set_volume(C.int(volume * 128 / 100))
+}
+
+func (sdlAudio) Notify(fn func()) {
+	go func() {
+		for C.audio_wait_event() != 0 {
+			fn()
+		}
+	}()
+}
+
+func (sdlAudio) FlushBuffers() {
+	C.audio_flush_buffers()
+}
+
+func (sdlAudio) State() AudioStateSnapshot {
+	var state C.AudioState
+	C.audio_get_state(&state)
+	return AudioStateSnapshot{
+		Position:  float64(state.position),
+		Duration:  float64(state.duration),
+		IsPlaying: state.is_playing != 0,
+		IsPaused:  state.is_paused != 0,
+		Finished:  state.finished != 0,
+		Advanced:  state.advanced != 0,
+	}
+}
@@ -0,0 +1,41 @@
+//go:build headless
+
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+)
+
+// Headless builds run without SDL: no keys arrive and audio goes to the
+// simulated backend. Used for tests on machines without the device toolchain
+// (go test -tags headless ./src/).
+
+// HEADLESS_FRAME_PATH receives every presented frame as a PNG when set, from
+// MIYOOPOD_FRAME_PNG. Empty discards frames.
+var HEADLESS_FRAME_PATH = os.Getenv("MIYOOPOD_FRAME_PNG")
+
+func sdlInit() error {
+	return nil
+}
+
+func newAudioBackend() AudioBackend {
+	return newSimAudio()
+}
+
+// presentFrame writes the framebuffer to HEADLESS_FRAME_PATH instead of a screen
+func presentFrame(fb *image.RGBA) {
+	if HEADLESS_FRAME_PATH == "" {
+		return
+	}
+	if err := savePNG(HEADLESS_FRAME_PATH, fb); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to write frame: %v", err))
+	}
+}
+
+func C_GetKeyPress() int {
+	return -1
+}
+
+func sdlCleanup() {}
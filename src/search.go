@@ -37,7 +37,7 @@ func (app *MiyooPod) isSearchableMenu() bool {
 		return false
 	}
 	first := current.Items[0]
-	return first.Track != nil || first.Album != nil || first.Artist != nil
+	return first.Track != nil || first.Album != nil || first.Artist != nil || first.Tracks != nil
 }
 
 // toggleSearch activates or deactivates the search panel
@@ -102,23 +102,15 @@ func (app *MiyooPod) filterMenuItems() {
 	}
 
 	current := app.MenuStack[len(app.MenuStack)-1]
-	query := strings.ToLower(app.SearchQuery)
+	// Accents are folded on both sides, so "e" on the grid finds "é"
+	query := foldText(app.SearchQuery)
 
 	if query == "" {
 		current.Items = app.SearchAllItems
 	} else {
 		filtered := make([]*MenuItem, 0)
 		for _, item := range app.SearchAllItems {
-			label := strings.ToLower(item.Label)
-			// Also search artist name for tracks
-			artistMatch := false
-			if item.Track != nil {
-				artistMatch = strings.Contains(strings.ToLower(item.Track.Artist), query)
-			}
-			if item.Album != nil {
-				artistMatch = strings.Contains(strings.ToLower(item.Album.Artist), query)
-			}
-			if strings.Contains(label, query) || artistMatch {
+			if searchMatches(item, query) {
 				filtered = append(filtered, item)
 			}
 		}
@@ -135,6 +127,27 @@ func (app *MiyooPod) filterMenuItems() {
 	current.ScrollOff = 0
 }
 
+// searchMatches reports whether a menu item matches a folded query, by its
+// label, its artist or composer, or the sort tags it is filed by
+func searchMatches(item *MenuItem, query string) bool {
+	fields := []string{item.Label}
+	// Also search artist name for tracks
+	if item.Track != nil {
+		fields = append(fields, item.Track.Artist, item.Track.Composer)
+	}
+	if item.Album != nil {
+		fields = append(fields, item.Album.Artist)
+	}
+	fields = append(fields, itemSortTags(item)...)
+
+	for _, field := range fields {
+		if field != "" && strings.Contains(foldText(field), query) {
+			return true
+		}
+	}
+	return false
+}
+
 // handleSearchKey processes key input when the search panel is active.
 // Returns true if the key was consumed.
 func (app *MiyooPod) handleSearchKey(key Key) bool {
@@ -142,38 +155,12 @@ func (app *MiyooPod) handleSearchKey(key Key) bool {
 		return false
 	}
 
+	if moveGridCursor(&app.SearchGridRow, &app.SearchGridCol, key) {
+		app.drawCurrentScreen()
+		return true
+	}
+
 	switch key {
-	case UP:
-		app.SearchGridRow--
-		if app.SearchGridRow < 0 {
-			app.SearchGridRow = searchGridRows - 1
-		}
-		// Clamp column if the new row is shorter
-		rowLen := len(searchGrid[app.SearchGridRow])
-		if app.SearchGridCol >= rowLen {
-			app.SearchGridCol = rowLen - 1
-		}
-	case DOWN:
-		app.SearchGridRow++
-		if app.SearchGridRow >= searchGridRows {
-			app.SearchGridRow = 0
-		}
-		rowLen := len(searchGrid[app.SearchGridRow])
-		if app.SearchGridCol >= rowLen {
-			app.SearchGridCol = rowLen - 1
-		}
-	case LEFT:
-		app.SearchGridCol--
-		rowLen := len(searchGrid[app.SearchGridRow])
-		if app.SearchGridCol < 0 {
-			app.SearchGridCol = rowLen - 1
-		}
-	case RIGHT:
-		app.SearchGridCol++
-		rowLen := len(searchGrid[app.SearchGridRow])
-		if app.SearchGridCol >= rowLen {
-			app.SearchGridCol = 0
-		}
 	case A:
 		// Add selected character to query
 		char := searchGrid[app.SearchGridRow][app.SearchGridCol]
@@ -196,6 +183,47 @@ func (app *MiyooPod) handleSearchKey(key Key) bool {
 	return true
 }
 
+// moveGridCursor moves a character grid cursor with the D-pad, wrapping at the
+// edges. Returns false if key isn't a direction.
+func moveGridCursor(row, col *int, key Key) bool {
+	switch key {
+	case UP:
+		*row--
+		if *row < 0 {
+			*row = searchGridRows - 1
+		}
+		// Clamp column if the new row is shorter
+		rowLen := len(searchGrid[*row])
+		if *col >= rowLen {
+			*col = rowLen - 1
+		}
+	case DOWN:
+		*row++
+		if *row >= searchGridRows {
+			*row = 0
+		}
+		rowLen := len(searchGrid[*row])
+		if *col >= rowLen {
+			*col = rowLen - 1
+		}
+	case LEFT:
+		*col--
+		rowLen := len(searchGrid[*row])
+		if *col < 0 {
+			*col = rowLen - 1
+		}
+	case RIGHT:
+		*col++
+		rowLen := len(searchGrid[*row])
+		if *col >= rowLen {
+			*col = 0
+		}
+	default:
+		return false
+	}
+	return true
+}
+
 // drawSearchPanel renders the search grid on the right side of the screen
 func (app *MiyooPod) drawSearchPanel(panelX, panelY, panelW int) {
 	dc := app.DC
@@ -224,18 +252,33 @@ func (app *MiyooPod) drawSearchPanel(panelX, panelY, panelW int) {
 
 	// Draw the character grid below the input
 	gridStartY := inputY + inputH + 12
-	cellW := (panelW - 16) / searchGridCols
 	cellH := 32
+	app.drawCharGrid(panelX+8, gridStartY, panelW-16, cellH, app.SearchGridRow, app.SearchGridCol)
+
+	// Draw hints at bottom of panel — stacked vertically with button legend styling
+	hintsY := gridStartY + searchGridRows*cellH + 8
+	dc.SetFontFace(app.FontSmall)
+	hintX := panelX + 8
+	lineH := 24.0
+	app.drawButtonLegend(hintX, float64(hintsY), "A", "Add Character")
+	app.drawButtonLegend(hintX, float64(hintsY)+lineH, "X", "Delete")
+	app.drawButtonLegend(hintX, float64(hintsY)+lineH*2, "B", "Close")
+}
+
+// drawCharGrid renders searchGrid at (x, y) with the cell at (selRow, selCol) highlighted
+func (app *MiyooPod) drawCharGrid(x, y, w, cellH, selRow, selCol int) {
+	dc := app.DC
+	cellW := w / searchGridCols
 
 	dc.SetFontFace(app.FontMenu)
 
 	for row := 0; row < searchGridRows; row++ {
 		for col := 0; col < len(searchGrid[row]); col++ {
 			char := searchGrid[row][col]
-			cx := panelX + 8 + col*cellW
-			cy := gridStartY + row*cellH
+			cx := x + col*cellW
+			cy := y + row*cellH
 
-			isSelected := row == app.SearchGridRow && col == app.SearchGridCol
+			isSelected := row == selRow && col == selCol
 
 			if isSelected {
 				// Highlight selected cell
@@ -247,7 +290,7 @@ func (app *MiyooPod) drawSearchPanel(panelX, panelY, panelW int) {
 				dc.SetHexColor(app.CurrentTheme.ItemTxt)
 			}
 
-			// Display character (show "␣" for space)
+			// Display character (show "_" for space)
 			displayChar := char
 			if char == " " {
 				displayChar = "_"
@@ -255,13 +298,4 @@ func (app *MiyooPod) drawSearchPanel(panelX, panelY, panelW int) {
 			dc.DrawStringAnchored(displayChar, float64(cx)+float64(cellW)/2, float64(cy)+float64(cellH-2)/2, 0.5, 0.5)
 		}
 	}
-
-	// Draw hints at bottom of panel — stacked vertically with button legend styling
-	hintsY := gridStartY + searchGridRows*cellH + 8
-	dc.SetFontFace(app.FontSmall)
-	hintX := panelX + 8
-	lineH := 24.0
-	app.drawButtonLegend(hintX, float64(hintsY), "A", "Add Character")
-	app.drawButtonLegend(hintX, float64(hintsY)+lineH, "X", "Delete")
-	app.drawButtonLegend(hintX, float64(hintsY)+lineH*2, "B", "Close")
 }
@@ -111,12 +111,13 @@ func (app *MiyooPod) performSeekTick() {
 	app.mpvSeek(amount)
 
 	// Update position immediately for responsive UI
-	state := audioGetState()
+	state := app.audioState()
 	if state.Position >= 0 {
 		app.Playing.Position = state.Position
 	}
 
 	if app.CurrentScreen == ScreenNowPlaying {
 		app.updateProgressBarOnly()
+		app.updateLyricsOnly()
 	}
 }
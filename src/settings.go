@@ -4,11 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/google/uuid"
 )
 
-const SETTINGS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_settings.json"
+var SETTINGS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_settings.json"
 
 type Settings struct {
 	InstallationID   string `json:"installation_id,omitempty"`
@@ -21,6 +23,21 @@ type Settings struct {
 	UpdateNotifications *bool  `json:"update_notifications,omitempty"`
 	Volume              *int   `json:"volume,omitempty"`
 	Brightness          *int   `json:"brightness,omitempty"`
+	ReplayGainMode      string   `json:"replaygain_mode,omitempty"`
+	ReplayGainPreamp    *float64 `json:"replaygain_preamp,omitempty"`
+	ListenBrainzToken  string `json:"listenbrainz_token,omitempty"`
+	ListenBrainzOffset int64  `json:"listenbrainz_offset,omitempty"`
+	MusicRoots            []string `json:"music_roots,omitempty"`
+	DataDir               string   `json:"data_dir,omitempty"`
+	HideCompilationArtists bool `json:"hide_compilation_artists,omitempty"`
+	IgnoreArticles *bool    `json:"ignore_articles,omitempty"`
+	SortArticles   []string `json:"sort_articles,omitempty"`
+	FeatPatterns   []string `json:"feat_patterns,omitempty"`
+	SleepAction    string   `json:"sleep_action,omitempty"`
+	AudiobookFolders []string `json:"audiobook_folders,omitempty"`
+	AudiobookSpeed   float64  `json:"audiobook_speed,omitempty"`
+	EQPreset string               `json:"eq_preset,omitempty"`
+	EQCurves map[string][]float64 `json:"eq_curves,omitempty"`
 }
 
 // loadSettings loads theme and lock key preferences from a lightweight JSON file
@@ -35,6 +52,9 @@ func (app *MiyooPod) loadSettings() error {
 		return err
 	}
 
+	// Storage folders first, so everything loaded afterwards comes from them
+	app.applyStorageSettings(settings.MusicRoots, settings.DataDir)
+
 	// Generate installation ID if it doesn't exist
 	if settings.InstallationID == "" {
 		settings.InstallationID = uuid.New().String()
@@ -72,8 +92,8 @@ func (app *MiyooPod) loadSettings() error {
 	}
 
 	// Restore log writing preference
-	app.LocalLogsEnabled = settings.LocalLogsEnabled
-	if app.LocalLogsEnabled {
+	app.LocalLogsEnabled.Store(settings.LocalLogsEnabled)
+	if settings.LocalLogsEnabled {
 		logMsg("Local logs enabled")
 	} else {
 		logMsg("Local logs disabled")
@@ -121,7 +141,7 @@ func (app *MiyooPod) loadSettings() error {
 	// Restore volume (default 50 if not set)
 	if settings.Volume != nil {
 		app.SystemVolume = *settings.Volume
-		setMiAOVolume(app.SystemVolume)
+		app.applySystemVolume(app.SystemVolume)
 		logMsg(fmt.Sprintf("INFO: Restored volume: %d%%", app.SystemVolume))
 	}
 
@@ -132,28 +152,119 @@ func (app *MiyooPod) loadSettings() error {
 		logMsg(fmt.Sprintf("INFO: Restored brightness: %d%%", app.SystemBrightness))
 	}
 
+	// Restore ReplayGain mode and preamp (default off, 0 dB)
+	if settings.ReplayGainMode != "" {
+		app.ReplayGainMode = parseReplayGainMode(settings.ReplayGainMode)
+		logMsg(fmt.Sprintf("INFO: Restored ReplayGain mode: %s", app.ReplayGainMode))
+	}
+	if settings.ReplayGainPreamp != nil {
+		app.ReplayGainPreamp = *settings.ReplayGainPreamp
+	}
+
+	app.HideCompilationArtists = settings.HideCompilationArtists
+
+	// Restore article handling (default on, with the built-in articles)
+	if settings.IgnoreArticles != nil {
+		app.IgnoreArticles = *settings.IgnoreArticles
+	}
+	app.SortArticles = settings.SortArticles
+
+	// Featured-artist patterns, kept so saving doesn't drop them
+	app.FeatPatterns = settings.FeatPatterns
+	setFeatPatterns(app.FeatPatterns)
+
+	// What the sleep timer does after pausing (default just pause)
+	app.SleepAction = parseSleepAction(settings.SleepAction)
+
+	// Audiobook folders and their playback speed (default none, 1.0x)
+	app.AudiobookFolders = normalizeMusicRoots(settings.AudiobookFolders)
+	if settings.AudiobookSpeed > 0 {
+		app.AudiobookSpeed = settings.AudiobookSpeed
+	}
+
+	// Equalizer preset and edited curves (default Flat, no edits)
+	app.EQPreset = eqPresetIndex(settings.EQPreset)
+	app.restoreEQCurves(settings.EQCurves)
+	app.applyEqualizer()
+
+	// Restore ListenBrainz token and submission progress
+	app.ListenBrainzToken = strings.TrimSpace(settings.ListenBrainzToken)
+	app.ListenBrainzOffset = settings.ListenBrainzOffset
+
 	return nil
 }
 
 // saveSettings saves current theme and lock key preferences
 func (app *MiyooPod) saveSettings() error {
+	data, err := app.settingsJSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(SETTINGS_PATH, data, 0644)
+}
+
+// saveSettingsAsync snapshots the settings on the main loop and writes them
+// in the background, for changes that repeat quickly (volume, brightness,
+// equalizer)
+func (app *MiyooPod) saveSettingsAsync() {
+	data, err := app.settingsJSON()
+	if err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to encode settings: %v", err))
+		return
+	}
+	settingsSnapshots++
+	seq := settingsSnapshots
+	go func() {
+		settingsWriteMu.Lock()
+		defer settingsWriteMu.Unlock()
+		if seq < settingsWritten {
+			return // A newer snapshot got there first
+		}
+		settingsWritten = seq
+		if err := os.WriteFile(SETTINGS_PATH, data, 0644); err != nil {
+			logMsg(fmt.Sprintf("ERROR: Failed to save settings: %v", err))
+		}
+	}()
+}
+
+// settingsWriteMu keeps background settings writes in order: the goroutines
+// may run in any order, so each skips its snapshot if a newer one was written.
+// settingsSnapshots counts snapshots (main loop only); settingsWritten is the
+// last one written.
+var (
+	settingsWriteMu   sync.Mutex
+	settingsSnapshots int
+	settingsWritten   int
+)
+
+func (app *MiyooPod) settingsJSON() ([]byte, error) {
 	settings := Settings{
 		InstallationID:      app.InstallationID,
 		Theme:               app.CurrentTheme.Name,
 		LockKey:             app.getLockKeyName(),
-		LocalLogsEnabled:    app.LocalLogsEnabled,
+		LocalLogsEnabled:    app.LocalLogsEnabled.Load(),
 		SentryEnabled:       app.SentryEnabled,
 		AutoLockMinutes:     &app.AutoLockMinutes,
 		ScreenPeekEnabled:   &app.ScreenPeekEnabled,
 		UpdateNotifications: &app.UpdateNotifications,
 		Volume:              &app.SystemVolume,
 		Brightness:          &app.SystemBrightness,
+		ReplayGainMode:      app.ReplayGainMode.String(),
+		ReplayGainPreamp:    &app.ReplayGainPreamp,
+		ListenBrainzToken:  app.ListenBrainzToken,
+		ListenBrainzOffset: app.ListenBrainzOffset,
+		MusicRoots:            app.MusicRoots,
+		DataDir:               app.DataDir,
+		HideCompilationArtists: app.HideCompilationArtists,
+		IgnoreArticles: &app.IgnoreArticles,
+		SortArticles:   app.SortArticles,
+		FeatPatterns:   app.FeatPatterns,
+		SleepAction:    app.SleepAction.String(),
+		AudiobookFolders: app.AudiobookFolders,
+		AudiobookSpeed:   app.AudiobookSpeed,
+		EQPreset: eqPresets[app.EQPreset].Key,
+		EQCurves: app.EQCurves,
 	}
 
-	data, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile(SETTINGS_PATH, data, 0644)
+	return json.MarshalIndent(settings, "", "  ")
 }
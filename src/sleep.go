@@ -0,0 +1,322 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// SleepMode selects when the sleep timer stops playback
+type SleepMode int
+
+const (
+	SleepOff SleepMode = iota
+	SleepAfterMinutes
+	SleepEndOfTrack
+	SleepEndOfAlbum
+)
+
+// SleepAction is what happens once the sleep timer has faded out and paused
+type SleepAction int
+
+const (
+	SleepPause SleepAction = iota
+	SleepLock
+	SleepExit
+)
+
+func (a SleepAction) String() string {
+	switch a {
+	case SleepLock:
+		return "lock"
+	case SleepExit:
+		return "exit"
+	default:
+		return "pause"
+	}
+}
+
+// parseSleepAction is the inverse of SleepAction.String
+func parseSleepAction(s string) SleepAction {
+	switch s {
+	case "lock":
+		return SleepLock
+	case "exit":
+		return SleepExit
+	default:
+		return SleepPause
+	}
+}
+
+// sleepTimerMinutes are the timer lengths offered before the end-of-track
+// and end-of-album options
+var sleepTimerMinutes = []int{15, 30, 45, 60, 90}
+
+// SLEEP_FADE_DURATION is how long the volume takes to fade out, ending when
+// the timer runs out. SLEEP_FADE_STEPS is how many volume changes it takes.
+var SLEEP_FADE_DURATION = 10 * time.Second
+
+const SLEEP_FADE_STEPS = 40
+
+// cycleSleepTimer steps the sleep timer through Off -> 15 ... 90 min ->
+// End of Track -> End of Album -> Off
+func (app *MiyooPod) cycleSleepTimer() {
+	switch app.SleepMode {
+	case SleepOff:
+		app.setSleepTimer(SleepAfterMinutes, sleepTimerMinutes[0])
+	case SleepAfterMinutes:
+		for i, m := range sleepTimerMinutes {
+			if m == app.SleepMinutes && i+1 < len(sleepTimerMinutes) {
+				app.setSleepTimer(SleepAfterMinutes, sleepTimerMinutes[i+1])
+				return
+			}
+		}
+		app.setSleepTimer(SleepEndOfTrack, 0)
+	case SleepEndOfTrack:
+		app.setSleepTimer(SleepEndOfAlbum, 0)
+	default:
+		app.setSleepTimer(SleepOff, 0)
+	}
+}
+
+// setSleepTimer starts the sleep timer, replacing any running one. minutes is
+// only used with SleepAfterMinutes.
+func (app *MiyooPod) setSleepTimer(mode SleepMode, minutes int) {
+	app.cancelSleepFade()
+	app.SleepMode = mode
+	app.SleepMinutes = minutes
+	if mode == SleepAfterMinutes {
+		app.SleepDeadline = time.Now().Add(time.Duration(minutes) * time.Minute)
+	}
+	app.NPCacheDirty = true
+	app.preloadNextTrack() // See sleepsAfterTrack
+
+	logMsg(fmt.Sprintf("INFO: Sleep timer: %s", app.sleepTimerLabel()))
+	TrackAction("sleep_timer_set", map[string]interface{}{"timer": app.sleepTimerLabel()})
+}
+
+// sleepTimerLabel describes the timer setting, for Settings
+func (app *MiyooPod) sleepTimerLabel() string {
+	switch app.SleepMode {
+	case SleepAfterMinutes:
+		return fmt.Sprintf("%d min", app.SleepMinutes)
+	case SleepEndOfTrack:
+		return "End of Track"
+	case SleepEndOfAlbum:
+		return "End of Album"
+	default:
+		return "Off"
+	}
+}
+
+// sleepTimerStatus describes the running timer for Now Playing, with the
+// minutes left rounded up. Empty when the timer is off.
+func (app *MiyooPod) sleepTimerStatus() string {
+	if app.SleepFading {
+		return "Sleep timer: fading out"
+	}
+	switch app.SleepMode {
+	case SleepAfterMinutes:
+		left := int(math.Ceil(time.Until(app.SleepDeadline).Minutes()))
+		if left < 1 {
+			left = 1
+		}
+		return fmt.Sprintf("Sleep timer: %d min left", left)
+	case SleepEndOfTrack:
+		return "Sleep timer: end of track"
+	case SleepEndOfAlbum:
+		return "Sleep timer: end of album"
+	}
+	return ""
+}
+
+// sleepsAfterTrack reports whether the timer runs out when the playing track
+// ends: in end-of-track mode, and in end-of-album mode on the album's last
+// track. The next track isn't preloaded then (see preloadNextTrack), so the
+// track's real end, not a gapless switch, finishes the timer (see
+// handleTrackEnd).
+func (app *MiyooPod) sleepsAfterTrack() bool {
+	switch app.SleepMode {
+	case SleepEndOfTrack:
+		return true
+	case SleepEndOfAlbum:
+		next := app.peekNextTrack()
+		return next == nil || albumKeyFor(next) != albumKeyFor(app.Playing.Track)
+	}
+	return false
+}
+
+// sleepTimeLeft returns how long until the timer should have paused
+// playback, and false while that isn't known yet (end of album before its
+// last track)
+func (app *MiyooPod) sleepTimeLeft() (time.Duration, bool) {
+	switch app.SleepMode {
+	case SleepAfterMinutes:
+		return time.Until(app.SleepDeadline), true
+	case SleepEndOfTrack, SleepEndOfAlbum:
+		if !app.sleepsAfterTrack() {
+			return 0, false
+		}
+		duration := app.Playing.Duration
+		if duration <= 0 && app.Playing.Track != nil {
+			duration = app.Playing.Track.Duration
+		}
+		if duration <= 0 {
+			return 0, false
+		}
+		return time.Duration((duration - app.Playing.Position) * float64(time.Second)), true
+	}
+	return 0, false
+}
+
+// checkSleepTimer starts the fade-out when the timer is about to run out.
+// Called by the playback poller every second.
+func (app *MiyooPod) checkSleepTimer() {
+	if app.SleepMode == SleepOff || app.SleepFading || app.Playing.Track == nil {
+		return
+	}
+
+	left, known := app.sleepTimeLeft()
+	switch {
+	case !known:
+	case app.Playing.State == StatePaused && left <= SLEEP_FADE_DURATION,
+		left <= 0 && app.SleepMode == SleepAfterMinutes:
+		// Nothing to fade: already paused, or the poller missed the window
+		app.finishSleepTimer()
+		return
+	case left <= SLEEP_FADE_DURATION:
+		app.startSleepFade()
+		return
+	}
+
+	// Minutes left changed
+	if app.sleepTimerStatus() != app.SleepStatusShown {
+		app.redrawSleepStatus()
+	}
+}
+
+// redrawSleepStatus re-renders Now Playing, whose cached background holds
+// the sleep timer status
+func (app *MiyooPod) redrawSleepStatus() {
+	app.NPCacheDirty = true
+	if app.CurrentScreen == ScreenNowPlaying {
+		app.requestRedraw()
+	}
+}
+
+// startSleepFade lowers the mixer volume to silence over SLEEP_FADE_DURATION,
+// then finishes the timer; a timer that runs out with the track stays silent
+// until the track ends. The steps are clocked by a goroutine and applied
+// on the main loop; SleepFadeGen tells them apart from a cancelled fade's.
+func (app *MiyooPod) startSleepFade() {
+	app.SleepFading = true
+	app.SleepFadeGen++
+	app.redrawSleepStatus()
+	gen := app.SleepFadeGen
+	base := app.mixerVolume()
+
+	logMsg("INFO: Sleep timer: fading out")
+	go func() {
+		for step := 1; step <= SLEEP_FADE_STEPS; step++ {
+			time.Sleep(SLEEP_FADE_DURATION / SLEEP_FADE_STEPS)
+			step := step // capture
+			app.post(func() { app.sleepFadeStep(gen, step, base) })
+		}
+	}()
+}
+
+// sleepFadeStep applies one step of the fade started with generation gen
+func (app *MiyooPod) sleepFadeStep(gen, step, base int) {
+	if !app.SleepFading || gen != app.SleepFadeGen {
+		return
+	}
+	if step >= SLEEP_FADE_STEPS {
+		if app.SleepMode != SleepAfterMinutes && app.Playing.State == StatePlaying {
+			app.Audio.SetVolume(0)
+			return
+		}
+		app.finishSleepTimer()
+		return
+	}
+	// Squared so the fade sounds even rather than dropping off at the end
+	left := float64(SLEEP_FADE_STEPS-step) / SLEEP_FADE_STEPS
+	app.Audio.SetVolume(int(math.Round(float64(base) * left * left)))
+}
+
+// cancelSleepFade stops a fade in progress and puts the volume back
+func (app *MiyooPod) cancelSleepFade() {
+	if !app.SleepFading {
+		return
+	}
+	app.SleepFading = false
+	app.SleepFadeGen++
+	app.applySystemVolume(app.SystemVolume)
+}
+
+// finishSleepTimer pauses playback, restores the volume for the next resume,
+// saves the playback state and runs the sleep action
+func (app *MiyooPod) finishSleepTimer() {
+	app.SleepFading = false
+	app.SleepFadeGen++
+	app.SleepMode = SleepOff
+
+	if app.Playing.State == StatePlaying {
+		app.Audio.Pause()
+		app.Playing.State = StatePaused
+	}
+	app.applySystemVolume(app.SystemVolume)
+	app.preloadNextTrack()
+	app.savePlaybackState()
+	app.NPCacheDirty = true
+
+	logMsg(fmt.Sprintf("INFO: Sleep timer ended (%s)", app.SleepAction))
+	TrackAction("sleep_timer_ended", map[string]interface{}{"action": app.SleepAction.String()})
+
+	switch app.SleepAction {
+	case SleepLock:
+		if !app.Locked {
+			app.toggleLock()
+			return
+		}
+	case SleepExit:
+		app.Running.Store(false)
+		return
+	}
+	app.requestRedraw()
+}
+
+// finishSleepTimerAtTrackEnd finishes a timer that ran out with the track,
+// once handleTrackEnd has moved on: the next track, started silent, is
+// paused at its beginning
+func (app *MiyooPod) finishSleepTimerAtTrackEnd() {
+	if app.Playing.State == StatePlaying {
+		app.Audio.Pause()
+		app.Playing.State = StatePaused
+		app.seekAudio(0)
+		app.Playing.Position = 0
+	}
+	app.finishSleepTimer()
+}
+
+// cycleSleepAction cycles what the sleep timer does after pausing:
+// Pause -> Lock Screen -> Exit App -> Pause
+func (app *MiyooPod) cycleSleepAction() {
+	app.SleepAction = (app.SleepAction + 1) % (SleepExit + 1)
+	app.refreshSettingsMenu()
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save sleep timer preference: %v", err))
+	}
+}
+
+// sleepActionLabel names the sleep action for Settings
+func (app *MiyooPod) sleepActionLabel() string {
+	switch app.SleepAction {
+	case SleepLock:
+		return "Lock Screen"
+	case SleepExit:
+		return "Exit App"
+	default:
+		return "Pause"
+	}
+}
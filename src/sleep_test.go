@@ -0,0 +1,144 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSleepTimerFadesOutAndPauses(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 0)
+	app.SystemVolume = 60
+	app.SleepAction = SleepLock
+
+	app.cycleSleepTimer()
+	if app.SleepMode != SleepAfterMinutes || app.SleepMinutes != 15 {
+		t.Fatalf("first step = %s, want 15 min", app.sleepTimerLabel())
+	}
+	if got := app.sleepTimerStatus(); got != "Sleep timer: 15 min left" {
+		t.Errorf("status = %q", got)
+	}
+
+	// Not due yet
+	app.checkSleepTimer()
+	if app.SleepFading {
+		t.Fatal("fading with 15 minutes left")
+	}
+
+	app.SleepDeadline = time.Now().Add(SLEEP_FADE_DURATION / 2)
+	app.checkSleepTimer()
+	if !app.SleepFading {
+		t.Fatal("not fading inside the fade window")
+	}
+
+	// Steps are applied by the main loop; run them here
+	gen := app.SleepFadeGen
+	app.sleepFadeStep(gen, SLEEP_FADE_STEPS/2, 100)
+	if got := sim.Volume(); got != 25 {
+		t.Errorf("volume halfway through the fade = %d, want 25", got)
+	}
+	if app.Playing.State != StatePlaying {
+		t.Fatal("paused before the fade ended")
+	}
+	app.sleepFadeStep(gen, SLEEP_FADE_STEPS, 100)
+
+	if app.Playing.State != StatePaused || sim.Current() == "" {
+		t.Errorf("state = %v, want paused on the same track", app.Playing.State)
+	}
+	if got := sim.Volume(); got != 100 {
+		t.Errorf("volume after the timer = %d, want it restored to 100", got)
+	}
+	if app.SleepMode != SleepOff || app.SleepFading {
+		t.Errorf("timer still running: %s", app.sleepTimerLabel())
+	}
+	if !app.Locked {
+		t.Error("screen not locked")
+	}
+	if _, err := os.Stat(PLAYBACK_STATE_PATH); err != nil {
+		t.Errorf("playback state not saved: %v", err)
+	}
+}
+
+func TestSleepTimerCancelRestoresVolume(t *testing.T) {
+	app, sim := newTestApp(t, 1)
+	playQueue(t, app, 0)
+	app.setSleepTimer(SleepAfterMinutes, 15)
+	app.startSleepFade()
+	app.sleepFadeStep(app.SleepFadeGen, 10, 100)
+
+	app.setSleepTimer(SleepOff, 0)
+	if got := sim.Volume(); got != 100 {
+		t.Errorf("volume after cancelling = %d, want 100", got)
+	}
+	// A step still queued by the cancelled fade does nothing
+	app.sleepFadeStep(app.SleepFadeGen-1, SLEEP_FADE_STEPS, 100)
+	if app.Playing.State != StatePlaying {
+		t.Error("cancelled fade paused playback")
+	}
+}
+
+func TestSleepTimerEndOfAlbum(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	app.Library.Tracks[2].Album = "Other"
+	playQueue(t, app, 0)
+	app.setSleepTimer(SleepEndOfAlbum, 0)
+
+	// The first track's album carries on into the next
+	sim.Advance(time.Duration(SIM_DEFAULT_DURATION-2) * time.Second)
+	app.syncAudioState()
+	app.checkSleepTimer()
+	if app.SleepFading {
+		t.Fatal("fading before the album's last track")
+	}
+
+	app.playTrackFromList(app.Library.Tracks, 1)
+	sim.Advance(time.Duration(SIM_DEFAULT_DURATION-2) * time.Second)
+	app.syncAudioState()
+	app.checkSleepTimer()
+	if !app.SleepFading {
+		t.Fatal("not fading at the end of the album")
+	}
+}
+
+func TestSleepTimerEndOfTrackStopsAtTheBoundary(t *testing.T) {
+	app, sim := newTestApp(t, 3)
+	playQueue(t, app, 0)
+	waitForPreload(t, sim, trackPath(2))
+	app.SystemVolume = 60
+
+	// Armed, the next track isn't preloaded, so the track can't switch over
+	app.setSleepTimer(SleepEndOfTrack, 0)
+	waitForPreload(t, sim, "")
+	sim.Advance(time.Duration(SIM_DEFAULT_DURATION-5) * time.Second)
+	app.syncAudioState()
+	app.checkSleepTimer()
+	if !app.SleepFading {
+		t.Fatal("not fading 5s before the end of the track")
+	}
+
+	// The fade runs out with the track: stay silent until it ends
+	app.sleepFadeStep(app.SleepFadeGen, SLEEP_FADE_STEPS, 100)
+	if app.SleepMode != SleepEndOfTrack || app.Playing.State != StatePlaying || sim.Volume() != 0 {
+		t.Fatalf("after the fade: %s, state %v, volume %d; want silent until the track ends",
+			app.sleepTimerLabel(), app.Playing.State, sim.Volume())
+	}
+
+	// Across the boundary the next track is left paused at its start
+	sim.Advance(10 * time.Second)
+	app.syncAudioState()
+	if currentPath(app) != trackPath(2) || app.Playing.State != StatePaused || app.Playing.Position != 0 {
+		t.Errorf("after the track: %s %v at %v; want %s paused at 0",
+			currentPath(app), app.Playing.State, app.Playing.Position, trackPath(2))
+	}
+	sim.Advance(5 * time.Second)
+	if state := sim.State(); state.Position != 0 || !state.IsPaused {
+		t.Errorf("audio at %v (paused %v), want paused at 0", state.Position, state.IsPaused)
+	}
+	if app.SleepMode != SleepOff || sim.Volume() != 100 {
+		t.Errorf("timer %s, volume %d; want off and restored", app.sleepTimerLabel(), sim.Volume())
+	}
+
+	// With the timer done, the track after is preloaded again
+	waitForPreload(t, sim, trackPath(3))
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var SMART_PLAYLISTS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_smart_playlists.json"
+
+// SmartPlaylist is a rule-based playlist computed from library metadata and
+// play counts. All set rules must match; unset (zero) rules are ignored.
+type SmartPlaylist struct {
+	Name            string  `json:"name"`
+	Genre           string  `json:"genre,omitempty"`             // Case-insensitive match of any of a track's genres
+	YearFrom        int     `json:"year_from,omitempty"`         // Inclusive
+	YearTo          int     `json:"year_to,omitempty"`           // Inclusive
+	AddedWithinDays int     `json:"added_within_days,omitempty"` // Recently added
+	MinPlays        int     `json:"min_plays,omitempty"`
+	MaxPlays        *int    `json:"max_plays,omitempty"`   // 0 means never played
+	MinMinutes      float64 `json:"min_minutes,omitempty"` // Tracks longer than this
+	Sort            string  `json:"sort,omitempty"`        // "title" (default), "added", "plays", "year", "artist"
+	Limit           int     `json:"limit,omitempty"`       // Max tracks, 0 for all
+}
+
+// defaultSmartPlaylists is written on first use so there's an example to edit
+func defaultSmartPlaylists() []SmartPlaylist {
+	never := 0
+	return []SmartPlaylist{
+		{Name: "Recently Added", AddedWithinDays: 30, Sort: "added", Limit: 100},
+		{Name: "Most Played", MinPlays: 1, Sort: "plays", Limit: 50},
+		{Name: "Never Played", MaxPlays: &never, Sort: "artist"},
+	}
+}
+
+// loadSmartPlaylists reads the rule definitions, creating the file with the
+// defaults if it doesn't exist yet
+func loadSmartPlaylists() []SmartPlaylist {
+	data, err := os.ReadFile(SMART_PLAYLISTS_PATH)
+	if os.IsNotExist(err) {
+		defs := defaultSmartPlaylists()
+		if data, err := json.MarshalIndent(defs, "", "  "); err == nil {
+			if err := os.WriteFile(SMART_PLAYLISTS_PATH, data, 0644); err != nil {
+				logMsg(fmt.Sprintf("WARNING: Failed to write smart playlists: %v", err))
+			}
+		}
+		return defs
+	}
+	if err != nil {
+		logMsg(fmt.Sprintf("WARNING: Failed to read smart playlists: %v", err))
+		return nil
+	}
+
+	var defs []SmartPlaylist
+	if err := json.Unmarshal(data, &defs); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to parse smart playlists: %v", err))
+		return nil
+	}
+	return defs
+}
+
+// buildSmartPlaylists computes every smart playlist from the current library
+func (app *MiyooPod) buildSmartPlaylists() []*Playlist {
+	if app.Library == nil {
+		return nil
+	}
+
+	var playlists []*Playlist
+	for _, def := range loadSmartPlaylists() {
+		if strings.TrimSpace(def.Name) == "" {
+			continue
+		}
+		playlists = append(playlists, &Playlist{
+			Name:   def.Name,
+			Tracks: app.evalSmartPlaylist(def),
+			Smart:  true,
+		})
+	}
+	return playlists
+}
+
+// hasGenre reports whether any of a track's genres is genre, ignoring case
+func hasGenre(t *Track, genre string) bool {
+	for _, g := range trackGenres(t) {
+		if strings.EqualFold(strings.TrimSpace(g), strings.TrimSpace(genre)) {
+			return true
+		}
+	}
+	return false
+}
+
+// evalSmartPlaylist returns the library tracks matching a rule, sorted and limited
+func (app *MiyooPod) evalSmartPlaylist(def SmartPlaylist) []*Track {
+	now := time.Now().Unix()
+
+	var tracks []*Track
+	for _, t := range app.Library.Tracks {
+		if def.Genre != "" && !hasGenre(t, def.Genre) {
+			continue
+		}
+		if def.YearFrom > 0 && t.Year < def.YearFrom {
+			continue
+		}
+		if def.YearTo > 0 && (t.Year == 0 || t.Year > def.YearTo) {
+			continue
+		}
+		if def.AddedWithinDays > 0 && now-trackAddedAt(t) > int64(def.AddedWithinDays)*24*60*60 {
+			continue
+		}
+		plays := app.playCount(t)
+		if plays < def.MinPlays {
+			continue
+		}
+		if def.MaxPlays != nil && plays > *def.MaxPlays {
+			continue
+		}
+		if def.MinMinutes > 0 && t.Duration <= def.MinMinutes*60 {
+			continue
+		}
+		tracks = append(tracks, t)
+	}
+
+	var less func(a, b *Track) bool
+	switch def.Sort {
+	case "added":
+		less = func(a, b *Track) bool { return trackAddedAt(a) > trackAddedAt(b) }
+	case "plays":
+		less = func(a, b *Track) bool { return app.playCount(a) > app.playCount(b) }
+	case "year":
+		less = func(a, b *Track) bool { return a.Year < b.Year }
+	case "artist":
+		less = func(a, b *Track) bool {
+			ka, kb := sortKey(a.Artist, a.SortArtist), sortKey(b.Artist, b.SortArtist)
+			if ka != kb {
+				return ka < kb
+			}
+			return trackSortKey(a) < trackSortKey(b)
+		}
+	default:
+		less = func(a, b *Track) bool { return trackSortKey(a) < trackSortKey(b) }
+	}
+	sort.SliceStable(tracks, func(i, j int) bool { return less(tracks[i], tracks[j]) })
+
+	if def.Limit > 0 && len(tracks) > def.Limit {
+		tracks = tracks[:def.Limit]
+	}
+	return tracks
+}
+
+// trackAddedAt returns when a track joined the library, falling back to the
+// file date for tracks scanned before that was recorded
+func trackAddedAt(t *Track) int64 {
+	if t.AddedAt > 0 {
+		return t.AddedAt
+	}
+	return t.ModTime / int64(time.Second)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSmartPlaylistGenreMatchesEachGenre(t *testing.T) {
+	app, _ := newTestApp(t, 3)
+	tags := []string{"Rock; Pop", "rock", "Pop"}
+	for i, track := range app.Library.Tracks {
+		track.Genre = tags[i]
+		track.Genres = nil
+		if genres := splitGenres(tags[i]); len(genres) > 1 {
+			track.Genres = genres
+		}
+	}
+
+	for _, tc := range []struct {
+		genre string
+		want  string
+	}{
+		{"Rock", "[Track 1 Track 2]"},
+		{" pop ", "[Track 1 Track 3]"},
+		{"Jazz", "[]"},
+	} {
+		var got []string
+		for _, track := range app.evalSmartPlaylist(SmartPlaylist{Name: "Genre", Genre: tc.genre}) {
+			got = append(got, track.Title)
+		}
+		if fmt.Sprint(got) != tc.want {
+			t.Errorf("genre %q matched %v, want %s", tc.genre, got, tc.want)
+		}
+	}
+}
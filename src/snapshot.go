@@ -0,0 +1,27 @@
+package main
+
+import (
+	"image"
+	"image/png"
+	"os"
+)
+
+// savePNG encodes img to path. It writes a temp file and renames it over the
+// target, so a viewer polling the file never sees a partial frame.
+func savePNG(path string, img image.Image) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Golden screenshots live in testdata/snapshots/<screen>/<theme>.png.
+// After an intended UI change, regenerate them and review the diff:
+//
+//	go test -tags headless ./src/ -run TestScreenSnapshots -update
+var updateSnapshots = flag.Bool("update", false, "rewrite golden screenshots")
+
+const SNAPSHOT_DIR = "testdata/snapshots"
+
+// snapshotScreens puts a fresh app (6 tracks, nothing playing, queue screen)
+// into the state to capture
+var snapshotScreens = []struct {
+	name  string
+	setup func(t *testing.T, app *MiyooPod, sim *simAudio)
+}{
+	{"menu", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		showRootMenu(app)
+	}},
+	{"now-playing", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		app.Queue.Repeat = RepeatAll
+		playQueue(t, app, 1)
+		sim.Advance(83 * time.Second)
+		app.syncAudioState()
+		app.CurrentScreen = ScreenNowPlaying
+	}},
+	{"sleep-timer", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		playQueue(t, app, 1)
+		app.CurrentScreen = ScreenNowPlaying
+		app.setSleepTimer(SleepEndOfAlbum, 0)
+	}},
+	{"lyrics", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		playQueue(t, app, 1)
+		sim.Advance(21 * time.Second)
+		app.syncAudioState()
+		app.CurrentScreen = ScreenNowPlaying
+		app.ShowLyrics = true
+		app.LyricsTrack = app.Playing.Track
+		app.Lyrics = parseLRC("[00:05]First line\n[00:12]Second line\n[00:20]The current line, long enough that it has to wrap onto a second row\n[00:31]Next line\n[00:40]Last line")
+	}},
+	{"audiobook", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		app.AudiobookFolders = []string{"/music/"}
+		app.AudiobookSpeed = 1.5
+		app.Library.Tracks[1].Chapters = []Chapter{{"Opening Credits", 0}, {"The Long Road", 40}, {"Homecoming", 120}}
+		playQueue(t, app, 1)
+		sim.Advance(40 * time.Second)
+		app.syncAudioState()
+		app.CurrentScreen = ScreenNowPlaying
+	}},
+	{"audiobook-list", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		app.AudiobookFolders = []string{"/music/"}
+		app.AudiobookProgress = map[string]*AudiobookProgress{
+			trackPath(1): {Finished: true},
+			trackPath(2): {Position: 120, Duration: 180},
+			trackPath(3): {Position: 20, Duration: 180},
+		}
+		showRootMenu(app)
+		songs := &MenuScreen{Title: "Songs", Parent: app.RootMenu, Items: app.buildTrackMenuItems(app.Library.Tracks)}
+		songs.SelIndex = 1
+		app.MenuStack = append(app.MenuStack, songs)
+	}},
+	{"equalizer", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		app.EQPreset = eqPresetIndex("speaker")
+		app.EQBand = 3
+		app.CurrentScreen = ScreenEqualizer
+	}},
+	{"queue", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		playQueue(t, app, 2)
+		app.QueueSelectedIndex = 4
+	}},
+	{"search", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		showRootMenu(app)
+		songs := &MenuScreen{Title: "Songs", Parent: app.RootMenu, Items: app.buildTrackMenuItems(app.Library.Tracks)}
+		app.MenuStack = append(app.MenuStack, songs)
+		app.toggleSearch()
+		app.SearchQuery = "TRACK"
+		app.SearchGridRow, app.SearchGridCol = 1, 3
+		app.filterMenuItems()
+	}},
+	{"lock", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		playQueue(t, app, 0)
+		app.CurrentScreen = ScreenNowPlaying
+		app.Locked = true
+	}},
+	{"error", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		showRootMenu(app)
+		app.ErrorMessage = "Failed to load audio\nOpus playback is not supported on this device"
+		app.ErrorTime = time.Now()
+	}},
+	{"library-scan", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		app.CurrentScreen = ScreenLibraryScan
+		app.LibScanCount = 1234
+		app.LibScanFolder = "/mnt/SDCARD/Media/Music/Artist/Album"
+		app.LibScanStatus = "Reading tags..."
+	}},
+	{"album-art", func(t *testing.T, app *MiyooPod, sim *simAudio) {
+		app.CurrentScreen = ScreenAlbumArt
+		app.AlbumArtFetching = true
+		app.AlbumArtCurrent, app.AlbumArtTotal = 12, 40
+		app.AlbumArtAlbumName, app.AlbumArtArtist = "Album", "Artist"
+		app.AlbumArtStatus = "Searching MusicBrainz..."
+		app.AlbumArtFetched, app.AlbumArtFailed = 9, 2
+	}},
+}
+
+func showRootMenu(app *MiyooPod) {
+	app.RootMenu = app.buildRootMenu()
+	app.MenuStack = []*MenuScreen{app.RootMenu}
+	app.CurrentScreen = ScreenMenu
+	app.refreshRootMenu()
+}
+
+func TestScreenSnapshots(t *testing.T) {
+	for _, screen := range snapshotScreens {
+		for _, theme := range AllThemes() {
+			screen, theme := screen, theme
+			name := screen.name + "/" + snapshotName(theme.Name)
+			t.Run(name, func(t *testing.T) {
+				app, sim := newTestApp(t, 6)
+				app.CurrentTheme = theme
+				screen.setup(t, app, sim)
+				app.NPCacheDirty = true
+				app.drawCurrentScreen()
+
+				checkSnapshot(t, app.FB, filepath.Join(SNAPSHOT_DIR, name+".png"))
+			})
+		}
+	}
+}
+
+// snapshotName turns a theme name into a file name
+func snapshotName(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "-")
+}
+
+// checkSnapshot compares a frame with its golden PNG, or rewrites the golden
+// with -update. On mismatch the actual frame is saved next to the golden as
+// <name>.actual.png (ignored by git).
+func checkSnapshot(t *testing.T, got *image.RGBA, path string) {
+	t.Helper()
+
+	if *updateSnapshots {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := savePNG(path, got); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("missing golden %s (run with -update): %v", path, err)
+	}
+	defer f.Close()
+	want, err := png.Decode(f)
+	if err != nil {
+		t.Fatalf("decode %s: %v", path, err)
+	}
+
+	if diff, first := diffImages(got, want); diff > 0 {
+		actual := strings.TrimSuffix(path, ".png") + ".actual.png"
+		savePNG(actual, got)
+		t.Errorf("%d pixels differ from %s, first at %v; see %s", diff, path, first, actual)
+	}
+}
+
+// diffImages counts differing pixels and returns the first one
+func diffImages(got *image.RGBA, want image.Image) (int, image.Point) {
+	if got.Bounds() != want.Bounds() {
+		return got.Bounds().Dx() * got.Bounds().Dy(), got.Bounds().Min
+	}
+
+	diff := 0
+	var first image.Point
+	b := got.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := got.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				if diff == 0 {
+					first = image.Pt(x, y)
+				}
+				diff++
+			}
+		}
+	}
+	return diff, first
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/dhowden/tag"
+	"golang.org/x/text/unicode/norm"
+)
+
+// DEFAULT_SORT_ARTICLES are the leading words ignored when sorting, unless the
+// settings file lists its own (sort_articles). An article ending in an
+// apostrophe is elided onto the next word, as in "L'Impératrice".
+var DEFAULT_SORT_ARTICLES = []string{"The", "A", "An", "Le", "La", "Les", "L'", "El", "Los", "Las", "Die", "Der", "Das"}
+
+// sortArticles holds the articles in use, folded and ready to match. Library
+// scans sort in the background, so it is swapped whole rather than edited;
+// nil means the defaults.
+var sortArticles atomic.Pointer[[]string]
+
+var defaultSortArticles = foldArticles(DEFAULT_SORT_ARTICLES)
+
+// readSortTags reads the sort-order tags: ID3 TSOT/TSOP/TSOA/TSO2 (TST/TSP/
+// TSA/TS2 in ID3v2.2) and Vorbis TITLESORT/ARTISTSORT/ALBUMSORT/
+// ALBUMARTISTSORT. The tag reader drops the MP4 sort atoms, so M4A files
+// sort by their plain names.
+func readSortTags(track *Track, m tag.Metadata) {
+	for key, value := range m.Raw() {
+		s, ok := value.(string)
+		if !ok {
+			continue
+		}
+		s = strings.TrimSpace(s)
+
+		switch strings.ToLower(key) {
+		case "tsot", "tst", "titlesort":
+			track.SortTitle = s
+		case "tsop", "tsp", "artistsort":
+			track.SortArtist = s
+		case "tsoa", "tsa", "albumsort":
+			track.SortAlbum = s
+		case "tso2", "ts2", "albumartistsort":
+			track.SortAlbumArtist = s
+		}
+	}
+}
+
+// foldSpelled spells out letters that are letters of their own rather than
+// accented ones, so they don't decompose, and folds typographic apostrophes
+var foldSpelled = map[rune]string{
+	'đ': "d", 'ð': "d", 'ħ': "h", 'ı': "i", 'ł': "l", 'ø': "o", 'ŧ': "t", 'ſ': "s",
+	'æ': "ae", 'œ': "oe", 'ß': "ss", 'þ': "th",
+	'‘': "'", '’': "'", '`': "'", '´': "'",
+}
+
+// foldText lowercases s and strips accents, for comparing and searching names:
+// "Émilie" sorts with the Es rather than after Z. Accented letters decompose
+// (NFD) into a base letter and combining marks, and the marks are dropped.
+func foldText(s string) string {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return strings.ToLower(s)
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for _, r := range norm.NFD.String(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		r = unicode.ToLower(r)
+		if plain, ok := foldSpelled[r]; ok {
+			b.WriteString(plain)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	// Recompose what NFD split without marks, such as Hangul syllables
+	return norm.NFC.String(b.String())
+}
+
+// sortKey returns the key name is ordered by. A sort tag is used as written;
+// otherwise leading punctuation and a leading article are skipped. Names that
+// fold to the same key fall back to their own spelling, so the order is stable.
+func sortKey(name, sortTag string) string {
+	var key string
+	if sortTag != "" {
+		key = foldText(sortTag)
+	} else {
+		key = stripArticle(foldText(name))
+	}
+	return key + "\x00" + strings.ToLower(name)
+}
+
+// stripArticle drops leading punctuation ("...And You Will Know Us") and a
+// leading article from a folded name, unless nothing would be left
+func stripArticle(key string) string {
+	key = strings.TrimSpace(key)
+	if trimmed := strings.TrimLeftFunc(key, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}); trimmed != "" {
+		key = trimmed
+	}
+
+	articles := defaultSortArticles
+	if p := sortArticles.Load(); p != nil {
+		articles = *p
+	}
+	for _, article := range articles {
+		if rest := strings.TrimSpace(strings.TrimPrefix(key, article)); len(rest) < len(key) && rest != "" {
+			return rest
+		}
+	}
+	return key
+}
+
+// foldArticles prepares articles for stripArticle: folded, and followed by a
+// space unless they elide onto the next word
+func foldArticles(articles []string) []string {
+	folded := make([]string, 0, len(articles))
+	for _, article := range articles {
+		article = foldText(strings.TrimSpace(article))
+		if article == "" {
+			continue
+		}
+		if !strings.HasSuffix(article, "'") {
+			article += " "
+		}
+		folded = append(folded, article)
+	}
+	return folded
+}
+
+// keyedSlice sorts items by keys computed once up front
+type keyedSlice[T any] struct {
+	items []T
+	keys  []string
+}
+
+func (s keyedSlice[T]) Len() int           { return len(s.items) }
+func (s keyedSlice[T]) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s keyedSlice[T]) Swap(i, j int) {
+	s.items[i], s.items[j] = s.items[j], s.items[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// sortByKey stably sorts items by the key of each, usually a sortKey
+func sortByKey[T any](items []T, key func(T) string) {
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = key(item)
+	}
+	sort.Stable(keyedSlice[T]{items, keys})
+}
+
+// trackSortKey orders tracks by title
+func trackSortKey(t *Track) string {
+	return sortKey(t.Title, t.SortTitle)
+}
+
+// albumSortTag returns the album sort tag of the first track that has one
+func albumSortTag(album *Album) string {
+	for _, t := range album.Tracks {
+		if t.SortAlbum != "" {
+			return t.SortAlbum
+		}
+	}
+	return ""
+}
+
+func albumSortKey(album *Album) string {
+	return sortKey(album.Name, albumSortTag(album))
+}
+
+// artistSortTag returns the sort tag matching the name an artist is filed
+// under: the album artist sort tag, or the artist sort tag for tracks filed
+// under their own artist (when it credits no one else)
+func artistSortTag(artist *Artist) string {
+	for _, album := range artist.Albums {
+		for _, t := range album.Tracks {
+			switch {
+			case t.VariousArtists:
+				return ""
+			case t.AlbumArtist != "" && t.SortAlbumArtist != "":
+				return t.SortAlbumArtist
+			case t.AlbumArtist == "" && t.SortArtist != "" && len(t.Artists) <= 1:
+				return t.SortArtist
+			}
+		}
+	}
+	return ""
+}
+
+func artistSortKey(artist *Artist) string {
+	return sortKey(artist.Name, artistSortTag(artist))
+}
+
+// sort orders tracks by title, albums and artists by name, and each album's
+// tracks by disc and track number
+func (lib *Library) sort() {
+	sortByKey(lib.Tracks, trackSortKey)
+	sortByKey(lib.Albums, albumSortKey)
+	sortByKey(lib.Artists, artistSortKey)
+	for _, album := range lib.Albums {
+		sort.SliceStable(album.Tracks, func(i, j int) bool {
+			if album.Tracks[i].DiscNum != album.Tracks[j].DiscNum {
+				return album.Tracks[i].DiscNum < album.Tracks[j].DiscNum
+			}
+			return album.Tracks[i].TrackNum < album.Tracks[j].TrackNum
+		})
+	}
+	for _, artist := range lib.Artists {
+		sortByKey(artist.Albums, albumSortKey)
+	}
+}
+
+// itemSortTags returns the sort tags behind a menu item, so a search also
+// finds items by the name they are filed under (e.g. a romanised artist)
+func itemSortTags(item *MenuItem) []string {
+	switch {
+	case item.Track != nil:
+		return []string{item.Track.SortTitle, item.Track.SortArtist}
+	case item.Album != nil:
+		return []string{albumSortTag(item.Album)}
+	case item.Artist != nil:
+		return []string{artistSortTag(item.Artist)}
+	}
+	return nil
+}
+
+// applySortArticles makes the article setting take effect for sorting
+func (app *MiyooPod) applySortArticles() {
+	articles := []string{}
+	if app.IgnoreArticles {
+		articles = defaultSortArticles
+		if len(app.SortArticles) > 0 {
+			articles = foldArticles(app.SortArticles)
+		}
+	}
+	sortArticles.Store(&articles)
+}
+
+// toggleIgnoreArticles switches between sorting "The Beatles" under B and
+// under T. The library is re-sorted in place; refreshSettingsMenu rebuilds the
+// root menu, so menus that were already built list in the new order too.
+func (app *MiyooPod) toggleIgnoreArticles() {
+	app.IgnoreArticles = !app.IgnoreArticles
+	app.applySortArticles()
+	if app.Library != nil {
+		// Cover Flow shares the album list: keep the same album in the centre
+		cf := app.Coverflow
+		var centered *Album
+		if cf != nil && cf.CenterIndex >= 0 && cf.CenterIndex < len(cf.Albums) {
+			centered = cf.Albums[cf.CenterIndex]
+		}
+		app.Library.sort()
+		if centered != nil {
+			for i, album := range cf.Albums {
+				if album == centered {
+					cf.CenterIndex = i
+				}
+			}
+		}
+	}
+	app.refreshSettingsMenu()
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save article setting: %v", err))
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+)
+
+func TestLibrarySortOrder(t *testing.T) {
+	app, _ := newTestApp(t, 0)
+	app.IgnoreArticles = true
+	app.applySortArticles()
+	prevSettings := SETTINGS_PATH
+	SETTINGS_PATH = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() {
+		sortArticles.Store(nil)
+		SETTINGS_PATH = prevSettings
+	})
+
+	for _, track := range []*Track{
+		{Path: "/m/1.mp3", Title: "Zebra", Artist: "Zebra", Album: "Z"},
+		{Path: "/m/2.mp3", Title: "Help!", Artist: "The Beatles", Album: "Help!"},
+		{Path: "/m/3.mp3", Title: "Émilie", Artist: "Émilie Simon", Album: "Végétal"},
+		{Path: "/m/4.mp3", Title: "Waterloo", Artist: "ABBA", Album: "Waterloo"},
+		{Path: "/m/5.mp3", Title: "Tokyo", Artist: "...And You Will Know Us", Album: "Source Tags"},
+		{Path: "/m/6.mp3", Title: "Sunset", Artist: "L'Impératrice", Album: "Odyssée"},
+		{Path: "/m/7.mp3", Title: "Automatic", Artist: "宇多田ヒカル", Album: "First Love",
+			SortArtist: "Utada Hikaru", SortTitle: "Automatic"},
+	} {
+		app.Library.addTrack(track, nil)
+	}
+
+	names := func() string {
+		app.Library.sort()
+		var out []string
+		for _, artist := range app.Library.Artists {
+			out = append(out, artist.Name)
+		}
+		return fmt.Sprint(out)
+	}
+
+	want := "[ABBA ...And You Will Know Us The Beatles Émilie Simon L'Impératrice 宇多田ヒカル Zebra]"
+	if got := names(); got != want {
+		t.Errorf("artists ignoring articles:\n got %s\nwant %s", got, want)
+	}
+
+	app.toggleIgnoreArticles()
+	want = "[ABBA ...And You Will Know Us Émilie Simon L'Impératrice The Beatles 宇多田ヒカル Zebra]"
+	if got := names(); got != want {
+		t.Errorf("artists with articles:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestSearchFoldsAccentsAndSortTags(t *testing.T) {
+	track := &Track{Title: "Déjà Vu", Artist: "宇多田ヒカル", SortArtist: "Utada Hikaru"}
+	item := &MenuItem{Label: track.Title, Track: track}
+
+	for query, want := range map[string]bool{
+		"deja":  true,
+		"utada": true,
+		"vu ":   false,
+	} {
+		if got := searchMatches(item, foldText(query)); got != want {
+			t.Errorf("search %q matched %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestFoldText(t *testing.T) {
+	for in, want := range map[string]string{
+		"The Beatles":      "the beatles",
+		"Émilie Simon":     "emilie simon",
+		"Sigur Rós":        "sigur ros",
+		"Mötley Crüe":      "motley crue",
+		"Dvořák":           "dvorak",
+		"Trần Tiến":        "tran tien",
+		"Ἀφροδίτη":         "αφροδιτη",
+		"Þursaflokkurinn":  "thursaflokkurinn",
+		"Røyksopp":         "royksopp",
+		"Straße":           "strasse",
+		"Łona":             "lona",
+		"Don’t":            "don't",
+		"İstanbul":         "istanbul",
+		"방탄소년단":            "방탄소년단",
+		"宇多田ヒカル":           "宇多田ヒカル",
+		"Beyonce\u0301":    "beyonce", // Already decomposed
+		"Ｆｕｌｌ ｗｉｄｔｈ stays": "ｆｕｌｌ ｗｉｄｔｈ stays",
+	} {
+		if got := foldText(in); got != want {
+			t.Errorf("foldText(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestToggleIgnoreArticlesResortsMenus(t *testing.T) {
+	app, _ := newTestApp(t, 0)
+	app.IgnoreArticles = true
+	app.applySortArticles()
+	prevSettings := SETTINGS_PATH
+	SETTINGS_PATH = filepath.Join(t.TempDir(), "settings.json")
+	t.Cleanup(func() {
+		sortArticles.Store(nil)
+		SETTINGS_PATH = prevSettings
+	})
+	for i, artist := range []string{"The Beatles", "ABBA", "Sade"} {
+		app.Library.addTrack(&Track{Path: fmt.Sprintf("/m/%d.mp3", i), Title: "Song", Artist: artist, Album: artist}, nil)
+	}
+	app.Library.sort()
+
+	app.RootMenu = app.buildRootMenu()
+	app.MenuStack = []*MenuScreen{app.RootMenu}
+	app.Coverflow.Albums = app.Library.Albums
+	app.Coverflow.CenterIndex = 1 // The Beatles
+	artists := func() string {
+		app.MenuStack = app.MenuStack[:1]
+		for i, item := range app.RootMenu.Items {
+			if item.Label == "Artists" {
+				app.RootMenu.SelIndex = i
+			}
+		}
+		app.handleMenuKey(A)
+		menu := app.MenuStack[len(app.MenuStack)-1]
+		app.handleMenuKey(B)
+		var labels []string
+		for _, item := range menu.Items {
+			labels = append(labels, item.Label)
+		}
+		return fmt.Sprint(labels)
+	}
+
+	if got := artists(); got != "[ABBA The Beatles Sade]" {
+		t.Fatalf("artists ignoring articles = %s", got)
+	}
+	app.toggleIgnoreArticles()
+	if got := artists(); got != "[ABBA Sade The Beatles]" {
+		t.Errorf("artists after the toggle = %s, want them re-sorted", got)
+	}
+	if cf := app.Coverflow; cf.Albums[cf.CenterIndex].Name != "The Beatles" {
+		t.Errorf("Cover Flow moved to %q", cf.Albums[cf.CenterIndex].Name)
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+var PLAY_STATS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_stats.json"
+
+// TrackStats is the listening history for one file, keyed by path. It lives
+// outside the library JSON so rescans and Clear App Data don't lose it.
+type TrackStats struct {
+	PlayCount  int   `json:"play_count"`
+	LastPlayed int64 `json:"last_played,omitempty"` // Unix time of the last counted play
+	SkipCount  int   `json:"skip_count,omitempty"`  // Times skipped before counting as a play
+}
+
+// loadPlayStats reads listening history from disk. Missing file means no history.
+func (app *MiyooPod) loadPlayStats() {
+	app.PlayStats = make(map[string]*TrackStats)
+
+	data, err := os.ReadFile(PLAY_STATS_PATH)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logMsg(fmt.Sprintf("WARNING: Failed to read play stats: %v", err))
+		}
+		return
+	}
+
+	if err := json.Unmarshal(data, &app.PlayStats); err != nil {
+		logMsg(fmt.Sprintf("WARNING: Failed to parse play stats: %v", err))
+		app.PlayStats = make(map[string]*TrackStats)
+		return
+	}
+
+	logMsg(fmt.Sprintf("INFO: Loaded play stats for %d tracks", len(app.PlayStats)))
+}
+
+// savePlayStats writes listening history to disk
+func (app *MiyooPod) savePlayStats() {
+	data, err := json.Marshal(app.PlayStats)
+	if err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to marshal play stats: %v", err))
+		return
+	}
+
+	if err := os.WriteFile(PLAY_STATS_PATH, data, 0644); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save play stats: %v", err))
+	}
+}
+
+// trackStats returns the stats for a track, or an empty record if it was never played
+func (app *MiyooPod) trackStats(track *Track) *TrackStats {
+	if s, ok := app.PlayStats[track.Path]; ok {
+		return s
+	}
+	return &TrackStats{}
+}
+
+// playCount returns how many times a track has been played
+func (app *MiyooPod) playCount(track *Track) int {
+	return app.trackStats(track).PlayCount
+}
+
+// recordListen is called by the playback poller and when the playing track is
+// replaced or stopped. It counts a play (and scrobbles it) once the track has
+// finished, or more than half of it (or four minutes) has been heard. Each
+// start of a track is recorded at most once.
+func (app *MiyooPod) recordListen(finished bool) {
+	if app.Playing == nil || app.Playing.Track == nil || app.ListenRecorded {
+		return
+	}
+
+	track := app.Playing.Track
+	listened := app.listenedTime()
+	duration := app.Playing.Duration
+	if duration <= 0 {
+		duration = track.Duration
+	}
+
+	if !finished && !(duration > 0 && listened >= duration/2) && listened < 4*time.Minute.Seconds() {
+		return
+	}
+	app.ListenRecorded = true
+
+	s := app.statsFor(track)
+	s.PlayCount++
+	s.LastPlayed = time.Now().Unix()
+	app.savePlayStats()
+
+	started := app.PlayStartedAt
+	if started.IsZero() {
+		// Restored from a previous session: estimate from the position
+		started = time.Now().Add(-time.Duration(app.Playing.Position * float64(time.Second)))
+	}
+	app.scrobble(track, started)
+}
+
+// startListen resets the played time when a track starts at position
+func (app *MiyooPod) startListen(position float64) {
+	app.ListenedTime = 0
+	app.ListenedFrom = position
+}
+
+// listenedTime returns how much of the current track has actually played.
+// Seeking moves the position without adding to it.
+func (app *MiyooPod) listenedTime() float64 {
+	return app.ListenedTime + max(0, app.Playing.Position-app.ListenedFrom)
+}
+
+// seekListen is called before a seek to position: what played since the last
+// seek is kept, and the count carries on from position
+func (app *MiyooPod) seekListen(position float64) {
+	app.ListenedTime = app.listenedTime()
+	app.ListenedFrom = position
+}
+
+// recordSkip is called when the user leaves the playing track with next/prev.
+// It counts a play if enough was heard, otherwise a skip.
+func (app *MiyooPod) recordSkip() {
+	app.recordListen(false)
+	if app.Playing == nil || app.Playing.Track == nil || app.ListenRecorded {
+		return
+	}
+	app.ListenRecorded = true
+
+	app.statsFor(app.Playing.Track).SkipCount++
+	app.savePlayStats()
+}
+
+// statsFor returns the stored stats for a track, creating the record if needed
+func (app *MiyooPod) statsFor(track *Track) *TrackStats {
+	if app.PlayStats == nil {
+		app.PlayStats = make(map[string]*TrackStats)
+	}
+	s, ok := app.PlayStats[track.Path]
+	if !ok {
+		s = &TrackStats{}
+		app.PlayStats[track.Path] = s
+	}
+	return s
+}
+
+// mostPlayedTracks returns played library tracks, most played first
+func (app *MiyooPod) mostPlayedTracks(limit int) []*Track {
+	var tracks []*Track
+	for _, t := range app.Library.Tracks {
+		if app.playCount(t) > 0 {
+			tracks = append(tracks, t)
+		}
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		pi, pj := app.trackStats(tracks[i]), app.trackStats(tracks[j])
+		if pi.PlayCount != pj.PlayCount {
+			return pi.PlayCount > pj.PlayCount
+		}
+		return pi.LastPlayed > pj.LastPlayed
+	})
+	if limit > 0 && len(tracks) > limit {
+		tracks = tracks[:limit]
+	}
+	return tracks
+}
+
+// recentlyPlayedTracks returns played library tracks, most recent first
+func (app *MiyooPod) recentlyPlayedTracks(limit int) []*Track {
+	var tracks []*Track
+	for _, t := range app.Library.Tracks {
+		if app.trackStats(t).LastPlayed > 0 {
+			tracks = append(tracks, t)
+		}
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		return app.trackStats(tracks[i]).LastPlayed > app.trackStats(tracks[j]).LastPlayed
+	})
+	if limit > 0 && len(tracks) > limit {
+		tracks = tracks[:limit]
+	}
+	return tracks
+}
+
+// ArtistPlays is an artist's total play count and their played tracks
+type ArtistPlays struct {
+	Name   string
+	Plays  int
+	Tracks []*Track // Most played first
+}
+
+// topArtists totals play counts per track artist, most played first
+func (app *MiyooPod) topArtists(limit int) []*ArtistPlays {
+	byName := make(map[string]*ArtistPlays)
+	var artists []*ArtistPlays
+
+	for _, t := range app.mostPlayedTracks(0) {
+		// Plays count for every credited artist
+		for _, name := range trackArtists(t) {
+			if name == "" {
+				name = "Unknown Artist"
+			}
+			key := strings.ToLower(name)
+			a, ok := byName[key]
+			if !ok {
+				a = &ArtistPlays{Name: name}
+				byName[key] = a
+				artists = append(artists, a)
+			}
+			a.Plays += app.playCount(t)
+			a.Tracks = append(a.Tracks, t)
+		}
+	}
+
+	sort.SliceStable(artists, func(i, j int) bool {
+		return artists[i].Plays > artists[j].Plays
+	})
+	if limit > 0 && len(artists) > limit {
+		artists = artists[:limit]
+	}
+	return artists
+}
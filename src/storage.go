@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// buildStorageMenuItems lists the music folders, audiobook folders and the
+// data folder, with options to add and remove folders
+func (app *MiyooPod) buildStorageMenuItems(menu *MenuScreen) []*MenuItem {
+	items := []*MenuItem{}
+
+	for _, root := range MUSIC_ROOTS {
+		r := root // capture
+		folderMenu := &MenuScreen{
+			Title:  r,
+			Parent: menu,
+			Builder: func() []*MenuItem {
+				return []*MenuItem{{
+					Label: "Remove from Library",
+					Action: func() {
+						app.removeMusicRoot(menu, r)
+					},
+				}}
+			},
+		}
+		items = append(items, &MenuItem{
+			Label:      "Music: " + r,
+			HasSubmenu: true,
+			Submenu:    folderMenu,
+		})
+	}
+
+	items = append(items, &MenuItem{
+		Label:      "Add Music Folder...",
+		HasSubmenu: true,
+		Submenu: app.folderBrowser(folderBrowseRoot(), menu, func(dir string) {
+			app.addMusicRoot(menu, dir)
+		}),
+	})
+
+	for _, folder := range app.AudiobookFolders {
+		f := folder // capture
+		items = append(items, &MenuItem{
+			Label:      "Audiobooks: " + f,
+			HasSubmenu: true,
+			Submenu: &MenuScreen{
+				Title:  f,
+				Parent: menu,
+				Builder: func() []*MenuItem {
+					return []*MenuItem{{
+						Label: "Stop Treating as Audiobooks",
+						Action: func() {
+							app.removeAudiobookFolder(menu, f)
+						},
+					}}
+				},
+			},
+		})
+	}
+
+	items = append(items, &MenuItem{
+		Label:      "Add Audiobook Folder...",
+		HasSubmenu: true,
+		Submenu: app.folderBrowser(folderBrowseRoot(), menu, func(dir string) {
+			app.addAudiobookFolder(menu, dir)
+		}),
+	})
+
+	items = append(items, &MenuItem{
+		Label:      "Data: " + DATA_DIR,
+		HasSubmenu: true,
+		Submenu: app.folderBrowser(folderBrowseRoot(), menu, func(dir string) {
+			app.setDataFolder(menu, dir)
+		}),
+	})
+
+	return items
+}
+
+// folderBrowser returns a menu listing the subfolders of dir. Its first item
+// passes dir to choose.
+func (app *MiyooPod) folderBrowser(dir string, parent *MenuScreen, choose func(string)) *MenuScreen {
+	screen := &MenuScreen{
+		Title:   dir,
+		Parent:  parent,
+		Rebuild: true, // Pick up folders created since
+	}
+	screen.Builder = func() []*MenuItem {
+		items := []*MenuItem{{
+			Label: "Use This Folder",
+			Action: func() {
+				choose(dir)
+			},
+		}}
+
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			logMsg(fmt.Sprintf("WARNING: Could not list %s: %v", dir, err))
+			return items
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if strings.HasPrefix(name, ".") {
+				continue
+			}
+			sub := filepath.Join(dir, name)
+			// Stat follows symlinks to folders
+			if info, err := os.Stat(sub); err != nil || !info.IsDir() {
+				continue
+			}
+			items = append(items, &MenuItem{
+				Label:      name,
+				HasSubmenu: true,
+				Submenu:    app.folderBrowser(sub, screen, choose),
+			})
+		}
+		return items
+	}
+	return screen
+}
+
+// returnToMenu pops the menu stack back to menu and rebuilds it
+func (app *MiyooPod) returnToMenu(menu *MenuScreen) {
+	for len(app.MenuStack) > 1 && app.MenuStack[len(app.MenuStack)-1] != menu {
+		app.MenuStack = app.MenuStack[:len(app.MenuStack)-1]
+	}
+	if menu.Builder != nil {
+		menu.Items = menu.Builder()
+		menu.Built = true
+	}
+	if menu.SelIndex >= len(menu.Items) {
+		menu.SelIndex = 0
+		menu.ScrollOff = 0
+	}
+}
+
+// addMusicRoot adds dir to the scanned folders and rescans the library
+func (app *MiyooPod) addMusicRoot(menu *MenuScreen, dir string) {
+	app.returnToMenu(menu)
+	if musicFromFlags {
+		app.showMessage("Music folders are set by -music", false)
+		return
+	}
+
+	roots := normalizeMusicRoots(append(append([]string{}, MUSIC_ROOTS...), dir))
+	if strings.Join(roots, "\x00") == strings.Join(MUSIC_ROOTS, "\x00") {
+		app.showMessage("Already in the library", false)
+		return
+	}
+
+	app.updateMusicRoots(roots)
+}
+
+// removeMusicRoot stops scanning root and rescans the library
+func (app *MiyooPod) removeMusicRoot(menu *MenuScreen, root string) {
+	app.returnToMenu(menu)
+	if musicFromFlags {
+		app.showMessage("Music folders are set by -music", false)
+		return
+	}
+	if len(MUSIC_ROOTS) < 2 {
+		app.showMessage("Can't remove the only music folder", false)
+		return
+	}
+
+	roots := make([]string, 0, len(MUSIC_ROOTS)-1)
+	for _, r := range MUSIC_ROOTS {
+		if r != root {
+			roots = append(roots, r)
+		}
+	}
+	app.updateMusicRoots(roots)
+}
+
+// updateMusicRoots saves the new folder list and rescans the library from it
+func (app *MiyooPod) updateMusicRoots(roots []string) {
+	setMusicRoots(roots)
+	app.MusicRoots = MUSIC_ROOTS
+
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save music folders: %v", err))
+	}
+	app.rescanLibrary()
+}
+
+// setDataFolder saves a new data folder. It is used from the next launch, so
+// the library cache, artwork and stats are never split between two folders.
+func (app *MiyooPod) setDataFolder(menu *MenuScreen, dir string) {
+	app.returnToMenu(menu)
+	if dataFromFlags {
+		app.showMessage("Data folder is set by -data", false)
+		return
+	}
+
+	app.DataDir = dir
+	if err := app.saveSettings(); err != nil {
+		logMsg(fmt.Sprintf("ERROR: Failed to save data folder: %v", err))
+		app.showMessage("Failed to save data folder", false)
+		return
+	}
+	logMsg(fmt.Sprintf("INFO: Data folder set to %s (used after restart)", dir))
+	app.showMessage("Restart MiyooPod to use the new data folder", true)
+}
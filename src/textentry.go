@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+)
+
+// promptText opens the on-screen keyboard. onDone is called with the entered
+// text when the user confirms with START; B cancels without calling it.
+func (app *MiyooPod) promptText(title, initial string, onDone func(string)) {
+	app.TextEntryTitle = title
+	app.TextEntryValue = initial
+	app.TextEntryRow = 0
+	app.TextEntryCol = 0
+	app.TextEntryDone = onDone
+	app.TextEntryReturn = app.CurrentScreen
+
+	app.setScreen(ScreenTextEntry)
+	app.drawCurrentScreen()
+}
+
+// handleTextEntryKey processes key input on the text entry screen
+func (app *MiyooPod) handleTextEntryKey(key Key) {
+	if moveGridCursor(&app.TextEntryRow, &app.TextEntryCol, key) {
+		app.drawCurrentScreen()
+		return
+	}
+
+	switch key {
+	case A:
+		char := searchGrid[app.TextEntryRow][app.TextEntryCol]
+		// Title case: capital at the start of each word
+		if app.TextEntryValue != "" && !strings.HasSuffix(app.TextEntryValue, " ") {
+			char = strings.ToLower(char)
+		}
+		app.TextEntryValue += char
+	case X:
+		if len(app.TextEntryValue) > 0 {
+			app.TextEntryValue = app.TextEntryValue[:len(app.TextEntryValue)-1]
+		}
+	case START:
+		value := strings.TrimSpace(app.TextEntryValue)
+		if value == "" {
+			return
+		}
+		done := app.TextEntryDone
+		app.TextEntryDone = nil
+		app.setScreen(app.TextEntryReturn)
+		if done != nil {
+			done(value)
+		}
+	case B, MENU:
+		app.TextEntryDone = nil
+		app.setScreen(app.TextEntryReturn)
+	default:
+		return
+	}
+
+	app.drawCurrentScreen()
+}
+
+// drawTextEntryScreen renders the input field and character grid
+func (app *MiyooPod) drawTextEntryScreen() {
+	dc := app.DC
+
+	dc.SetHexColor(app.CurrentTheme.BG)
+	dc.Clear()
+
+	app.drawHeader(app.TextEntryTitle)
+
+	// Input field
+	inputX := MENU_LEFT_PAD
+	inputY := MENU_TOP_Y + 16
+	inputW := SCREEN_WIDTH - MENU_LEFT_PAD - MENU_RIGHT_PAD
+	inputH := 44
+
+	dc.SetHexColor(app.CurrentTheme.ProgBG)
+	dc.DrawRoundedRectangle(float64(inputX), float64(inputY), float64(inputW), float64(inputH), 6)
+	dc.Fill()
+
+	dc.SetFontFace(app.FontMenu)
+	textY := float64(inputY) + float64(inputH)/2
+	if app.TextEntryValue != "" {
+		dc.SetHexColor(app.CurrentTheme.ItemTxt)
+		text := app.truncateText(app.TextEntryValue+"_", float64(inputW-16), app.FontMenu)
+		dc.DrawStringAnchored(text, float64(inputX+8), textY, 0, 0.5)
+	} else {
+		dc.SetHexColor(app.CurrentTheme.Dim)
+		dc.DrawStringAnchored("Enter a name...", float64(inputX+8), textY, 0, 0.5)
+	}
+
+	// Character grid, centered below the input
+	gridW := 420
+	gridX := (SCREEN_WIDTH - gridW) / 2
+	gridY := inputY + inputH + 20
+	app.drawCharGrid(gridX, gridY, gridW, 42, app.TextEntryRow, app.TextEntryCol)
+}
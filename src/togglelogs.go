@@ -7,7 +7,7 @@ import (
 
 // toggleLocalLogs toggles the local logs setting
 func (app *MiyooPod) toggleLocalLogs() {
-	app.LocalLogsEnabled = !app.LocalLogsEnabled
+	app.LocalLogsEnabled.Store(!app.LocalLogsEnabled.Load())
 
 	// Rebuild the settings menu to update the label
 	app.RootMenu = app.buildRootMenu()
@@ -29,6 +29,22 @@ func (app *MiyooPod) toggleLocalLogs() {
 	}
 }
 
+// refreshSettingsMenu rebuilds the root menu so settings labels update, then
+// returns to the Settings screen
+func (app *MiyooPod) refreshSettingsMenu() {
+	app.RootMenu = app.buildRootMenu()
+	app.MenuStack = []*MenuScreen{app.RootMenu}
+
+	for _, item := range app.RootMenu.Items {
+		if item.Label == "Settings" {
+			app.MenuStack = append(app.MenuStack, item.Submenu)
+			break
+		}
+	}
+
+	app.drawCurrentScreen()
+}
+
 // cycleAutoLock cycles through auto-lock options: 1min -> 3min -> 5min -> 10min -> Off -> 1min...
 func (app *MiyooPod) cycleAutoLock() {
 	switch app.AutoLockMinutes {
@@ -93,7 +109,7 @@ func (app *MiyooPod) toggleSentry() {
 
 	// Update PostHog client state (don't log to avoid circular call)
 	if posthogClient != nil {
-		posthogClient.Enabled = app.SentryEnabled
+		posthogClient.Enabled.Store(app.SentryEnabled)
 	}
 
 	// Rebuild the settings menu to update the label
@@ -2,6 +2,8 @@ package main
 
 import (
 	"image"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/fogleman/gg"
@@ -19,10 +21,10 @@ const (
 const SCREEN_WIDTH = 640
 const SCREEN_HEIGHT = 480
 
-// Music source
-const MUSIC_ROOT = "/mnt/SDCARD/Media/Music/"
-const LIBRARY_JSON_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_library.json"
-const ARTWORK_DIR = "/mnt/SDCARD/Media/Music/.miyoopod_artwork/"
+// Music source (overridable with setMusicRoots / setDataDir)
+var MUSIC_ROOT = "/mnt/SDCARD/Media/Music/"
+var LIBRARY_JSON_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_library.json"
+var ARTWORK_DIR = "/mnt/SDCARD/Media/Music/.miyoopod_artwork/"
 
 // UI layout constants (at 640x480 native resolution)
 const (
@@ -422,8 +424,41 @@ type Track struct {
 	DiscNum     int     `json:"disc_num"`
 	Year        int     `json:"year"`
 	Genre       string  `json:"genre"`
+	Composer    string  `json:"composer,omitempty"`
 	Duration    float64 `json:"duration"`
 	HasArt      bool    `json:"has_art"`
+	TrackGain   float64 `json:"track_gain,omitempty"`  // ReplayGain track gain (dB)
+	TrackPeak   float64 `json:"track_peak,omitempty"`  // ReplayGain track peak (1.0 = full scale)
+	AlbumGain   float64 `json:"album_gain,omitempty"`  // ReplayGain album gain (dB)
+	AlbumPeak   float64 `json:"album_peak,omitempty"`  // ReplayGain album peak (1.0 = full scale)
+	ModTime     int64   `json:"mod_time,omitempty"`    // File mtime (unix nanos) at last scan
+	Size        int64   `json:"size,omitempty"`        // File size in bytes at last scan
+	TagVersion  int     `json:"tag_version,omitempty"` // TRACK_TAG_VERSION the tags were read with
+	AddedAt     int64   `json:"added_at,omitempty"`    // Unix time the file was first scanned
+
+	Compilation    bool `json:"compilation,omitempty"`     // Tagged with the iTunes compilation flag
+	VariousArtists bool `json:"various_artists,omitempty"` // Filed under Various Artists (see markCompilations)
+
+	// Every artist and genre of a multi-value tag (see splitArtists), kept only
+	// when there is more than one; Artist and Genre hold the tag as written
+	Artists []string `json:"artists,omitempty"`
+	Genres  []string `json:"genres,omitempty"`
+
+	// Sort-order tags (see readSortTags); empty when the file has none
+	SortTitle       string `json:"sort_title,omitempty"`
+	SortArtist      string `json:"sort_artist,omitempty"`
+	SortAlbum       string `json:"sort_album,omitempty"`
+	SortAlbumArtist string `json:"sort_album_artist,omitempty"`
+
+	// Chapter markers (see readChapters), in playing order
+	Chapters []Chapter `json:"chapters,omitempty"`
+
+	// Set on tracks a cue sheet splits out of a larger file (see
+	// splitByCueSheets): the file, and where the track starts and ends in it
+	// in seconds. End is 0 for the last track, which plays to the end.
+	File  string  `json:"file,omitempty"`
+	Start float64 `json:"start,omitempty"`
+	End   float64 `json:"end,omitempty"`
 }
 
 type Album struct {
@@ -445,14 +480,22 @@ type Playlist struct {
 	Name   string   `json:"name"`
 	Path   string   `json:"path"`
 	Tracks []*Track `json:"-"` // Reconstructed from playlist file
+
+	Missing []string `json:"-"` // Entries that matched no file, kept so saving doesn't drop them
+	Smart   bool     `json:"-"` // Computed from a smart playlist rule, not backed by a file
 }
 
 type Library struct {
+	Version   int         `json:"version"` // LIBRARY_SCHEMA_VERSION the file was written with
 	Tracks    []*Track    `json:"tracks"`
 	Albums    []*Album    `json:"albums"`
 	Artists   []*Artist   `json:"artists"`
 	Playlists []*Playlist `json:"playlists"`
 
+	// Files split into Tracks by a cue sheet, kept so a rescan can reuse
+	// their tags
+	SplitFiles []*Track `json:"split_files,omitempty"`
+
 	TracksByPath  map[string]*Track  `json:"-"` // Reconstructed on load
 	AlbumsByKey   map[string]*Album  `json:"-"` // Reconstructed on load
 	ArtistsByName map[string]*Artist `json:"-"` // Reconstructed on load
@@ -478,6 +521,9 @@ const (
 	ScreenQueue
 	ScreenAlbumArt
 	ScreenLibraryScan
+	ScreenPlaylistEdit
+	ScreenTextEntry
+	ScreenEqualizer
 )
 
 func (s ScreenType) String() string {
@@ -492,6 +538,12 @@ func (s ScreenType) String() string {
 		return "album_art"
 	case ScreenLibraryScan:
 		return "library_scan"
+	case ScreenPlaylistEdit:
+		return "playlist_edit"
+	case ScreenTextEntry:
+		return "text_entry"
+	case ScreenEqualizer:
+		return "equalizer"
 	default:
 		return "unknown"
 	}
@@ -503,8 +555,10 @@ type MenuItem struct {
 	Action     func()
 	Submenu    *MenuScreen
 	Track      *Track
-	Album      *Album  // For album preview display
-	Artist     *Artist // For artist track queuing
+	Album      *Album    // For album preview display
+	Artist     *Artist   // For artist track queuing
+	Playlist   *Playlist // For playlist queuing and editing
+	Tracks     []*Track  // For other track groups (queuing, adding to playlists)
 }
 
 type MenuScreen struct {
@@ -515,6 +569,8 @@ type MenuScreen struct {
 	Parent    *MenuScreen
 	Builder   func() []*MenuItem
 	Built     bool
+	Rebuild   bool   // Re-run Builder every time the menu is entered
+	OnBack    func() // Called when the screen is left with B/LEFT
 }
 
 // --- Now Playing state ---
@@ -525,6 +581,9 @@ type NowPlaying struct {
 	Position float64
 	Duration float64
 	Volume   float64
+	Speed    float64 // Playback speed applied to the track (1.0 = normal)
+
+	SpeedBlocked bool // The audiobook speed can't be applied to the file, which plays at 1.0x
 }
 
 // --- Coverflow state ---
@@ -537,10 +596,17 @@ type CoverflowState struct {
 
 // --- Main application ---
 
+// MiyooPod is owned by the main loop: only the main goroutine reads or writes
+// its fields. Background goroutines (playback poller, library scan, art
+// fetches, timers) hand their results back with post. The exceptions are
+// the atomic fields and the channels.
 type MiyooPod struct {
-	Running       bool
+	Running       atomic.Bool // Cleared to quit; checked by every background loop
 	ShouldRefresh bool
 	RefreshChan   chan struct{} // Signal channel for screen refresh
+	Posted        chan func()   // Work queued for the main loop (see post)
+	frameMu       sync.Mutex    // Guards frame
+	frame         *image.RGBA   // Copy of FB handed to RunUI by triggerRefresh
 
 	// Display
 	DC *gg.Context
@@ -563,6 +629,40 @@ type MiyooPod struct {
 	Queue   *PlaybackQueue
 	Playing *NowPlaying
 
+	// Audio output (SDL_mixer on device, simulated in headless builds)
+	Audio AudioBackend
+
+	// Gapless playback: path of the track opened ahead in the audio layer, or
+	// of the cue track the playing one runs on into (see preloadNextTrack)
+	PreloadedPath string
+
+	// Listening history (see stats.go)
+	PlayStats      map[string]*TrackStats
+	ListenRecorded bool      // The current track was already counted as a play or skip
+	PlayStartedAt  time.Time // When the current track started, for scrobble timestamps
+	ListenedTime   float64   // Seconds of the current track played before the last seek
+	ListenedFrom   float64   // Position playback last started or seeked to
+
+	// ListenBrainz submission (see scrobble.go)
+	ListenBrainzToken  string // User token, set in the settings file
+	ListenBrainzOffset int64  // Bytes of the scrobble log already submitted
+	ListenBrainzBusy   bool   // Whether a manual submission is running
+
+	// Leave compilation-only artists (and Various Artists) out of Artists
+	HideCompilationArtists bool
+
+	// Sort "The Beatles" under B; SortArticles replaces the default articles
+	// (see sortkey.go)
+	IgnoreArticles bool
+	SortArticles   []string
+
+	// Featured-artist patterns from settings; empty means FEAT_PATTERNS
+	FeatPatterns []string
+
+	// Storage folders from settings (see paths.go); empty means the defaults
+	MusicRoots []string
+	DataDir    string
+
 	// Navigation
 	CurrentScreen ScreenType
 	MenuStack     []*MenuScreen
@@ -578,6 +678,34 @@ type MiyooPod struct {
 	NowPlayingBG *image.RGBA
 	NPCacheDirty bool
 
+	// Lyrics view on Now Playing (UP toggles it)
+	ShowLyrics    bool
+	Lyrics        *Lyrics       // Lyrics of LyricsTrack; nil if it has none
+	LyricsTrack   *Track        // Track the lyrics were loaded for
+	LyricsLoading bool          // Whether the lyrics are still being read
+	LyricsSprites []*image.RGBA // Pre-rendered lines, white on transparent
+	LyricsLine    int           // Line highlighted in the last draw
+
+	// Sleep timer (see sleep.go)
+	SleepMode        SleepMode
+	SleepMinutes     int         // Length of a SleepAfterMinutes timer
+	SleepDeadline    time.Time   // When a SleepAfterMinutes timer runs out
+	SleepAction      SleepAction // What to do after pausing, saved in settings
+	SleepFading      bool        // Whether the fade-out is in progress
+	SleepFadeGen     int         // Bumped to cancel a fade's pending steps
+	SleepStatusShown string      // Status drawn in the Now Playing cache
+
+	// Audiobook and podcast folders (see audiobook.go)
+	AudiobookFolders  []string                      // Files under these keep their own resume position
+	AudiobookSpeed    float64                       // Playback speed of audiobook files
+	AudiobookProgress map[string]*AudiobookProgress // Resume positions by path
+	ChapterShown      int                           // Chapter drawn in the Now Playing cache
+
+	// Equalizer (see eq.go)
+	EQPreset int                  // Selected preset, index into eqPresets
+	EQCurves map[string][]float64 // Edited curves by preset key, saved in settings
+	EQBand   int                  // Band selected on the Equalizer screen
+
 	// Performance optimization: text measurement cache
 	// Key: text+font.Face pointer, Value: width in pixels
 	TextMeasureCache map[string]float64
@@ -624,8 +752,8 @@ type MiyooPod struct {
 	// Queue view state
 	QueueScrollOffset int // Scroll position for queue view
 
-	// Album art fetch state (background goroutine updates these, main thread reads for rendering)
-	albumArtStatusFunc func(string)
+	// Album art fetch state (progress posted by the fetch goroutine)
+	albumArtCancel     *atomic.Bool   // Set to stop the running fetch
 	AlbumArtFetching   bool           // Whether a fetch is currently running
 	AlbumArtCurrent    int            // Current album index being fetched
 	AlbumArtTotal      int            // Total albums to fetch
@@ -638,26 +766,31 @@ type MiyooPod struct {
 	AlbumArtElapsed    string         // Elapsed time for results display
 	RedrawChan         chan struct{}   // Background goroutines signal main thread to redraw
 
-	// Library scan state (background goroutine)
-	LibScanRunning   bool   // Whether a scan is currently running
-	LibScanDone      bool   // Scan complete, showing results
-	LibScanCount     int    // Number of tracks found so far
-	LibScanFolder    string // Current folder being scanned
-	LibScanStatus    string // Status text
-	LibScanElapsed   string // Elapsed time for results display
-	LibScanPhase     string // Current phase: "scanning", "sorting", "decoding", "saving"
+	// Library scan state (progress posted by the scan goroutine)
+	LibScanRunning    bool   // Whether a scan is currently running
+	LibScanDone       bool   // Scan complete, showing results
+	LibScanCount      int    // Number of tracks found so far
+	LibScanFolder     string // Current folder being scanned
+	LibScanStatus     string // Status text
+	LibScanElapsed    string // Elapsed time for results display
+	LibScanPhase      string // Current phase: "scanning", "sorting", "decoding", "saving"
+	LibScanAdded      int    // Tracks tagged for the first time in the last scan
+	LibScanUpdated    int    // Tracks re-tagged because their file changed
+	LibScanRemoved    int    // Tracks dropped because their file is gone
+	LibScanUnresolved int    // Playlist entries that matched no file
 	QueueSelectedIndex int      // Selected track in queue view
 
 	// Settings
-	InstallationID   string // Unique ID for this installation
-	LocalLogsEnabled bool   // Whether to write logs to file
-	SentryEnabled    bool   // Whether to send events to Sentry
+	InstallationID   string         // Unique ID for this installation
+	LocalLogsEnabled atomic.Bool    // Whether to write logs to file (read by logMsg on any goroutine)
+	SentryEnabled    bool           // Whether to send events to Sentry
+	ReplayGainMode   ReplayGainMode // Loudness normalization: off, track or album gain
+	ReplayGainPreamp float64        // Extra gain in dB added to tagged tracks
 
 	// Update state
 	UpdateAvailable      bool            // Whether an update is available
 	UpdateInfo           *VersionInfo    // Remote version info when update is available
 	UpdateNotifications  bool            // Whether to show auto update popup on launch
-	VersionCheckDone     chan struct{}    // Closed when async version check completes
 	ShowingUpdatePrompt  bool            // True when update prompt overlay is visible
 
 	// Seek state (fast forward / rewind on Now Playing)
@@ -678,6 +811,22 @@ type MiyooPod struct {
 	MarqueeDstW       int          // Visible window width for marquee blit
 	MarqueeColor      [3]uint8     // Tint color (r, g, b)
 
+	// Playlist editor state
+	EditPlaylist  *Playlist // Playlist being edited
+	EditTracks    []*Track  // Working copy of its tracks
+	EditSelIndex  int       // Selected row
+	EditScrollOff int       // Scroll position
+	EditGrabbed   bool      // Selected track is grabbed and moves with UP/DOWN
+	EditDirty     bool      // Unsaved changes
+
+	// Text entry state (on-screen keyboard)
+	TextEntryTitle  string       // Header shown above the input
+	TextEntryValue  string       // Text typed so far
+	TextEntryRow    int          // Selected row in the character grid
+	TextEntryCol    int          // Selected column in the character grid
+	TextEntryDone   func(string) // Called with the text when confirmed
+	TextEntryReturn ScreenType   // Screen to return to on confirm or cancel
+
 	// Search state
 	SearchActive    bool        // Whether search panel is visible
 	SearchQuery     string      // Current search input string
@@ -650,6 +650,26 @@ func (app *MiyooPod) drawStatusIndicators(y int) {
 	} else {
 		app.drawRepeatIcon(rightX+190, y-10, 20)
 	}
+
+	// Playback speed, when not normal; greyed out when the file can't be
+	// sped up
+	if app.Playing != nil && app.Playing.SpeedBlocked {
+		dc.SetFontFace(app.FontSmall)
+		dc.SetHexColor(app.CurrentTheme.Dim)
+		dc.DrawStringAnchored(formatSpeed(app.AudiobookSpeed), float64(rightX+230), float64(y), 0, 0.5)
+	} else if app.Playing != nil && app.Playing.Speed > 0 && app.Playing.Speed != 1 {
+		dc.SetFontFace(app.FontSmall)
+		dc.SetHexColor(app.CurrentTheme.Accent)
+		dc.DrawStringAnchored(formatSpeed(app.Playing.Speed), float64(rightX+230), float64(y), 0, 0.5)
+	}
+
+	// Sleep timer, below the controls
+	app.SleepStatusShown = app.sleepTimerStatus()
+	if app.SleepStatusShown != "" {
+		dc.SetFontFace(app.FontSmall)
+		dc.SetHexColor(app.CurrentTheme.Accent)
+		dc.DrawString(app.SleepStatusShown, float64(rightX), float64(y+35))
+	}
 }
 
 // drawStatusBar draws a permanent status bar at the bottom of the screen
@@ -692,7 +712,7 @@ func (app *MiyooPod) drawStatusBar() {
 				current := app.MenuStack[len(app.MenuStack)-1]
 				if len(current.Items) > 0 {
 					firstItem := current.Items[0]
-					if firstItem.Track != nil || firstItem.Album != nil || firstItem.Artist != nil {
+					if firstItem.Track != nil || firstItem.Album != nil || firstItem.Artist != nil || firstItem.Tracks != nil {
 						if app.Playing == nil || app.Playing.State == StateStopped {
 							app.drawButtonLegend(360, centerY, "Y", "Add to Q")
 						}
@@ -710,6 +730,20 @@ func (app *MiyooPod) drawStatusBar() {
 		app.drawButtonLegend(100, centerY, "A", "Play")
 		app.drawButtonLegend(200, centerY, "X", "Remove")
 		app.drawButtonLegend(320, centerY, "SELECT", "Clear All")
+		app.drawButtonLegend(480, centerY, "Y", "Playlist")
+	case ScreenPlaylistEdit:
+		app.drawButtonLegend(12, centerY, "A", "Grab/Drop")
+		app.drawButtonLegend(160, centerY, "X", "Remove")
+		app.drawButtonLegend(270, centerY, "B", "Save & Back")
+	case ScreenTextEntry:
+		app.drawButtonLegend(12, centerY, "A", "Add Char")
+		app.drawButtonLegend(140, centerY, "X", "Delete")
+		app.drawButtonLegend(250, centerY, "START", "Save")
+		app.drawButtonLegend(370, centerY, "B", "Cancel")
+	case ScreenEqualizer:
+		app.drawButtonLegend(12, centerY, "A", "Preset")
+		app.drawButtonLegend(130, centerY, "X", "Reset")
+		app.drawButtonLegend(235, centerY, "B", "Back")
 	}
 
 }
@@ -741,23 +775,22 @@ func (app *MiyooPod) drawButtonLegend(x int, centerY float64, button string, lab
 	dc.DrawStringAnchored(label, badgeX+badgeW+6, centerY, 0, 0.5)
 }
 
+// batteryPaths are tried in order for the battery percentage
+var batteryPaths = []string{
+	"/tmp/percBat", // Miyoo Mini Plus
+	"/sys/class/power_supply/battery/capacity",
+	"/sys/class/power_supply/BAT0/capacity",
+	"/sys/class/power_supply/axp22-battery/capacity",
+	"/proc/battery",
+	"/proc/miyoo/battery",
+	"/sys/class/mstar/battery",
+	"/mnt/SDCARD/.tmp_update/battery",
+	"/tmp/battery_capacity",
+}
+
 // getBatteryLevel reads the battery percentage from the system
 func getBatteryLevel() int {
-	// Try reading battery capacity from various paths
-	// Miyoo Mini Plus stores battery percentage in /tmp/percBat
-	paths := []string{
-		"/tmp/percBat", // Miyoo Mini Plus
-		"/sys/class/power_supply/battery/capacity",
-		"/sys/class/power_supply/BAT0/capacity",
-		"/sys/class/power_supply/axp22-battery/capacity",
-		"/proc/battery",
-		"/proc/miyoo/battery",
-		"/sys/class/mstar/battery",
-		"/mnt/SDCARD/.tmp_update/battery",
-		"/tmp/battery_capacity",
-	}
-
-	for _, path := range paths {
+	for _, path := range batteryPaths {
 		data, err := os.ReadFile(path)
 		if err == nil {
 			capacityStr := strings.TrimSpace(string(data))
@@ -815,6 +848,24 @@ func (app *MiyooPod) drawBatteryIcon(x, y, percent int) {
 	}
 }
 
+// showMessage shows a full-screen result for 1.5s, then returns to the menu
+func (app *MiyooPod) showMessage(text string, ok bool) {
+	dc := app.DC
+	dc.SetHexColor(app.CurrentTheme.BG)
+	dc.Clear()
+	dc.SetFontFace(app.FontMenu)
+	if ok {
+		dc.SetHexColor(app.CurrentTheme.Accent)
+	} else {
+		dc.SetHexColor(app.CurrentTheme.Dim)
+	}
+	dc.DrawStringAnchored(text, SCREEN_WIDTH/2, SCREEN_HEIGHT/2, 0.5, 0.5)
+	app.triggerRefresh()
+	time.Sleep(1500 * time.Millisecond)
+
+	app.drawCurrentScreen()
+}
+
 // showError displays an error message popup for 1 second
 func (app *MiyooPod) showError(message string) {
 	app.ErrorMessage = message
@@ -10,8 +10,9 @@ import (
 	"github.com/fogleman/gg"
 )
 
-const UPDATE_INFO_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update.json"
-const UPDATE_STATUS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update_status"
+// OTA handoff files, kept in the data folder (see setDataDir)
+var UPDATE_INFO_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update.json"
+var UPDATE_STATUS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update_status"
 
 type UpdateRequest struct {
 	Version  string `json:"version"`
@@ -37,6 +38,14 @@ func (app *MiyooPod) showUpdatePrompt() {
 	app.drawCurrentScreen()
 }
 
+// promptForUpdate shows the update prompt after a startup version check
+// found a newer version, unless update notifications are off
+func (app *MiyooPod) promptForUpdate() {
+	if app.UpdateAvailable && app.UpdateNotifications && !app.ShowingUpdatePrompt {
+		app.showUpdatePrompt()
+	}
+}
+
 // drawUpdatePromptOverlay renders the update dialog overlay.
 // Uses the same design language as the rest of the app: HeaderBG header bar,
 // ProgBG button badges, and flat layout matching drawHeader/drawStatusBar/drawButtonLegend.
@@ -144,7 +153,7 @@ func (app *MiyooPod) launchUpdater() {
 
 	// Stop playback
 	if app.Playing != nil && app.Playing.State == StatePlaying {
-		audioStop()
+		app.Audio.Stop()
 		app.Playing.State = StateStopped
 	}
 
@@ -187,11 +196,14 @@ func (app *MiyooPod) launchUpdater() {
 
 	// Cleanup
 	close(app.RefreshChan)
+	app.Audio.Quit()
 	sdlCleanup()
 
-	// Exec the updater binary (replaces this process)
+	// Exec the updater binary (replaces this process), telling it where the
+	// handoff files and settings are
 	updaterPath := "./updater"
-	err = syscall.Exec(updaterPath, []string{updaterPath}, os.Environ())
+	args := []string{updaterPath, "-data", DATA_DIR, "-settings", SETTINGS_PATH}
+	err = syscall.Exec(updaterPath, args, os.Environ())
 	if err != nil {
 		logMsg(fmt.Sprintf("ERROR: Failed to exec updater: %v", err))
 		// If exec fails, clean up the update request file
@@ -332,7 +344,7 @@ func (app *MiyooPod) clearAppData() {
 	app.triggerRefresh()
 
 	// Wait for user input
-	for app.Running {
+	for app.Running.Load() {
 		key := Key(C_GetKeyPress())
 		if key == NONE {
 			time.Sleep(33 * time.Millisecond)
@@ -364,9 +376,11 @@ func (app *MiyooPod) performClearAppData() {
 	dc.DrawStringAnchored("Clearing app data...", SCREEN_WIDTH/2, SCREEN_HEIGHT/2, 0.5, 0.5)
 	app.triggerRefresh()
 
-	// Delete library cache
-	if err := os.Remove(LIBRARY_JSON_PATH); err != nil && !os.IsNotExist(err) {
-		logMsg(fmt.Sprintf("WARNING: Failed to remove library cache: %v", err))
+	// Delete library cache, and its backup so it isn't recovered on restart
+	for _, path := range []string{LIBRARY_JSON_PATH, libraryBackupPath()} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			logMsg(fmt.Sprintf("WARNING: Failed to remove library cache: %v", err))
+		}
 	}
 
 	// Delete settings
@@ -379,9 +393,12 @@ func (app *MiyooPod) performClearAppData() {
 		logMsg(fmt.Sprintf("WARNING: Failed to remove artwork: %v", err))
 	}
 
-	// Write back a minimal settings file with just the UUID
+	// Write back a minimal settings file with just the UUID and the storage
+	// folders, so the library is rescanned from the same place
 	minimalSettings := Settings{
 		InstallationID: installID,
+		MusicRoots:     app.MusicRoots,
+		DataDir:        app.DataDir,
 	}
 	data, err := json.MarshalIndent(minimalSettings, "", "  ")
 	if err == nil {
@@ -392,11 +409,12 @@ func (app *MiyooPod) performClearAppData() {
 
 	// Stop playback
 	if app.Playing != nil && app.Playing.State == StatePlaying {
-		audioStop()
+		app.Audio.Stop()
 	}
 
 	// Cleanup
 	close(app.RefreshChan)
+	app.Audio.Quit()
 	sdlCleanup()
 
 	// Restart the app via launch.sh
@@ -21,43 +21,57 @@ type VersionInfo struct {
 
 // checkVersion fetches the latest version from GitHub and compares with current version
 func (app *MiyooPod) checkVersion() string {
+	info, status := fetchLatestVersion()
+	app.setUpdateInfo(info)
+	return status
+}
+
+// setUpdateInfo records the result of a version check; nil means no update
+func (app *MiyooPod) setUpdateInfo(info *VersionInfo) {
+	app.UpdateAvailable = info != nil
+	if info != nil {
+		app.UpdateInfo = info
+	}
+}
+
+// fetchLatestVersion fetches version.json and returns it when the remote
+// version is newer, along with a status line for the settings screen.
+// Doesn't touch app state, so it can run in the background.
+func fetchLatestVersion() (*VersionInfo, string) {
 	client := getInsecureHTTPClient(5 * time.Second)
 
 	resp, err := client.Get(VERSION_CHECK_URL)
 	if err != nil {
 		logMsg(fmt.Sprintf("WARNING: Failed to fetch version: %v", err))
-		return "Failed to fetch version"
+		return nil, "Failed to fetch version"
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
 		logMsg(fmt.Sprintf("WARNING: Version check returned status: %d", resp.StatusCode))
-		return "Failed to fetch version"
+		return nil, "Failed to fetch version"
 	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logMsg(fmt.Sprintf("WARNING: Failed to read version response: %v", err))
-		return "Failed to fetch version"
+		return nil, "Failed to fetch version"
 	}
 
 	var remoteVersion VersionInfo
 	if err := json.Unmarshal(body, &remoteVersion); err != nil {
 		logMsg(fmt.Sprintf("WARNING: Failed to parse version JSON: %v", err))
-		return "Failed to fetch version"
+		return nil, "Failed to fetch version"
 	}
 
 	// Compare versions — only prompt if remote is strictly newer
 	if !isNewerVersion(APP_VERSION, remoteVersion.Version) {
 		logMsg(fmt.Sprintf("INFO: Version check: Up to date (%s, remote: %s)", APP_VERSION, remoteVersion.Version))
-		app.UpdateAvailable = false
-		return "Up to date"
+		return nil, "Up to date"
 	}
 
 	logMsg(fmt.Sprintf("INFO: Version check: Update available (current: %s, latest: %s)", APP_VERSION, remoteVersion.Version))
-	app.UpdateAvailable = true
-	app.UpdateInfo = &remoteVersion
-	return fmt.Sprintf("Update available: v%s", remoteVersion.Version)
+	return &remoteVersion, fmt.Sprintf("Update available: v%s", remoteVersion.Version)
 }
 
 // isNewerVersion returns true if remote is strictly newer than current.
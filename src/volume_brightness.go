@@ -9,17 +9,13 @@ import (
 func (app *MiyooPod) adjustVolume(delta int) {
 	newVolume := clamp(app.SystemVolume+delta, 0, 100)
 
-	// Always max SDL2_mixer volume
-	audioSetVolume(100)
-
-	// Set MI_AO system volume
-	setMiAOVolume(newVolume)
+	app.applySystemVolume(newVolume)
 	app.SystemVolume = newVolume
 
 	app.showOverlay("volume", newVolume)
 
 	// Persist to settings
-	go app.saveSettings()
+	app.saveSettingsAsync()
 
 	logMsg(fmt.Sprintf("Volume: %d%%", newVolume))
 }
@@ -34,7 +30,7 @@ func (app *MiyooPod) adjustBrightness(delta int) {
 	app.showOverlay("brightness", newBrightness)
 
 	// Persist to settings
-	go app.saveSettings()
+	app.saveSettingsAsync()
 
 	logMsg(fmt.Sprintf("Brightness: %d%%", newBrightness))
 }
@@ -54,10 +50,17 @@ func (app *MiyooPod) showOverlay(overlayType string, value int) {
 	app.requestRedraw()
 
 	// Hide after 2 seconds
-	app.OverlayTimer = time.AfterFunc(2*time.Second, func() {
-		app.OverlayVisible = false
-		app.requestRedraw()
+	var timer *time.Timer
+	timer = time.AfterFunc(2*time.Second, func() {
+		app.post(func() {
+			if app.OverlayTimer != timer {
+				return // Shown again since
+			}
+			app.OverlayVisible = false
+			app.requestRedraw()
+		})
 	})
+	app.OverlayTimer = timer
 }
 
 func clamp(value, min, max int) int {
@@ -14,6 +14,7 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"image"
 	"io"
@@ -103,11 +104,26 @@ var (
 	powerWarningVisible int32
 )
 
-const UPDATE_INFO_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update.json"
-const UPDATE_STATUS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update_status"
-const SETTINGS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_settings.json"
+// Default locations; MiyooPod passes its configured ones with -data and -settings
+var UPDATE_INFO_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update.json"
+var UPDATE_STATUS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_update_status"
+var SETTINGS_PATH = "/mnt/SDCARD/Media/Music/.miyoopod_settings.json"
+
 const BACKUP_DIR = ".miyoopod_backup"
 
+// parseFlags picks up the data folder and settings file MiyooPod was using
+func parseFlags() {
+	dataDir := flag.String("data", "", "folder holding the update request and status files")
+	settings := flag.String("settings", SETTINGS_PATH, "MiyooPod settings file (for the theme)")
+	flag.Parse()
+
+	if *dataDir != "" {
+		UPDATE_INFO_PATH = filepath.Join(*dataDir, ".miyoopod_update.json")
+		UPDATE_STATUS_PATH = filepath.Join(*dataDir, ".miyoopod_update_status")
+	}
+	SETTINGS_PATH = *settings
+}
+
 func init() {
 	runtime.GOMAXPROCS(2)
 	runtime.LockOSThread()
@@ -166,6 +182,8 @@ func main() {
 	// GC runs more often but prevents OOM during download
 	debug.SetGCPercent(20)
 
+	parseFlags()
+
 	// Clean up any leftover temp files from previous failed updates
 	os.Remove(".update_download.zip")
 	os.Remove(".update_download.zip.tmp")